@@ -1,76 +1,82 @@
-import { ModelFactory } from './models.js';
-
+// Bosses are server-authoritative: they arrive through mob_update like any
+// other mob. This system only draws their health bars and the telegraphs the
+// server sends before an area attack lands.
 export class BossSystem {
     constructor(scene) {
         this.scene = scene;
-        this.bosses = [];
+        this.telegraphs = [];
     }
 
-    spawnBoss(type, x, z) {
-        let mesh;
-        let hp = 1000;
-        let name = "Boss";
-
-        if (type === "Gorilla King") {
-            mesh = ModelFactory.createGorilla();
-            hp = 500;
-            name = "Gorilla King";
-        } else if (type === "Ice Admiral") {
-            mesh = ModelFactory.createAdmiral();
-            hp = 2000;
-            name = "Ice Admiral";
-        } else {
-            return;
+    // Called from updateMobs for every mob with isBoss set
+    updateBoss(mesh, data) {
+        if (!mesh.userData.hpBar) {
+            this.createHealthBar(mesh);
         }
+        const key = `${data.health}/${data.phase || 0}/${data.enraged ? 1 : 0}`;
+        if (mesh.userData.hpKey !== key) {
+            mesh.userData.hpKey = key;
+            this.drawHealthBar(mesh.userData.hpBar, data);
+        }
+    }
 
-        mesh.position.set(x, 0, z); // Models handle Y offset internally mostly, but ensure ground
-        if (mesh.position.y < 0.1) mesh.position.y = 0; // Integrity check
-
-        mesh.userData = {
-            type: "boss",
-            name: name,
-            hp: hp,
-            maxHp: hp
-        };
+    createHealthBar(mesh) {
+        const canvas = document.createElement('canvas');
+        canvas.width = 256;
+        canvas.height = 64;
 
-        this.scene.add(mesh);
-        this.bosses.push(mesh);
+        const texture = new THREE.CanvasTexture(canvas);
+        const spriteMat = new THREE.SpriteMaterial({ map: texture });
+        const sprite = new THREE.Sprite(spriteMat);
 
-        this.createHealthBar(mesh, name, hp);
+        sprite.position.set(0, 3.5, 0); // Above head
+        sprite.scale.set(4, 1, 1);
+        sprite.userData.canvas = canvas;
 
-        console.log(`Spawned Boss: ${name} at ${x}, ${z}`);
+        mesh.add(sprite);
+        sprite.raycast = () => { }; // Disable raycasting to prevent crash
+        mesh.userData.hpBar = sprite; // Save ref to update later
     }
 
-    createHealthBar(mesh, name, hp) {
-        // Simple HTML-like billboard or canvas sprite
-        const canvas = document.createElement('canvas');
-        canvas.width = 256;
-        canvas.height = 64;
+    drawHealthBar(sprite, data) {
+        const canvas = sprite.userData.canvas;
         const ctx = canvas.getContext('2d');
+        ctx.clearRect(0, 0, canvas.width, canvas.height);
 
         // Background
         ctx.fillStyle = "#333";
         ctx.fillRect(0, 20, 256, 24);
 
         // HP Fill
-        ctx.fillStyle = "#f00";
-        ctx.fillRect(2, 22, 252, 20);
+        const pct = data.maxHealth > 0 ? data.health / data.maxHealth : 0;
+        ctx.fillStyle = data.enraged ? "#ff00ff" : "#f00";
+        ctx.fillRect(2, 22, 252 * pct, 20);
 
         // Text
         ctx.fillStyle = "#fff";
         ctx.font = "bold 20px Arial";
         ctx.textAlign = "center";
-        ctx.fillText(`${name} [${hp}/${hp}]`, 128, 18);
+        ctx.fillText(`${data.type} [${data.health}/${data.maxHealth}]`, 128, 18);
 
-        const texture = new THREE.CanvasTexture(canvas);
-        const spriteMat = new THREE.SpriteMaterial({ map: texture });
-        const sprite = new THREE.Sprite(spriteMat);
+        sprite.material.map.needsUpdate = true;
+    }
 
-        sprite.position.set(0, 3.5, 0); // Above head
-        sprite.scale.set(4, 1, 1);
+    // Ground ring that stays up for the ability's wind-up
+    showTelegraph(msg) {
+        const radius = Math.max(msg.radius || 1, 0.5);
+        const geo = new THREE.RingGeometry(radius * 0.9, radius, 32);
+        const mat = new THREE.MeshBasicMaterial({ color: 0xff3300, side: THREE.DoubleSide, transparent: true, opacity: 0.6 });
+        const ring = new THREE.Mesh(geo, mat);
+        ring.rotation.x = -Math.PI / 2;
+        ring.position.set(msg.x, 0.1, msg.z);
+        ring.raycast = () => { };
+        this.scene.add(ring);
 
-        mesh.add(sprite);
-        sprite.raycast = () => { }; // Disable raycasting to prevent crash
-        mesh.userData.hpBar = sprite; // Save ref to update later
+        this.telegraphs.push(ring);
+        setTimeout(() => {
+            this.scene.remove(ring);
+            geo.dispose();
+            mat.dispose();
+            this.telegraphs = this.telegraphs.filter(t => t !== ring);
+        }, msg.windUp || 500);
     }
 }
@@ -0,0 +1,83 @@
+// Crews. The server owns membership, ranks and the bank; this system sends
+// the crew actions and shows their results in chat.
+// Chat commands: /c <message>, /crew create|invite|accept|kick|promote|demote|leave|disband|deposit|withdraw|info
+export class CrewSystem {
+    constructor(getSocket) {
+        this.getSocket = getSocket;
+        this.crew = null;
+        this.pendingInvite = null;
+    }
+
+    send(payload) {
+        const socket = this.getSocket();
+        if (socket && socket.readyState === WebSocket.OPEN) {
+            socket.send(JSON.stringify(payload));
+        }
+    }
+
+    // Returns true if the chat line was a crew command
+    handleCommand(text) {
+        if (text.startsWith('/c ')) {
+            this.send({ type: 'crew_chat', item: text.slice(3) });
+            return true;
+        }
+        if (!text.startsWith('/crew')) return false;
+
+        const [, action = 'info', ...rest] = text.split(' ');
+        const arg = rest.join(' ');
+        switch (action) {
+            case 'deposit':
+            case 'withdraw':
+                this.send({ type: 'crew_' + action, amount: parseInt(arg, 10) || 0 });
+                break;
+            case 'accept':
+                this.send({ type: 'crew_accept', item: arg || this.pendingInvite });
+                break;
+            case 'create': case 'invite': case 'kick': case 'promote': case 'demote':
+            case 'leave': case 'disband': case 'info':
+                this.send({ type: 'crew_' + action, item: arg });
+                break;
+            default:
+                this.log(`Unknown crew command: ${action}`);
+        }
+        return true;
+    }
+
+    // Returns true if the message was a crew message
+    handleMessage(msg) {
+        switch (msg.type) {
+            case 'crew_update':
+                this.crew = msg.crew;
+                if (!msg.crew) {
+                    this.log('You are not in a crew.');
+                } else {
+                    const roster = msg.crew.members
+                        .map(m => `${m.id} (${m.rank}${m.online ? '' : ', offline'})`)
+                        .join(', ');
+                    this.log(`Crew ${msg.crew.name} - Bank $${msg.crew.bank} - ${roster}`);
+                }
+                return true;
+            case 'crew_invite':
+                this.pendingInvite = msg.crew;
+                this.log(`${msg.from} invited you to ${msg.crew}. Type /crew accept to join.`);
+                return true;
+            case 'crew_chat':
+                this.log(`[${msg.crew}] ${msg.id}: ${msg.item}`, '#7CFC00');
+                return true;
+            case 'crew_error':
+                this.log(`Crew error: ${msg.reason}`);
+                return true;
+        }
+        return false;
+    }
+
+    log(text, color = 'orange') {
+        const chatBox = document.getElementById('chat-messages');
+        if (!chatBox) return;
+        const line = document.createElement('div');
+        line.style.color = color;
+        line.textContent = text;
+        chatBox.appendChild(line);
+        chatBox.scrollTop = chatBox.scrollHeight;
+    }
+}
@@ -37,6 +37,14 @@ export class InteractionSystem {
         this.interactables.push(object);
     }
 
+    unregister(object) {
+        this.interactables = this.interactables.filter(obj => obj !== object);
+        if (this.hoveredObject === object) {
+            this.hoveredObject = null;
+            this.hintDiv.style.display = 'none';
+        }
+    }
+
     update() {
         // Simple distance check first (optimisation)
         // Then raycast or proximity check
@@ -0,0 +1,54 @@
+// Leaderboards. Boards are read from /api/leaderboard; the server pushes
+// leaderboard_update when a top 10 changes.
+// Chat commands: /top [bounty|honor|level|money] [all|daily|weekly]
+export class LeaderboardSystem {
+    constructor() {
+        this.watching = null; // "stat/period" last shown in chat
+    }
+
+    // Returns true if the chat line was a leaderboard command
+    handleCommand(text) {
+        if (!text.startsWith('/top')) return false;
+        const [, stat = 'bounty', period = 'all'] = text.split(' ');
+        this.show(stat, period);
+        return true;
+    }
+
+    async show(stat, period) {
+        try {
+            const res = await fetch(`/api/leaderboard?stat=${encodeURIComponent(stat)}&period=${encodeURIComponent(period)}`);
+            const data = await res.json();
+            if (!res.ok) {
+                this.log(`Leaderboard error: ${data.error}`);
+                return;
+            }
+            this.watching = `${stat}/${period}`;
+            this.print(stat, period, data.entries);
+        } catch (e) {
+            this.log('Leaderboard unavailable.');
+        }
+    }
+
+    handleMessage(msg) {
+        if (msg.type !== 'leaderboard_update') return false;
+        if (this.watching === `${msg.stat}/${msg.period}`) {
+            this.print(msg.stat, msg.period, msg.entries);
+        }
+        return true;
+    }
+
+    print(stat, period, entries) {
+        this.log(`Top ${stat} (${period}):`);
+        (entries || []).forEach(e => this.log(`${e.rank}. ${e.username} - ${e.score}`));
+    }
+
+    log(text) {
+        const chatBox = document.getElementById('chat-messages');
+        if (!chatBox) return;
+        const line = document.createElement('div');
+        line.style.color = 'gold';
+        line.textContent = text;
+        chatBox.appendChild(line);
+        chatBox.scrollTop = chatBox.scrollHeight;
+    }
+}
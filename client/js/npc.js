@@ -17,12 +17,13 @@ export class NPCSystem {
     registerNPC(mesh) {
         if (!this.interactionSystem) return;
 
+        // Quest givers list their quests from the server catalog
         this.interactionSystem.register(mesh, () => {
-            alert("Hello! I am " + mesh.userData.name + ". Would you like a quest?");
+            window.openQuestDialog(mesh.userData.questGiver, mesh.userData.name);
         }, "Talk to " + mesh.userData.name);
     }
 
-    spawnNPC(name, x, z) {
+    spawnNPC(name, x, z, questGiver = null) {
         // Use Humanoid Model
         const mesh = ModelFactory.createHumanoid(0xffd700); // Gold shirt
         mesh.position.set(x, 0, z); // Factory handles Y offset
@@ -30,7 +31,8 @@ export class NPCSystem {
 
         mesh.userData = {
             type: "npc",
-            name: name
+            name: name,
+            questGiver: questGiver // NPC id in server/quests.json
         };
 
         this.scene.add(mesh);
@@ -39,5 +41,6 @@ export class NPCSystem {
         this.registerNPC(mesh);
 
         console.log(`Spawned NPC: ${name} at ${x}, ${z}`);
+        return mesh;
     }
 }
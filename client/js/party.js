@@ -0,0 +1,90 @@
+// Parties. The server owns membership and kill sharing; this system sends
+// the party actions and draws the party frame from party_frame messages.
+// Chat commands: /party invite <player>, /party accept, /party kick <player>, /party leave
+export class PartySystem {
+    constructor(getSocket) {
+        this.getSocket = getSocket;
+        this.party = null;
+
+        this.frame = document.createElement('div');
+        this.frame.id = 'party-frame';
+        Object.assign(this.frame.style, {
+            position: 'absolute', top: '200px', left: '20px', width: '160px',
+            fontFamily: 'Arial', fontSize: '12px', color: 'white', display: 'none'
+        });
+        document.body.appendChild(this.frame);
+    }
+
+    send(payload) {
+        const socket = this.getSocket();
+        if (socket && socket.readyState === WebSocket.OPEN) {
+            socket.send(JSON.stringify(payload));
+        }
+    }
+
+    // Returns true if the chat line was a party command
+    handleCommand(text) {
+        if (!text.startsWith('/party')) return false;
+        const [, action = '', arg = ''] = text.split(' ');
+        if (['invite', 'accept', 'kick', 'leave'].includes(action)) {
+            this.send({ type: 'party_' + action, item: arg });
+        } else {
+            this.log('Party commands: /party invite <player>, /party accept, /party kick <player>, /party leave');
+        }
+        return true;
+    }
+
+    // Returns true if the message was a party message
+    handleMessage(msg, myID) {
+        switch (msg.type) {
+            case 'party_invite':
+                this.log(`${msg.from} invited you to a party. Type /party accept to join.`);
+                return true;
+            case 'party_update':
+                this.party = msg.party;
+                if (!msg.party) {
+                    this.frame.style.display = 'none';
+                    this.log('You left the party.');
+                } else {
+                    this.log(`Party: ${msg.party.members.join(', ')} (leader ${msg.party.leader})`);
+                }
+                return true;
+            case 'party_frame':
+                this.drawFrame(msg.members, myID);
+                return true;
+            case 'party_error':
+                this.log(`Party error: ${msg.reason}`);
+                return true;
+        }
+        return false;
+    }
+
+    drawFrame(members, myID) {
+        this.frame.innerHTML = '';
+        members.filter(m => m.id !== myID).forEach(m => {
+            const row = document.createElement('div');
+            row.style.marginBottom = '6px';
+            const name = document.createElement('div');
+            name.textContent = `${m.id} Lv.${m.level}`;
+            const bar = document.createElement('div');
+            Object.assign(bar.style, { height: '6px', background: 'rgba(0,0,0,0.5)' });
+            const fill = document.createElement('div');
+            const pct = m.maxHealth > 0 ? Math.max(0, m.health / m.maxHealth) * 100 : 0;
+            Object.assign(fill.style, { height: '100%', width: pct + '%', background: '#2ecc71' });
+            bar.appendChild(fill);
+            row.append(name, bar);
+            this.frame.appendChild(row);
+        });
+        this.frame.style.display = this.frame.children.length ? 'block' : 'none';
+    }
+
+    log(text) {
+        const chatBox = document.getElementById('chat-messages');
+        if (!chatBox) return;
+        const line = document.createElement('div');
+        line.style.color = 'orange';
+        line.textContent = text;
+        chatBox.appendChild(line);
+        chatBox.scrollTop = chatBox.scrollHeight;
+    }
+}
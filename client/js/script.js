@@ -9,6 +9,10 @@ import { NPCSystem } from './npc.js';
 import { ModelFactory } from './models.js';
 import { HitSystem } from './hit_system.js';
 import { BossSystem } from './boss.js';
+import { TradeSystem } from './trade.js';
+import { CrewSystem } from './crew.js';
+import { PartySystem } from './party.js';
+import { LeaderboardSystem } from './leaderboard.js';
 import { InteractionSystem } from './interaction.js';
 import { BoatSystem } from './boats.js';
 import { SkillSystem } from './skills.js';
@@ -131,6 +135,10 @@ export function updateUI() {
     if (statSword) statSword.innerText = p.statSword || 1;
     const statFruit = document.getElementById('stat-fruit');
     if (statFruit) statFruit.innerText = p.statFruit || 1;
+    const statGun = document.getElementById('stat-gun');
+    if (statGun) statGun.innerText = p.statGun || 1;
+    const statPoints = document.getElementById('stat-points');
+    if (statPoints) statPoints.innerText = p.statPoints || 0;
 
     const mText = document.getElementById('money-display'); // Fixed ID
     if (mText) mText.innerText = "$ " + p.money;
@@ -143,6 +151,8 @@ export function updateUI() {
 
     const bDisplay = document.getElementById('bounty-display');
     if (bDisplay) bDisplay.innerText = "Bounty: " + (p.bounty || 0);
+    const hDisplay = document.getElementById('honor-display');
+    if (hDisplay) hDisplay.innerText = "Honor: " + (p.honor || 0);
 
     // Call secondary updates
     if (typeof window.updateSecondaryUI === 'function') window.updateSecondaryUI();
@@ -386,13 +396,65 @@ window.buyFruit = function () {
 }
 
 // Quest Functions
-window.acceptQuest = function () {
+// The server answers with quest_list, which fills in the dialog
+let dialogNpc = null;
+
+window.openQuestDialog = function (npcId, name) {
+    dialogNpc = npcId;
+    document.getElementById('npc-name').innerText = name;
+    document.getElementById('npc-text').innerText = "Let me see what I have for you...";
+    document.getElementById('dialog-options').innerHTML = '<button onclick="closeDialog()">Decline</button>';
+    document.getElementById('dialog-ui').classList.remove('hidden');
     if (socket && socket.readyState === WebSocket.OPEN) {
-        socket.send(JSON.stringify({ type: 'accept_quest', item: 'gorilla_quest' }));
+        socket.send(JSON.stringify({ type: 'list_quests', item: npcId }));
+    }
+}
+
+window.turnInQuest = function (questId) {
+    if (socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({ type: 'turn_in_quest', item: questId }));
         closeDialog();
     }
 }
 
+window.acceptQuest = function (questId) {
+    if (socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({ type: 'accept_quest', item: questId }));
+        closeDialog();
+    }
+}
+
+function showQuestList(quests) {
+    const text = document.getElementById('npc-text');
+    const options = document.getElementById('dialog-options');
+    options.innerHTML = '';
+    if (!quests || quests.length === 0) {
+        text.innerText = "I have nothing for you right now. Come back later.";
+    } else {
+        text.innerText = "Pick a job:";
+        quests.forEach(q => {
+            const btn = document.createElement('button');
+            btn.innerText = `${q.name} (Lv ${q.minLevel}-${q.maxLevel})`;
+            btn.title = q.description;
+            btn.onclick = () => window.acceptQuest(q.id);
+            options.appendChild(btn);
+        });
+    }
+    // Collect quests are handed in to the NPC that gave them
+    (gameState.player.quests || [])
+        .filter(q => q.type === 'collect' && q.npc === dialogNpc && q.current >= q.targetCount)
+        .forEach(q => {
+            const btn = document.createElement('button');
+            btn.innerText = `Turn in: ${q.name}`;
+            btn.onclick = () => window.turnInQuest(q.id);
+            options.appendChild(btn);
+        });
+    const close = document.createElement('button');
+    close.innerText = "Close";
+    close.onclick = closeDialog;
+    options.appendChild(close);
+}
+
 window.closeDialog = function () {
     document.getElementById('dialog-ui').classList.add('hidden');
 }
@@ -449,13 +511,25 @@ window.adminAction = function (action) {
     }));
 }
 
+// Spend an unspent stat point; the server validates and the next state sync updates the panel
+window.allocateStat = function (stat) {
+    if (!socket || socket.readyState !== WebSocket.OPEN) return;
+    socket.send(JSON.stringify({ type: 'allocate_stat', item: stat, amount: 1 }));
+}
+
 // Chat Logic
 document.getElementById('chat-input')?.addEventListener('keydown', (e) => {
     if (e.key === 'Enter') {
         const input = e.target;
         const msg = input.value.trim();
         if (msg) {
-            socket.send(JSON.stringify({ type: 'chat', item: msg })); // Using 'item' for message content
+            const handled = handlePvPCommand(msg) || handleSpawnCommand(msg) ||
+                (window.crewSystem && window.crewSystem.handleCommand(msg)) ||
+                (window.partySystem && window.partySystem.handleCommand(msg)) ||
+                (window.leaderboardSystem && window.leaderboardSystem.handleCommand(msg));
+            if (!handled) {
+                socket.send(JSON.stringify({ type: 'chat', item: msg })); // Using 'item' for message content
+            }
             input.value = '';
             // Unfocus to return control to game? Or keep focus?
             // Users usually want to keep chatting or click away.
@@ -501,6 +575,11 @@ function init(token, roomID) {
         scene.particleSystem = particleSystem;
 
         window.boatSystem = boatSystem; // Global access for loop
+        window.bossSystem = bossSystem;
+        window.tradeSystem = new TradeSystem(() => socket);
+        window.crewSystem = new CrewSystem(() => socket);
+        window.partySystem = new PartySystem(() => socket);
+        window.leaderboardSystem = new LeaderboardSystem();
         window.skillSystem = skillSystem;
         window.weatherSystem = weatherSystem;
         window.ghostEffect = ghostEffect;
@@ -539,14 +618,12 @@ function init(token, roomID) {
         fruitSystem.spawnFruit("Fire", 5, 5);
         fruitSystem.spawnFruit("Ice", -5, 5);
 
-        // Test Spawn NPC
-        const questGiver = npcSystem.spawnNPC("Quest Giver", 0, 8);
-
-        if (questGiver && window.interactionSystem) {
-            window.interactionSystem.register(questGiver, () => {
-                document.getElementById('dialog-ui').classList.remove('hidden');
-            }, "Talk to Quest Giver");
-        }
+        // Quest givers, matching the npcs in server/quests.json
+        [
+            { id: 'quest_giver', name: "Quest Giver", x: 0, z: 8 },
+            { id: 'jungle_scout', name: "Jungle Scout", x: -40, z: -40 },
+            { id: 'snow_captain', name: "Snow Captain", x: 45, z: 45 },
+        ].forEach(npc => npcSystem.spawnNPC(npc.name, npc.x, npc.z, npc.id));
 
         // Weapon Dealer
         const dealer = npcSystem.spawnNPC("Weapon Dealer", 10, 10);
@@ -557,9 +634,7 @@ function init(token, roomID) {
             }, "Open Shop");
         }
 
-        // Spawn Bosses
-        bossSystem.spawnBoss("Gorilla King", -50, -50);
-        bossSystem.spawnBoss("Ice Admiral", 50, 50);
+        // Bosses are spawned by the server and arrive via mob_update
 
         // --- Fruit Spawner ---
         // Spawn every 60 seconds
@@ -698,28 +773,80 @@ const originalOnMessage = (event) => {
             banner.innerText = "Event: " + msg.name;
             banner.classList.remove('hidden');
         }
+    } else if (msg.type === 'quest_list') {
+        showQuestList(msg.quests);
     } else if (msg.type === 'quest_update') {
         // Update Quest HUD
-        gameState.player.activeQuest = msg.activeQuest;
+        gameState.player.quests = msg.quests || [];
+        gameState.player.completedQuests = msg.completed || {};
         gameState.player.money = msg.money; // Sync money reward
-        // Also sync EXP if we had it in gameState
-
-        updateQuestUI();
-
-        if (!msg.activeQuest) {
-            // Quest Completed or None
-            document.getElementById('quest-hud').classList.add('hidden');
-            // Check if it was a completion (money went up significantly)
-            // For now just hide it.
-            if (msg.money > (gameState.player.money || 0)) {
-                alert("Quest Completed! Reward Received.");
-            }
-        } else {
-            document.getElementById('quest-hud').classList.remove('hidden');
-        }
-        document.getElementById('quest-hud').classList.remove('hidden');
+        updateUI();
     } else if (msg.type === 'mob_update') {
         updateMobs(msg.mobs);
+    } else if (msg.type === 'loot_drop') {
+        (msg.drops || []).forEach(addLootDrop);
+    } else if (msg.type === 'loot_removed') {
+        removeLootDrop(msg.id);
+    } else if (msg.type === 'level_up') {
+        gameState.player.level = msg.level;
+        gameState.player.statPoints = msg.statPoints;
+        const banner = document.getElementById('event-banner');
+        if (banner) {
+            banner.innerText = `Level Up! You are now level ${msg.level}`;
+            banner.classList.remove('hidden');
+        }
+    } else if (msg.type === 'teleport') {
+        // Server rejected or overrode our position
+        if (myPlayerMesh) myPlayerMesh.position.set(msg.x, msg.y, msg.z);
+        if (msg.msg) systemMessage(msg.msg);
+    } else if (msg.type === 'cooldown') {
+        if (window.skillSystem) window.skillSystem.setCooldown(msg.ability, msg.duration);
+    } else if (msg.type === 'update_stats') {
+        gameState.player.money = msg.money;
+        gameState.player.inventory = msg.inventory;
+        if (msg.currentFruit !== undefined) gameState.player.currentFruit = msg.currentFruit;
+        updateSecondaryUI();
+    } else if (msg.type === 'player_died') {
+        gameState.player.dead = true;
+        showDeathScreen(msg);
+    } else if (msg.type === 'respawn_point') {
+        showRespawnOptions(msg.respawnPoint, msg.points);
+    } else if (msg.type === 'player_respawned') {
+        gameState.player.dead = false;
+        clearInterval(gameState.deathTimer);
+        document.getElementById('death-ui')?.classList.add('hidden');
+    } else if (msg.type === 'pvp_status') {
+        gameState.player.pvp = msg.pvp;
+        systemMessage(msg.reason ? `PvP: ${msg.reason}` : `PvP is now ${msg.pvp ? 'ON' : 'OFF'}`);
+    } else if (msg.type === 'leaderboard_update') {
+        if (window.leaderboardSystem) window.leaderboardSystem.handleMessage(msg);
+    } else if (msg.type.startsWith('party_')) {
+        if (window.partySystem) window.partySystem.handleMessage(msg, gameState.myID);
+    } else if (msg.type.startsWith('crew_')) {
+        if (window.crewSystem) window.crewSystem.handleMessage(msg);
+    } else if (msg.type.startsWith('trade_')) {
+        if (window.tradeSystem) window.tradeSystem.handleMessage(msg, gameState.myID);
+        if (msg.type === 'trade_complete') {
+            gameState.player.money = msg.money;
+            gameState.player.inventory = msg.inventory;
+            updateSecondaryUI();
+        }
+    } else if (msg.type === 'boss_telegraph') {
+        if (window.bossSystem) window.bossSystem.showTelegraph(msg);
+    } else if (msg.type === 'boss_spawn' || msg.type === 'boss_phase' || msg.type === 'boss_enrage' || msg.type === 'boss_defeated') {
+        const banner = document.getElementById('event-banner');
+        if (banner) {
+            const mob = gameState.mobs && gameState.mobs[msg.mobId];
+            const name = msg.mobType || (mob ? mob.data.type : "Boss");
+            const text = {
+                boss_spawn: `${name} has appeared!`,
+                boss_phase: `${name} enters phase ${msg.phase + 1}!`,
+                boss_enrage: `${name} is enraged!`,
+                boss_defeated: `${name} has been defeated!`
+            }[msg.type];
+            banner.innerText = text;
+            banner.classList.remove('hidden');
+        }
     } else if (msg.type === 'chat') {
         const chatBox = document.getElementById('chat-messages');
         if (chatBox) {
@@ -771,6 +898,135 @@ window.originalOnMessage = originalOnMessage;
 
 
 
+// /pvp on|off. Switching has a cooldown, and combat blocks opting out.
+function handlePvPCommand(text) {
+    if (!text.startsWith('/pvp')) return false;
+    const arg = text.split(' ')[1];
+    if (arg === 'on' || arg === 'off') {
+        socket.send(JSON.stringify({ type: 'set_pvp', item: arg }));
+    } else {
+        systemMessage(`PvP is ${gameState.player.pvp ? 'ON' : 'OFF'}. Use /pvp on or /pvp off.`);
+    }
+    return true;
+}
+
+// /setspawn makes the island you stand on your respawn point
+function handleSpawnCommand(text) {
+    if (text !== '/setspawn') return false;
+    socket.send(JSON.stringify({ type: 'set_spawn' }));
+    return true;
+}
+
+// Closing the tab mid-fight leaves the character in the world until the
+// combat tag runs out
+window.addEventListener('beforeunload', (e) => {
+    if (gameState.player.combatTagEnd > Date.now()) {
+        e.preventDefault();
+        e.returnValue = '';
+    }
+});
+
+const EFFECT_ICONS = {
+    stun: '💫', slow: '🐌', poison: '☠️', burn: '🔥', charm: '💘', freeze: '❄️', shield: '🛡️', damage_buff: '⚔️'
+};
+
+function isDisabled(effects) {
+    return (effects || []).some(e => ['stun', 'freeze', 'charm'].includes(e.kind));
+}
+
+// Status effect icons under the health bar, with stacks and time left
+function updateStatusEffectsUI(effects) {
+    let bar = document.getElementById('status-effects');
+    if (!bar) {
+        bar = document.createElement('div');
+        bar.id = 'status-effects';
+        Object.assign(bar.style, {
+            position: 'absolute', top: '120px', left: '20px', display: 'flex', gap: '6px',
+            fontFamily: 'Arial', fontSize: '12px', color: 'white'
+        });
+        document.body.appendChild(bar);
+    }
+    bar.innerHTML = '';
+    effects.forEach(e => {
+        const icon = document.createElement('span');
+        const secs = Math.max(0, Math.ceil((e.expiresAt - Date.now()) / 1000)); // Server clock may differ
+        icon.title = e.kind + (e.source ? ` (${e.source})` : '');
+        icon.textContent = `${EFFECT_ICONS[e.kind] || e.kind}${e.stacks > 1 ? 'x' + e.stacks : ''} ${secs}s`;
+        bar.appendChild(icon);
+    });
+}
+
+// Death screen with a respawn countdown and the respawn point choice
+function showDeathScreen(msg) {
+    const ui = document.getElementById('death-ui');
+    const text = document.getElementById('death-text');
+    if (!ui || !text) return;
+    const penalty = msg.moneyLost || msg.expLost ? ` Lost $${msg.moneyLost} and ${msg.expLost} exp.` : '';
+    const respawnAt = Date.now() + msg.delay; // Server clock may differ
+    const tick = () => {
+        const secs = Math.max(0, Math.ceil((respawnAt - Date.now()) / 1000));
+        text.innerText = `Killed by ${msg.killer}.${penalty} Respawning in ${secs}s`;
+    };
+    tick();
+    clearInterval(gameState.deathTimer);
+    gameState.deathTimer = setInterval(tick, 250);
+    showRespawnOptions(msg.respawnPoint, msg.points);
+    ui.classList.remove('hidden');
+}
+
+function showRespawnOptions(selected, points) {
+    const options = document.getElementById('respawn-options');
+    if (!options || !points) return;
+    options.innerHTML = '';
+    for (const [point, island] of Object.entries(points)) {
+        const btn = document.createElement('button');
+        btn.innerText = (point === 'spawn' ? 'Spawn: ' : 'Last island: ') + island;
+        if (point === selected) btn.style.outline = '2px solid gold';
+        btn.onclick = () => socket.send(JSON.stringify({ type: 'set_respawn_point', item: point }));
+        options.appendChild(btn);
+    }
+}
+
+// Server notices shown in the chat box
+function systemMessage(text) {
+    const chatBox = document.getElementById('chat-messages');
+    if (!chatBox) return;
+    const line = document.createElement('div');
+    line.style.color = 'orange';
+    line.textContent = text;
+    chatBox.appendChild(line);
+    chatBox.scrollTop = chatBox.scrollHeight;
+}
+
+// Ground loot sent by the server; owners receive it before the rest of the room
+function addLootDrop(drop) {
+    if (!gameState.drops) gameState.drops = {};
+    if (gameState.drops[drop.id]) return;
+
+    const color = { money: 0xffd700, weapon: 0xaaaaaa, fruit: 0xff55ff, material: 0x8b5a2b }[drop.kind] || 0xffffff;
+    const mesh = new THREE.Mesh(new THREE.BoxGeometry(0.5, 0.5, 0.5), new THREE.MeshLambertMaterial({ color: color }));
+    mesh.position.set(drop.x, 0.5, drop.z);
+    scene.add(mesh);
+
+    const label = drop.kind === 'money' ? `Pick up $${drop.quantity}` : `Pick up ${drop.item} x${drop.quantity}`;
+    if (window.interactionSystem) {
+        window.interactionSystem.register(mesh, () => {
+            if (socket && socket.readyState === WebSocket.OPEN) {
+                socket.send(JSON.stringify({ type: 'pickup_item', item: drop.id }));
+            }
+        }, label);
+    }
+    gameState.drops[drop.id] = mesh;
+}
+
+function removeLootDrop(id) {
+    if (!gameState.drops || !gameState.drops[id]) return;
+    const mesh = gameState.drops[id];
+    if (window.interactionSystem) window.interactionSystem.unregister(mesh);
+    scene.remove(mesh);
+    delete gameState.drops[id];
+}
+
 function updateMobs(mobsData) {
     if (!gameState.mobs) gameState.mobs = {};
 
@@ -783,6 +1039,9 @@ function updateMobs(mobsData) {
             let mesh;
             if (mData.type === "Gorilla") {
                 mesh = ModelFactory.createGorilla();
+            } else if (mData.type === "Gorilla King") {
+                mesh = ModelFactory.createGorilla();
+                mesh.scale.multiplyScalar(1.5);
             } else if (mData.type === "Ice Admiral") {
                 mesh = ModelFactory.createIceAdmiral();
             } else {
@@ -810,6 +1069,10 @@ function updateMobs(mobsData) {
             m.data = mData; // Update ref
             m.mesh.position.lerp(_reusableVec.set(mData.x, mData.y, mData.z), 0.1);
         }
+
+        if (mData.isBoss && window.bossSystem) {
+            window.bossSystem.updateBoss(gameState.mobs[id].mesh, mData);
+        }
     }
 
     // Remove Dead
@@ -837,6 +1100,32 @@ function updateWorldState(serverPlayers) {
             if (combat && serverPlayers[id].health < gameState.player.health) {
                 // Took damage logic
             }
+            // Server owns the resource bars
+            gameState.player.health = serverPlayers[id].health;
+            gameState.player.maxHealth = serverPlayers[id].maxHealth;
+            gameState.player.energy = serverPlayers[id].energy;
+            gameState.player.maxEnergy = serverPlayers[id].maxEnergy;
+            gameState.player.level = serverPlayers[id].level;
+            gameState.player.statPoints = serverPlayers[id].statPoints;
+            gameState.player.pvp = serverPlayers[id].pvp;
+            gameState.player.combatTagEnd = serverPlayers[id].combatTagEnd || 0;
+            gameState.player.dead = serverPlayers[id].dead;
+            gameState.player.effects = serverPlayers[id].effects || [];
+            updateStatusEffectsUI(gameState.player.effects);
+            if (gameState.player.quests === undefined) {
+                // Restore the saved quest log; quest_update keeps it current
+                gameState.player.quests = serverPlayers[id].quests || [];
+            }
+            const stats = serverPlayers[id].stats;
+            if (stats) {
+                // Panel shows 1 for an empty stat, matching the old client-side values
+                gameState.player.statMelee = stats.melee + 1;
+                gameState.player.statDefense = stats.defense + 1;
+                gameState.player.statSword = stats.sword + 1;
+                gameState.player.statGun = stats.gun + 1;
+                gameState.player.statFruit = stats.fruit + 1;
+            }
+            updateUI();
             // Update our own player's Haki state
             if (myPlayerMesh.userData.arms) {
                 const armColor = serverPlayers[id].hakiActive ? 0x111111 : 0xffccaa; // Black or Skin
@@ -932,8 +1221,13 @@ function updatePlayerList(players) {
         nameSpan.style.fontWeight = "bold";
 
         const bountySpan = document.createElement('span');
-        bountySpan.innerText = "$" + (p.bounty || 0); // Or Level?
-        bountySpan.style.color = "#ffff00";
+        if (p.team === 'marine') {
+            bountySpan.innerText = "Honor " + (p.honor || 0);
+            bountySpan.style.color = "#7fd4ff";
+        } else {
+            bountySpan.innerText = "$" + (p.bounty || 0); // Or Level?
+            bountySpan.style.color = "#ffff00";
+        }
 
         row.appendChild(nameSpan);
         row.appendChild(bountySpan);
@@ -1049,6 +1343,8 @@ function setupScene() {
 
     function updatePlayer(deltaTime) {
         if (!gameState.isPlaying) return;
+        if (gameState.player.dead) return; // Frozen until the server respawns us
+        if (isDisabled(gameState.player.effects)) return; // Stunned, frozen or charmed
 
         // Use new Character Controller
         if (characterController) {
@@ -1210,30 +1506,87 @@ function updateSecondaryUI() {
     const hotbar = document.getElementById('hotbar-container');
     if (hotbar) {
         hotbar.replaceChildren();
-        (gameState.player.inventory || []).forEach(item => {
+        (gameState.player.inventory || []).forEach(stack => {
+            // Stacks are { id, qty }; older servers sent plain item names
+            const item = typeof stack === 'string' ? stack : stack.id;
+            const qty = typeof stack === 'string' ? 1 : stack.qty;
             const slot = document.createElement('div');
             slot.className = 'hotbar-slot';
-            slot.innerText = item.charAt(0).toUpperCase();
+            slot.innerText = item.charAt(0).toUpperCase() + (qty > 1 ? ` x${qty}` : '');
             slot.title = item;
             slot.onclick = () => {
                 if (socket && socket.readyState === WebSocket.OPEN) {
-                    socket.send(JSON.stringify({ type: 'set_weapon', weapon: item }));
+                    // Fruits have to be eaten before their abilities can be used
+                    const type = item.endsWith(' Fruit') ? 'eat_fruit' : 'set_weapon';
+                    socket.send(JSON.stringify({ type: type, weapon: item, item: item }));
                 }
             };
             hotbar.appendChild(slot);
         });
+
+        // The eaten fruit no longer sits in the inventory
+        const fruit = gameState.player.currentFruit;
+        if (fruit) {
+            const slot = document.createElement('div');
+            slot.className = 'hotbar-slot';
+            slot.innerText = fruit.charAt(0).toUpperCase() + '*';
+            slot.title = fruit + ' (eaten)';
+            slot.onclick = () => {
+                if (socket && socket.readyState === WebSocket.OPEN) {
+                    socket.send(JSON.stringify({ type: 'set_weapon', weapon: fruit }));
+                }
+            };
+            hotbar.appendChild(slot);
+        }
     }
 }
 
-function updateQuestUI() {
-    const q = gameState.player.activeQuest;
-    const qt = document.getElementById('quest-title');
-    const qp = document.getElementById('quest-progress');
+function questProgressText(q) {
+    if (q.type === 'reach') {
+        return `Travel to (${q.x}, ${q.z})`;
+    } else if (q.type === 'escort') {
+        const left = Math.max(0, Math.ceil((q.expiresAt - Date.now()) / 1000));
+        return `Lead the ${q.target} to (${q.x}, ${q.z}) - ${left}s`;
+    } else if (q.type === 'collect' && q.npc && q.current >= q.targetCount) {
+        return `${q.targetCount} ${q.target}s - return to the quest giver`;
+    }
+    return `${q.current}/${q.targetCount} ${q.target}s`;
+}
 
-    if (q && qt && qp) {
-        qt.innerText = q.name;
-        qp.innerText = `${q.current}/${q.targetCount} ${q.target}s`;
+window.abandonQuest = function (questId) {
+    if (socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({ type: 'abandon_quest', item: questId }));
     }
 }
 
+function updateQuestUI() {
+    const hud = document.getElementById('quest-hud');
+    if (!hud) return;
+    const quests = gameState.player.quests || [];
+
+    hud.innerHTML = '';
+    quests.forEach(q => {
+        const entry = document.createElement('div');
+        entry.className = 'quest-entry';
+
+        const abandon = document.createElement('button');
+        abandon.className = 'quest-abandon';
+        abandon.innerText = 'X';
+        abandon.title = 'Abandon quest';
+        abandon.onclick = () => window.abandonQuest(q.id);
+
+        const title = document.createElement('h3');
+        title.innerText = q.name;
+        const progress = document.createElement('p');
+        progress.innerText = questProgressText(q);
+        const rewards = document.createElement('div');
+        rewards.className = 'quest-rewards';
+        rewards.innerText = `$${q.rewardMoney} | ${q.rewardExp} XP`;
+
+        entry.append(abandon, title, progress, rewards);
+        hud.appendChild(entry);
+    });
+    hud.classList.toggle('hidden', quests.length === 0);
+}
+
 // window.onload = init; // Now handled by Login Button via startGame()
\ No newline at end of file
@@ -3,6 +3,15 @@ export class SkillSystem {
         this.scene = scene;
         this.playerMesh = playerMesh;
         this.projectiles = [];
+        this.cooldowns = {}; // Ability -> local time it is ready again, from the server
+    }
+
+    setCooldown(ability, duration) {
+        this.cooldowns[ability] = Date.now() + duration;
+    }
+
+    isReady(ability) {
+        return !this.cooldowns[ability] || Date.now() >= this.cooldowns[ability];
     }
 
     castSkill(key) {
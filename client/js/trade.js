@@ -0,0 +1,71 @@
+// Player-to-player trading. The server owns the session and validates every
+// step; this system only sends the protocol messages and shows the state.
+// Usage from the console: tradeSystem.request('bob'), tradeSystem.offer({ katana: 1 }, 500)
+export class TradeSystem {
+    constructor(getSocket) {
+        this.getSocket = getSocket;
+        this.session = null;
+        this.pendingFrom = null;
+    }
+
+    send(payload) {
+        const socket = this.getSocket();
+        if (socket && socket.readyState === WebSocket.OPEN) {
+            socket.send(JSON.stringify(payload));
+        }
+    }
+
+    request(playerId) { this.send({ type: 'trade_request', item: playerId }); }
+    accept() { this.send({ type: 'trade_accept' }); }
+    offer(items, money) { this.send({ type: 'trade_offer', items: items || {}, money: money || 0 }); }
+    lock() { this.send({ type: 'trade_lock' }); }
+    confirm() { this.send({ type: 'trade_confirm' }); }
+    cancel() { this.send({ type: 'trade_cancel' }); }
+
+    // Returns true if the message was a trade message
+    handleMessage(msg, myID) {
+        switch (msg.type) {
+            case 'trade_request':
+                this.pendingFrom = msg.from;
+                this.log(`${msg.from} wants to trade. Use tradeSystem.accept() to accept.`);
+                return true;
+            case 'trade_update':
+                this.session = msg.trade;
+                this.log(this.describe(msg.trade, myID));
+                return true;
+            case 'trade_complete':
+                this.session = null;
+                this.log('Trade complete.');
+                return true;
+            case 'trade_cancelled':
+                this.session = null;
+                this.log(`Trade cancelled: ${msg.reason}`);
+                return true;
+            case 'trade_error':
+                this.log(`Trade error: ${msg.reason}`);
+                return true;
+        }
+        return false;
+    }
+
+    describe(trade, myID) {
+        const fmt = (id) => {
+            const o = trade.offers[id] || {};
+            const items = Object.entries(o.items || {}).map(([k, v]) => `${v}x ${k}`);
+            if (o.money) items.push(`$${o.money}`);
+            const flags = (trade.locked[id] ? ' [locked]' : '') + (trade.confirmed[id] ? ' [confirmed]' : '');
+            return `${id === myID ? 'You' : id}: ${items.join(', ') || 'nothing'}${flags}`;
+        };
+        return `Trade - ${fmt(trade.initiator)} | ${fmt(trade.partner)}`;
+    }
+
+    log(text) {
+        const chatBox = document.getElementById('chat-messages');
+        if (!chatBox) return;
+        const line = document.createElement('div');
+        line.style.color = 'orange';
+        line.textContent = text;
+        chatBox.appendChild(line);
+        chatBox.scrollTop = chatBox.scrollHeight;
+    }
+}
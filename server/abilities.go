@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	// defaultGlobalCooldown is the lockout a fruit ability puts on the
+	// player's other GCD abilities, so a Z/X/C/V combo can't land in a
+	// single tick.
+	defaultGlobalCooldown = 250 // ms
+
+	baseMaxEnergy     = 100
+	energyRegenPerSec = 8.0
+	defaultEnergyCost = 15
+)
+
+// AbilityConfig holds the timing rules of a weapon attack or fruit ability,
+// and for fruit abilities the damage of a hit.
+type AbilityConfig struct {
+	Cooldown       int64 // ms before the same ability can be used again
+	GlobalCooldown int64 // ms the use locks out other GCD abilities
+	OffGlobal      bool  // Neither triggers nor waits on the global cooldown
+	EnergyCost     int
+	Damage         int // Before stats and Haki; weapons use getWeaponDamage
+}
+
+// abilityConfigs are keyed by weapon or ability name. Weapons are off the
+// global cooldown so they can be swung between fruit casts. Every ability
+// in fruitAbilities needs an entry here.
+var abilityConfigs = map[string]AbilityConfig{
+	// Weapons
+	"melee":   {Cooldown: 500, OffGlobal: true},
+	"katana":  {Cooldown: 600, OffGlobal: true},
+	"cutlass": {Cooldown: 700, OffGlobal: true},
+	"pipe":    {Cooldown: 1000, OffGlobal: true},
+	"bazooka": {Cooldown: 2000, OffGlobal: true},
+
+	// Fruit abilities
+	"Fireball":       {Cooldown: 1000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 40},
+	"FlamePillar":    {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 60},
+	"FireBullet":     {Cooldown: 800, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 10, Damage: 25},
+	"FirePillar":     {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 60},
+	"IceShards":      {Cooldown: 800, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 10, Damage: 25},
+	"IceSurge":       {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 50},
+	"IceSpear":       {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 40},
+	"IceAge":         {Cooldown: 5000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 40, Damage: 90},
+	"LightSpeed":     {Cooldown: 1500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 80},
+	"LightBeam":      {Cooldown: 1500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 50},
+	"LightFlight":    {Cooldown: 2500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 40},
+	"Transform":      {Cooldown: 5000, GlobalCooldown: 1000, EnergyCost: 40, Damage: 100}, // Buddha and the beasts
+	"LoveBeam":       {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 30},
+	"MellowShot":     {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 35},
+	"LoveZone":       {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 55},
+	"MagmaRain":      {Cooldown: 3500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 70},
+	"Barrier":        {Cooldown: 5000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25}, // Wall CD
+	"DiamondBody":    {Cooldown: 8000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25},
+	"DiamondBolt":    {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 40},
+	"VenomTransform": {Cooldown: 20000, GlobalCooldown: 1000, EnergyCost: 40},
+	"PoisonDagger":   {Cooldown: 1000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 30},
+	"DragonBreath":   {Cooldown: 2000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 60},
+	"Tornado":        {Cooldown: 1500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 30},
+	"RazorWind":      {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 30},
+	"TornadoSpin":    {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 55},
+	"Missile":        {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 35},
+	"RocketCrash":    {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 60},
+	"ChopPunch":      {Cooldown: 1000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 30},
+	"ChopFestival":   {Cooldown: 3500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 65},
+	"SpringSnipe":    {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 35},
+	"SpringLeap":     {Cooldown: 2500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 45},
+	"BombShot":       {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 35},
+	"Explosion":      {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 60},
+	"SelfDestruct":   {Cooldown: 5000, GlobalCooldown: 1000, EnergyCost: 40, Damage: 100},
+	"SmokeBomber":    {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 30},
+	"SmokeTornado":   {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 50},
+	"SpikeShot":      {Cooldown: 1000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 30},
+	"SpikeField":     {Cooldown: 3500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 60},
+	"TalonHit":       {Cooldown: 1000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 30},
+	"TalonRush":      {Cooldown: 2000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 45},
+	"DesertSword":    {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 40},
+	"SandTornado":    {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 55},
+	"DarkBomb":       {Cooldown: 1500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 45},
+	"BlackHole":      {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 65},
+	"RubberPistol":   {Cooldown: 1000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 35},
+	"Tsunami":        {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 35, Damage: 80},
+	"Parasite":       {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 45},
+	"Heal":           {Cooldown: 2000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 40}, // Phoenix flames; the healing is the regen passive
+	"Thunder":        {Cooldown: 2000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 55},
+	"PawShot":        {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 35},
+	"PainRepel":      {Cooldown: 3500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 60},
+	"Meteor":         {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 35, Damage: 85},
+	"MochiPunch":     {Cooldown: 1000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 35},
+	"Donut":          {Cooldown: 3000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 25, Damage: 55},
+	"ShadowBall":     {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 40},
+	"ShadowEruption": {Cooldown: 3500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 65},
+	"Room":           {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 50},
+	"SoulBeam":       {Cooldown: 1500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 50},
+	"SoulSnatch":     {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 70},
+	"SpiritBarrage":  {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 40},
+	"SpiritWrath":    {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 35, Damage: 80},
+	"PortalDash":     {Cooldown: 2000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 40},
+	"Banishment":     {Cooldown: 5000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 40, Damage: 90},
+	"Snowflake":      {Cooldown: 1000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 30},
+	"BlizzardDomain": {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 35, Damage: 75},
+	"SoundBlast":     {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 40},
+	"Symphony":       {Cooldown: 3500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 65},
+	"AncientRoar":    {Cooldown: 3500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 60},
+	"TailSwipe":      {Cooldown: 1500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 45},
+	"FoxFire":        {Cooldown: 1200, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 15, Damage: 40},
+	"GasBlast":       {Cooldown: 1500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 20, Damage: 45},
+	"GasZone":        {Cooldown: 4000, GlobalCooldown: defaultGlobalCooldown, EnergyCost: 30, Damage: 55},
+}
+
+// abilityConfig returns the rules for an ability. Basic attacks while
+// holding a fruit behave like melee; unknown fruit abilities get a short
+// cooldown on the global cooldown.
+func abilityConfig(name string) AbilityConfig {
+	if cfg, ok := abilityConfigs[name]; ok {
+		return cfg
+	}
+	if isFruit(name) {
+		return abilityConfigs["melee"]
+	}
+	return AbilityConfig{Cooldown: 500, GlobalCooldown: defaultGlobalCooldown, EnergyCost: defaultEnergyCost}
+}
+
+// useAbility starts the ability's cooldown and spends its energy if it is
+// ready and affordable, and reports whether it may be used.
+func (p *Player) useAbility(name string, now int64) bool {
+	cfg := abilityConfig(name)
+	if now < p.Cooldowns[name] {
+		return false
+	}
+	if !cfg.OffGlobal && now < p.GlobalCooldownEnd {
+		return false
+	}
+	if p.Energy < cfg.EnergyCost {
+		return false
+	}
+
+	p.Energy -= cfg.EnergyCost
+
+	if p.Cooldowns == nil {
+		p.Cooldowns = make(map[string]int64)
+	}
+	p.Cooldowns[name] = now + cfg.Cooldown
+	if !cfg.OffGlobal && now+cfg.GlobalCooldown > p.GlobalCooldownEnd {
+		p.GlobalCooldownEnd = now + cfg.GlobalCooldown
+	}
+	return true
+}
+
+// sendCooldown tells the client when an ability it just used is ready again.
+func sendCooldown(c *websocket.Conn, p *Player, name string) {
+	if c == nil {
+		return
+	}
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":          "cooldown",
+		"ability":       name,
+		"readyAt":       p.Cooldowns[name],
+		"duration":      abilityConfig(name).Cooldown,
+		"globalReadyAt": p.GlobalCooldownEnd,
+	})
+	c.WriteMessage(websocket.TextMessage, msg)
+}
+
+// regenEnergy restores energy for dt seconds of game time.
+func (p *Player) regenEnergy(dt float64) {
+	if p.Energy >= p.MaxEnergy {
+		p.energyRegen = 0
+		return
+	}
+	p.energyRegen += energyRegenPerSec * dt
+	whole := int(p.energyRegen)
+	p.energyRegen -= float64(whole)
+	p.Energy += whole
+	if p.Energy > p.MaxEnergy {
+		p.Energy = p.MaxEnergy
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUseAbility_IndependentCooldowns(t *testing.T) {
+	p := &Player{ID: "p1", Energy: 1000, MaxEnergy: 1000}
+	now := int64(10000)
+
+	if !p.useAbility("Fireball", now) {
+		t.Fatal("First Fireball should be allowed")
+	}
+	if p.useAbility("Fireball", now+500) {
+		t.Error("Fireball should still be on cooldown")
+	}
+	// Weapons are off the global cooldown and keep their own timer
+	if !p.useAbility("melee", now+10) {
+		t.Error("Melee should not be blocked by Fireball")
+	}
+	if !p.useAbility("Fireball", now+1000) {
+		t.Error("Fireball should be ready after its cooldown")
+	}
+}
+
+func TestUseAbility_GlobalCooldown(t *testing.T) {
+	p := &Player{ID: "p1", Energy: 1000, MaxEnergy: 1000}
+	now := int64(10000)
+
+	p.useAbility("Fireball", now)
+	if p.useAbility("FlamePillar", now+defaultGlobalCooldown-1) {
+		t.Error("FlamePillar should wait for the global cooldown")
+	}
+	if !p.useAbility("FlamePillar", now+defaultGlobalCooldown) {
+		t.Error("FlamePillar should be usable once the global cooldown ends")
+	}
+
+	// A longer global cooldown is not shortened by a later ability
+	p.useAbility("Transform", now+5000)
+	if p.GlobalCooldownEnd != now+6000 {
+		t.Errorf("GlobalCooldownEnd = %d, want %d", p.GlobalCooldownEnd, now+6000)
+	}
+	if p.useAbility("Tornado", now+5500) {
+		t.Error("Tornado should be blocked by Transform's global cooldown")
+	}
+}
+
+func TestUseAbility_Energy(t *testing.T) {
+	p := &Player{ID: "p1", Energy: 20, MaxEnergy: baseMaxEnergy}
+	now := int64(10000)
+
+	if !p.useAbility("Fireball", now) {
+		t.Fatal("Fireball should be affordable")
+	}
+	if p.Energy != 5 {
+		t.Errorf("Energy = %d, want 5", p.Energy)
+	}
+	if p.useAbility("IceShards", now+1000) {
+		t.Error("Cast should be rejected without enough energy")
+	}
+	if _, started := p.Cooldowns["IceShards"]; started {
+		t.Error("Rejected cast should not start a cooldown")
+	}
+	if !p.useAbility("melee", now+1000) {
+		t.Error("Weapons should not cost energy")
+	}
+}
+
+func TestRegenEnergy(t *testing.T) {
+	p := &Player{Energy: 0, MaxEnergy: baseMaxEnergy}
+
+	// 20 ticks of 50ms = 1 second
+	for i := 0; i < 20; i++ {
+		p.regenEnergy(0.05)
+	}
+	if p.Energy != int(energyRegenPerSec) {
+		t.Errorf("Energy after 1s = %d, want %d", p.Energy, int(energyRegenPerSec))
+	}
+
+	for i := 0; i < 400; i++ {
+		p.regenEnergy(0.05)
+	}
+	if p.Energy != p.MaxEnergy {
+		t.Errorf("Energy = %d, should cap at %d", p.Energy, p.MaxEnergy)
+	}
+}
+
+func TestApplyDefaults_LegacyEnergy(t *testing.T) {
+	p := &Player{}
+	p.applyDefaults()
+	if p.MaxEnergy != baseMaxEnergy || p.Energy != baseMaxEnergy {
+		t.Errorf("Legacy player energy = %d/%d", p.Energy, p.MaxEnergy)
+	}
+}
+
+func TestAbilityHit_MeleeFollowsWeapon(t *testing.T) {
+	hub, clock := setupClockTest()
+	mm := NewMobManager(hub, defaultRoomID)
+	hub.rooms[defaultRoomID] = &Room{ID: defaultRoomID, MobManager: mm}
+	mm.SpawnMob("near", "Gorilla", 100, 150)
+	mm.SpawnMob("far", "Gorilla", 100, 200)
+	mm.Mobs["near"].Health, mm.Mobs["far"].Health = 10000, 10000
+	p := &Player{ID: "p", RoomID: defaultRoomID, Level: 1, Health: 100, MaxHealth: 100, X: 100, Z: 100, Weapon: "bazooka", Inventory: NewInventory()}
+	hub.players[p.ID] = p
+
+	hub.handleInput(nil, p, InputMessage{Type: "ability_hit", Item: "far", Weapon: "melee"})
+	if mm.Mobs["far"].Health != 10000 {
+		t.Error("Hit landed beyond the bazooka's range")
+	}
+	hub.handleInput(nil, p, InputMessage{Type: "ability_hit", Item: "near", Weapon: "melee"})
+	hit := 10000 - mm.Mobs["near"].Health
+	if hit <= 0 {
+		t.Fatal("The first bazooka hit should land")
+	}
+	clock.Advance(500 * time.Millisecond)
+	hub.handleInput(nil, p, InputMessage{Type: "ability_hit", Item: "near", Weapon: "melee"})
+	if mm.Mobs["near"].Health != 10000-hit {
+		t.Error("Second bazooka hit 500ms later should be refused")
+	}
+}
+
+func TestAbilityConfigs_CoverFruitAbilities(t *testing.T) {
+	for fruit, abilities := range fruitAbilities {
+		for _, ability := range abilities {
+			cfg, ok := abilityConfigs[ability]
+			if !ok {
+				t.Errorf("%s: %s has no abilityConfig", fruit, ability)
+				continue
+			}
+			if _, self := selfEffects[ability]; self {
+				continue // Buffs the caster instead of hitting
+			}
+			if cfg.Damage <= 0 {
+				t.Errorf("%s: %s deals no damage", fruit, ability)
+			}
+		}
+	}
+}
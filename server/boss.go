@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+)
+
+// BossPhase is the behavior of a boss while its health is at or below
+// HealthBelow (a fraction of MaxHealth).
+type BossPhase struct {
+	HealthBelow     float64  `json:"healthBelow"`
+	Abilities       []string `json:"abilities"`       // Rotation, cast in order
+	SpeedMultiplier float64  `json:"speedMultiplier"` // Optional, defaults to 1
+}
+
+// BossConfig describes the encounter of a boss mob type.
+type BossConfig struct {
+	Phases           []BossPhase `json:"phases"`
+	EnrageAfter      int64       `json:"enrageAfter"`      // ms after the pull; 0 disables enrage
+	EnrageMultiplier float64     `json:"enrageMultiplier"` // Damage and speed multiplier once enraged
+}
+
+func (b *BossConfig) validate(t *MobType) error {
+	if !t.IsBoss {
+		return errors.New("boss encounter requires isBoss")
+	}
+	if len(b.Phases) == 0 || b.Phases[0].HealthBelow < 1 {
+		return errors.New("first boss phase must start at healthBelow 1")
+	}
+	for i, p := range b.Phases {
+		if i > 0 && p.HealthBelow >= b.Phases[i-1].HealthBelow {
+			return fmt.Errorf("phase %d: healthBelow must decrease", i)
+		}
+		if len(p.Abilities) == 0 {
+			return fmt.Errorf("phase %d: needs at least one ability", i)
+		}
+		for _, name := range p.Abilities {
+			if _, ok := t.ability(name); !ok {
+				return fmt.Errorf("phase %d: unknown ability %q", i, name)
+			}
+		}
+	}
+	if b.EnrageAfter < 0 || (b.EnrageAfter > 0 && b.EnrageMultiplier < 1) {
+		return errors.New("enrageMultiplier must be at least 1")
+	}
+	return nil
+}
+
+// BossSpawn schedules a boss in every room: it appears when the room is
+// created and again Interval ms after each death.
+type BossSpawn struct {
+	MobType  string  `json:"mobType"`
+	X        float64 `json:"x"`
+	Z        float64 `json:"z"`
+	Interval int64   `json:"interval"`
+}
+
+func (s *BossSpawn) validate() error {
+	def, ok := mobRegistry.Get(s.MobType)
+	if !ok {
+		return fmt.Errorf("unknown mob type %q", s.MobType)
+	}
+	if !def.IsBoss {
+		return fmt.Errorf("%q is not a boss", s.MobType)
+	}
+	if s.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	return nil
+}
+
+// bossEncounter is the runtime state of a living boss.
+type bossEncounter struct {
+	config    *BossConfig
+	def       *MobType
+	rotation  int   // Index of the next ability in the phase rotation
+	engagedAt int64 // ms; 0 until the boss is pulled
+	cast      *pendingCast
+}
+
+// pendingCast is a telegraphed ability waiting for its wind-up to finish.
+type pendingCast struct {
+	ability   MobAbility
+	x         float64
+	z         float64
+	resolveAt int64
+}
+
+// bossSpawner tracks one scheduled boss within a room.
+type bossSpawner struct {
+	spawn  *BossSpawn
+	mobID  string
+	nextAt int64
+}
+
+// SetBossSchedule replaces the scheduled bosses of this room. Spawners of
+// a mob type that stays scheduled keep their living boss and timer.
+func (mm *MobManager) SetBossSchedule(spawns []*BossSpawn) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	previous := make(map[string][]*bossSpawner, len(mm.bossSpawners))
+	for _, s := range mm.bossSpawners {
+		previous[s.spawn.MobType] = append(previous[s.spawn.MobType], s)
+	}
+
+	mm.bossSpawners = make([]*bossSpawner, 0, len(spawns))
+	for _, sp := range spawns {
+		s := &bossSpawner{}
+		if old := previous[sp.MobType]; len(old) > 0 {
+			s, previous[sp.MobType] = old[0], old[1:]
+		}
+		s.spawn = sp
+		mm.bossSpawners = append(mm.bossSpawners, s)
+	}
+}
+
+// updateBossSpawnersLocked respawns scheduled bosses whose timer is up.
+// Caller MUST hold mm.mutex.
+func (mm *MobManager) updateBossSpawnersLocked(now int64) {
+	for _, s := range mm.bossSpawners {
+		if s.mobID != "" {
+			if mob, ok := mm.Mobs[s.mobID]; ok && mob.State != StateDead {
+				continue
+			}
+			s.mobID = ""
+			s.nextAt = now + s.spawn.Interval
+		}
+		if now < s.nextAt {
+			continue
+		}
+		mob, err := mm.spawnMobLocked(mm.newMobIDLocked(), s.spawn.MobType, s.spawn.X, s.spawn.Z)
+		if err != nil {
+			log.Printf("Boss spawn %s: %v", s.spawn.MobType, err)
+			s.nextAt = now + s.spawn.Interval
+			continue
+		}
+		s.mobID = mob.ID
+	}
+}
+
+// SummonBoss spawns a boss outside of the schedule, e.g. from an admin action.
+func (mm *MobManager) SummonBoss(mobType string, x, z float64) (*Mob, error) {
+	def, ok := mobRegistry.Get(mobType)
+	if !ok || !def.IsBoss {
+		return nil, fmt.Errorf("%q is not a boss", mobType)
+	}
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	return mm.spawnMobLocked(mm.newMobIDLocked(), mobType, x, z)
+}
+
+func (mm *MobManager) queueBossEvent(event map[string]interface{}) {
+	msg, _ := json.Marshal(event)
+	mm.queue(msg)
+}
+
+// engage starts the enrage timer the first time the boss is pulled.
+func (b *bossEncounter) engage(now int64) {
+	if b.engagedAt == 0 {
+		b.engagedAt = now
+	}
+}
+
+// reset returns a leashed boss to a fresh encounter.
+// Caller MUST hold mm.mutex.
+func (mm *MobManager) resetBossLocked(mob *Mob) {
+	mob.boss.engagedAt = 0
+	mob.boss.rotation = 0
+	mob.boss.cast = nil
+	mob.Phase = 0
+	mob.Enraged = false
+	mob.Health = mob.MaxHealth
+	mob.Contributions = make(map[string]int)
+}
+
+// updateBossLocked advances a boss's phase, enrage timer and telegraphed
+// abilities. It returns true while the boss is winding up and must not move.
+// Caller MUST hold mm.mutex and mm.hub.mutex.
+func (mm *MobManager) updateBossLocked(mob *Mob, target *Player, now int64) bool {
+	b := mob.boss
+
+	// Phase transitions on HP thresholds
+	for mob.Phase+1 < len(b.config.Phases) &&
+		float64(mob.Health) <= b.config.Phases[mob.Phase+1].HealthBelow*float64(mob.MaxHealth) {
+		mob.Phase++
+		b.rotation = 0
+		mm.queueBossEvent(map[string]interface{}{
+			"type":  "boss_phase",
+			"mobId": mob.ID,
+			"phase": mob.Phase,
+		})
+	}
+
+	if target != nil {
+		b.engage(now)
+	}
+
+	// Enrage timer
+	if b.engagedAt > 0 && b.config.EnrageAfter > 0 && !mob.Enraged && now-b.engagedAt >= b.config.EnrageAfter {
+		mob.Enraged = true
+		mm.queueBossEvent(map[string]interface{}{
+			"type":  "boss_enrage",
+			"mobId": mob.ID,
+		})
+	}
+
+	// Resolve a telegraphed ability once its wind-up is over
+	if b.cast != nil {
+		if now < b.cast.resolveAt {
+			return true
+		}
+		mm.resolveBossCastLocked(mob, b.cast, now)
+		b.cast = nil
+		return false
+	}
+
+	if target == nil || now <= mob.AbilityCD {
+		return false
+	}
+
+	phase := b.config.Phases[mob.Phase]
+	ability, _ := b.def.ability(phase.Abilities[b.rotation%len(phase.Abilities)])
+	if distanceSq(mob.X, mob.Z, target.X, target.Z) >= ability.Range*ability.Range {
+		return false
+	}
+	b.rotation++
+
+	// Telegraph at the target's current position; players can walk out of it
+	b.cast = &pendingCast{ability: ability, x: target.X, z: target.Z, resolveAt: now + ability.WindUp}
+	mob.AbilityCD = b.cast.resolveAt + ability.Cooldown
+	mm.queueBossEvent(map[string]interface{}{
+		"type":      "boss_telegraph",
+		"mobId":     mob.ID,
+		"ability":   ability.Name,
+		"x":         b.cast.x,
+		"z":         b.cast.z,
+		"radius":    ability.Radius,
+		"windUp":    ability.WindUp,
+		"timestamp": now,
+	})
+	if ability.WindUp == 0 {
+		mm.resolveBossCastLocked(mob, b.cast, now)
+		b.cast = nil
+		return false
+	}
+	return true
+}
+
+// resolveBossCastLocked deals a telegraphed ability's damage to every player
+// of the room standing in its area.
+// Caller MUST hold mm.mutex and mm.hub.mutex.
+func (mm *MobManager) resolveBossCastLocked(mob *Mob, cast *pendingCast, now int64) {
+	damage := int(float64(cast.ability.Damage) * mob.damageMultiplier())
+	radiusSq := cast.ability.Radius * cast.ability.Radius
+
+	// In ID order so dodge and proc rolls replay under the same seed
+	hits := make([]string, 0)
+	for _, id := range slices.Sorted(maps.Keys(mm.hub.players)) {
+		p := mm.hub.players[id]
+		if p.RoomID != mm.RoomID || p.Dead || isSafeZone(p.X, p.Z) {
+			continue
+		}
+		if distanceSq(cast.x, cast.z, p.X, p.Z) > radiusSq {
+			continue
+		}
+		mm.hub.dealDamageUnsafe(&DamageEvent{
+			Mob:     mob,
+			Target:  p,
+			Ability: &cast.ability,
+			Base:    damage,
+			Tags:    []string{TagAbility, TagBoss},
+			Now:     now,
+		})
+		hits = append(hits, p.ID)
+	}
+
+	mm.queueBossEvent(map[string]interface{}{
+		"type":      "mob_cast_ability",
+		"mobId":     mob.ID,
+		"ability":   cast.ability.Name,
+		"x":         cast.x,
+		"z":         cast.z,
+		"radius":    cast.ability.Radius,
+		"hits":      hits,
+		"timestamp": now,
+	})
+}
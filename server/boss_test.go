@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func spawnTestBoss(t *testing.T, hub *Hub) (*MobManager, *Mob) {
+	t.Helper()
+	mm := NewMobManager(hub, defaultRoomID)
+	if err := mm.SpawnMob("boss", "Ice Admiral", 100.0, 100.0); err != nil {
+		t.Fatal(err)
+	}
+	mm.DrainMessages() // boss_spawn
+	return mm, mm.Mobs["boss"]
+}
+
+func drainTypes(mm *MobManager) []string {
+	var types []string
+	for _, raw := range mm.DrainMessages() {
+		var msg map[string]interface{}
+		json.Unmarshal(raw, &msg)
+		types = append(types, msg["type"].(string))
+	}
+	return types
+}
+
+func TestBoss_PhaseTransition(t *testing.T) {
+	hub := newHub()
+	mm, boss := spawnTestBoss(t, hub)
+
+	boss.Health = boss.MaxHealth / 2 // Below the 0.6 threshold
+	mm.updateBossLocked(boss, nil, time.Now().UnixMilli())
+
+	if boss.Phase != 1 {
+		t.Errorf("expected phase 1, got %d", boss.Phase)
+	}
+	if types := drainTypes(mm); len(types) != 1 || types[0] != "boss_phase" {
+		t.Errorf("expected a boss_phase event, got %v", types)
+	}
+}
+
+func TestBoss_TelegraphResolvesAfterWindUp(t *testing.T) {
+	hub := newHub()
+	mm, boss := spawnTestBoss(t, hub)
+
+	inside := &Player{ID: "inside", RoomID: defaultRoomID, X: 105, Z: 100, Health: 100}
+	outside := &Player{ID: "outside", RoomID: defaultRoomID, X: 130, Z: 100, Health: 100}
+	hub.players[inside.ID] = inside
+	hub.players[outside.ID] = outside
+
+	now := time.Now().UnixMilli()
+	if !mm.updateBossLocked(boss, inside, now) {
+		t.Fatal("boss should be rooted while winding up")
+	}
+	if types := drainTypes(mm); len(types) != 1 || types[0] != "boss_telegraph" {
+		t.Fatalf("expected a boss_telegraph event, got %v", types)
+	}
+	if inside.Health != 100 {
+		t.Errorf("damage must not land before the wind-up ends")
+	}
+
+	windUp := boss.boss.cast.ability.WindUp
+	mm.updateBossLocked(boss, inside, now+windUp)
+
+	if inside.Health >= 100 {
+		t.Errorf("player inside the telegraph should be hit")
+	}
+	if outside.Health != 100 {
+		t.Errorf("player outside the telegraph should not be hit")
+	}
+	if types := drainTypes(mm); len(types) != 1 || types[0] != "mob_cast_ability" {
+		t.Errorf("expected a mob_cast_ability event, got %v", types)
+	}
+}
+
+func TestBoss_Enrage(t *testing.T) {
+	hub := newHub()
+	mm, boss := spawnTestBoss(t, hub)
+
+	now := time.Now().UnixMilli()
+	boss.boss.engage(now)
+	mm.updateBossLocked(boss, nil, now+boss.boss.config.EnrageAfter)
+
+	if !boss.Enraged {
+		t.Fatal("boss should enrage once the timer expires")
+	}
+	if boss.damageMultiplier() != boss.boss.config.EnrageMultiplier {
+		t.Errorf("enraged boss should use the enrage multiplier")
+	}
+}
+
+func TestBoss_LeashResetsEncounter(t *testing.T) {
+	hub := newHub()
+	mm, boss := spawnTestBoss(t, hub)
+
+	boss.Health = 10
+	boss.RecordDamage("player1", 100)
+	boss.X = 100 + boss.LeashRange + 1
+
+	mm.Update(0.05)
+
+	if boss.Health != boss.MaxHealth || len(boss.Contributions) != 0 {
+		t.Errorf("leashed boss should reset, got health=%d contributions=%v", boss.Health, boss.Contributions)
+	}
+}
+
+func TestMob_RewardShares(t *testing.T) {
+	hub := newHub()
+	_, boss := spawnTestBoss(t, hub)
+
+	boss.RecordDamage("a", 300)
+	boss.RecordDamage("b", 100)
+
+	shares := boss.RewardShares("b")
+	if shares["a"] != 0.75 || shares["b"] != 0.25 {
+		t.Errorf("boss rewards should split by contribution, got %v", shares)
+	}
+
+	mm := NewMobManager(hub, defaultRoomID)
+	mm.SpawnMob("gorilla", "Gorilla", 0, 0)
+	gorilla := mm.Mobs["gorilla"]
+	gorilla.RecordDamage("a", 150)
+	if shares := gorilla.RewardShares("b"); len(shares) != 1 || shares["b"] != 1.0 {
+		t.Errorf("regular mobs reward the killer only, got %v", shares)
+	}
+}
+
+func TestBossSchedule_ReloadKeepsLivingBoss(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	schedule := []*BossSpawn{{MobType: "Ice Admiral", X: 100, Z: 100, Interval: 60000}}
+	mm.SetBossSchedule(schedule)
+
+	now := time.Now().UnixMilli()
+	mm.updateBossSpawnersLocked(now)
+	if len(mm.Mobs) != 1 {
+		t.Fatalf("Expected the scheduled boss, got %d mobs", len(mm.Mobs))
+	}
+
+	// Hot reload with the same boss, e.g. from reload_mobs
+	reloaded := []*BossSpawn{{MobType: "Ice Admiral", X: 100, Z: 100, Interval: 60000}}
+	mm.SetBossSchedule(reloaded)
+	mm.updateBossSpawnersLocked(now + 50)
+	if len(mm.Mobs) != 1 {
+		t.Errorf("Reload spawned a second boss: %d mobs", len(mm.Mobs))
+	}
+	if mm.bossSpawners[0].spawn != reloaded[0] {
+		t.Error("The spawner should pick up the reloaded schedule")
+	}
+}
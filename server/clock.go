@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the hub's source of game time. Everything in the simulation
+// reads time through it so tests can run the world on a ManualClock.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// ManualClock only moves when Advance or Set is called.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// now returns the current game time in unix milliseconds.
+func (h *Hub) now() int64 {
+	return h.clock.Now().UnixMilli()
+}
+
+// Timer is a per-entity interval on game time. The zero value is due
+// immediately.
+type Timer struct {
+	next int64
+}
+
+// Fire reports whether the timer is due at now and, if so, arms it to
+// fire again interval ms later.
+func (t *Timer) Fire(now, interval int64) bool {
+	if now < t.next {
+		return false
+	}
+	t.next = now + interval
+	return true
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func setupClockTest() (*Hub, *ManualClock) {
+	clock := NewManualClock(time.UnixMilli(1_000_000))
+	hub := newHub()
+	hub.clock = clock
+	return hub, clock
+}
+
+func TestTimer_Fire(t *testing.T) {
+	var timer Timer
+	if !timer.Fire(100, 500) {
+		t.Fatal("The zero timer is due immediately")
+	}
+	if timer.Fire(599, 500) {
+		t.Error("Fired before the interval elapsed")
+	}
+	if !timer.Fire(600, 500) {
+		t.Error("Should fire once the interval elapsed")
+	}
+}
+
+func TestTick_MobMeleeOnInterval(t *testing.T) {
+	hub, clock := setupClockTest()
+	// A room without spawn zones so only our mob is simulated
+	mm := NewMobManager(hub, defaultRoomID)
+	hub.rooms[defaultRoomID] = &Room{ID: defaultRoomID, MobManager: mm}
+	mm.SpawnMob("mob1", "Gorilla", 100, 100)
+	p := &Player{ID: "p", RoomID: defaultRoomID, Level: 1, Health: 1000, MaxHealth: 1000, X: 101, Z: 100}
+	hub.players[p.ID] = p
+
+	hub.tick(0.05)
+	hit := 1000 - p.Health
+	if hit <= 0 {
+		t.Fatal("The first tick in range should land a hit")
+	}
+
+	for i := 0; i < 9; i++ {
+		clock.Advance(50 * time.Millisecond)
+		hub.tick(0.05)
+	}
+	if p.Health != 1000-hit {
+		t.Fatalf("Hit again before the interval: health %d", p.Health)
+	}
+	clock.Advance(50 * time.Millisecond)
+	hub.tick(0.05)
+	if p.Health != 1000-2*hit {
+		t.Errorf("Health = %d, want exactly two hits after %dms", p.Health, mobAttackInterval)
+	}
+}
+
+func TestTick_RegenFollowsClock(t *testing.T) {
+	hub, clock := setupClockTest()
+	p := &Player{ID: "p", RoomID: defaultRoomID, Level: 1, Health: 50, MaxHealth: 100, CurrentFruit: "Phoenix Fruit"}
+	hub.players[p.ID] = p
+	regen := p.passiveSet().Regen
+	amount := regen.Amount
+
+	hub.tick(0.05)
+	hub.tick(0.05) // Same instant: no second application
+	if p.Health != 50+amount {
+		t.Fatalf("Health = %d, want one regen tick", p.Health)
+	}
+	clock.Advance(time.Duration(regen.Tick) * time.Millisecond)
+	hub.tick(0.05)
+	if p.Health != 50+2*amount {
+		t.Errorf("Health = %d, want two regen ticks", p.Health)
+	}
+}
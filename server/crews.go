@@ -0,0 +1,464 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// Crew ranks
+const (
+	CrewLeader  = "leader"
+	CrewOfficer = "officer"
+	CrewMember  = "member"
+)
+
+const (
+	maxCrewSize              = 20
+	crewNameMinLen           = 3
+	crewNameMaxLen           = 16
+	crewInviteTimeout        = 60000    // ms an invite stays open
+	crewOfficerWithdrawLimit = 10000    // Per officer and window; the leader has no limit
+	crewWithdrawWindow       = 86400000 // ms, one day
+	crewChatMaxLen           = 200
+)
+
+var crewRankOrder = map[string]int{CrewMember: 1, CrewOfficer: 2, CrewLeader: 3}
+
+// Crew is a player-run group with ranks and a shared bank. Crews are stored
+// in the crews and crew_members tables.
+type Crew struct {
+	Name      string            `json:"name"`
+	Members   map[string]string `json:"members"` // Player -> rank
+	Bank      int               `json:"bank"`
+	CreatedAt int64             `json:"createdAt"`
+
+	withdrawn map[string]withdrawWindow // Officer -> current window, in memory only
+}
+
+// withdrawWindow is what an officer took out of the bank since start.
+type withdrawWindow struct {
+	start int64
+	total int
+}
+
+// CrewInvite is an open invitation to join a crew.
+type CrewInvite struct {
+	Crew      string
+	From      string
+	CreatedAt int64
+}
+
+func (cr *Crew) clone() *Crew {
+	next := *cr
+	next.Members = make(map[string]string, len(cr.Members))
+	for id, rank := range cr.Members {
+		next.Members[id] = rank
+	}
+	next.withdrawn = make(map[string]withdrawWindow, len(cr.withdrawn))
+	for id, w := range cr.withdrawn {
+		next.withdrawn[id] = w
+	}
+	return &next
+}
+
+// officerWithdrawn returns what the officer took out in the window
+// running at now.
+func (cr *Crew) officerWithdrawn(id string, now int64) int {
+	w, ok := cr.withdrawn[id]
+	if !ok || now-w.start >= crewWithdrawWindow {
+		return 0
+	}
+	return w.total
+}
+
+// recordWithdrawal adds to the officer's window, starting a new one once
+// the last has run out.
+func (cr *Crew) recordWithdrawal(id string, amount int, now int64) {
+	w := cr.withdrawn[id]
+	if now-w.start >= crewWithdrawWindow {
+		w = withdrawWindow{start: now}
+	}
+	w.total += amount
+	if cr.withdrawn == nil {
+		cr.withdrawn = make(map[string]withdrawWindow)
+	}
+	cr.withdrawn[id] = w
+}
+
+// outranks reports whether a has a higher rank than b.
+func (cr *Crew) outranks(a, b string) bool {
+	return crewRankOrder[cr.Members[a]] > crewRankOrder[cr.Members[b]]
+}
+
+// successor picks the next leader: the highest ranked remaining member,
+// ties broken by name so the choice is stable.
+func (cr *Crew) successor(leaving string) string {
+	ids := make([]string, 0, len(cr.Members))
+	for id := range cr.Members {
+		if id != leaving {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ri, rj := crewRankOrder[cr.Members[ids[i]]], crewRankOrder[cr.Members[ids[j]]]
+		if ri != rj {
+			return ri > rj
+		}
+		return ids[i] < ids[j]
+	})
+	return ids[0]
+}
+
+func validCrewName(name string) error {
+	if len(name) < crewNameMinLen || len(name) > crewNameMaxLen {
+		return errors.New("crew names are 3-16 characters")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == ' ') {
+			return errors.New("crew names may only use letters, digits and spaces")
+		}
+	}
+	return nil
+}
+
+func crewErrorMsg(reason string) []byte {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":   "crew_error",
+		"reason": reason,
+	})
+	return msg
+}
+
+// crewOfUnsafe returns the player's crew, if any.
+// Caller MUST hold h.mutex.
+func (h *Hub) crewOfUnsafe(playerID string) *Crew {
+	for _, cr := range h.crews {
+		if _, ok := cr.Members[playerID]; ok {
+			return cr
+		}
+	}
+	return nil
+}
+
+// setCrewUnsafe updates the Crew field of an online player.
+// Caller MUST hold h.mutex.
+func (h *Hub) setCrewUnsafe(playerID, crew string) {
+	if p, ok := h.players[playerID]; ok {
+		p.Crew = crew
+	}
+}
+
+// crewUpdateMsgUnsafe describes the crew roster and bank.
+// Caller MUST hold h.mutex.
+func (h *Hub) crewUpdateMsgUnsafe(cr *Crew) []byte {
+	type member struct {
+		ID     string `json:"id"`
+		Rank   string `json:"rank"`
+		Online bool   `json:"online"`
+	}
+	members := make([]member, 0, len(cr.Members))
+	for id, rank := range cr.Members {
+		_, online := h.players[id]
+		members = append(members, member{ID: id, Rank: rank, Online: online})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type": "crew_update",
+		"crew": map[string]interface{}{
+			"name":    cr.Name,
+			"members": members,
+			"bank":    cr.Bank,
+		},
+	})
+	return msg
+}
+
+// broadcastCrewUnsafe sends the crew roster and bank to its online members.
+// Caller MUST hold h.mutex.
+func (h *Hub) broadcastCrewUnsafe(cr *Crew) {
+	msg := h.crewUpdateMsgUnsafe(cr)
+	for id := range cr.Members {
+		h.sendToPlayerUnsafe(id, msg)
+	}
+}
+
+// sendNoCrewUnsafe tells a player they are no longer in a crew.
+// Caller MUST hold h.mutex.
+func (h *Hub) sendNoCrewUnsafe(playerID string) {
+	h.sendToPlayerUnsafe(playerID, []byte(`{"type":"crew_update","crew":null}`))
+}
+
+// commitCrewUnsafe saves a changed crew and makes it live. Changes are made
+// on a clone so a failed save leaves the live crew untouched.
+// Caller MUST hold h.mutex.
+func (h *Hub) commitCrewUnsafe(next *Crew) error {
+	if err := SaveCrew(next); err != nil {
+		log.Printf("Crew %s failed to save: %v", next.Name, err)
+		return errors.New("crew could not be saved")
+	}
+	h.crews[next.Name] = next
+	h.broadcastCrewUnsafe(next)
+	return nil
+}
+
+// disbandCrewUnsafe deletes the crew, paying its bank out to the leader.
+// Caller MUST hold h.mutex.
+func (h *Hub) disbandCrewUnsafe(cr *Crew, leader *Player) error {
+	next := *leader
+	next.Money += cr.Bank
+	next.Crew = ""
+	if err := DeleteCrew(cr.Name, &next); err != nil {
+		log.Printf("Crew %s failed to disband: %v", cr.Name, err)
+		return errors.New("crew could not be disbanded")
+	}
+	leader.Money, leader.Crew = next.Money, ""
+	delete(h.crews, cr.Name)
+	for id := range cr.Members {
+		h.setCrewUnsafe(id, "")
+		h.sendNoCrewUnsafe(id)
+	}
+	return nil
+}
+
+// handleCrewInput runs the crew actions: crew_create (Item = name),
+// crew_invite (Item = player), crew_accept (Item = crew), crew_kick,
+// crew_promote and crew_demote (Item = player), crew_leave, crew_disband,
+// crew_chat (Item = text), crew_deposit and crew_withdraw (Amount) and
+// crew_info.
+// Caller MUST hold h.mutex.
+func (h *Hub) handleCrewInput(c *websocket.Conn, player *Player, input InputMessage) {
+	now := h.now()
+	cr := h.crewOfUnsafe(player.ID)
+	fail := func(reason string) {
+		h.sendToPlayerUnsafe(player.ID, crewErrorMsg(reason))
+	}
+
+	if input.Type != "crew_create" && input.Type != "crew_accept" && cr == nil {
+		fail("you are not in a crew")
+		return
+	}
+
+	switch input.Type {
+	case "crew_create":
+		if cr != nil {
+			fail("leave your crew first")
+			return
+		}
+		name := strings.TrimSpace(input.Item)
+		if err := validCrewName(name); err != nil {
+			fail(err.Error())
+			return
+		}
+		for existing := range h.crews {
+			if strings.EqualFold(existing, name) {
+				fail("crew name taken")
+				return
+			}
+		}
+		next := &Crew{Name: name, Members: map[string]string{player.ID: CrewLeader}, CreatedAt: now}
+		if err := h.commitCrewUnsafe(next); err != nil {
+			fail(err.Error())
+			return
+		}
+		player.Crew = name
+
+	case "crew_invite":
+		if cr.Members[player.ID] == CrewMember {
+			fail("only officers can invite")
+			return
+		}
+		target, ok := h.players[input.Item]
+		if !ok {
+			fail("player not online")
+			return
+		}
+		if h.crewOfUnsafe(target.ID) != nil {
+			fail(target.ID + " is already in a crew")
+			return
+		}
+		if len(cr.Members) >= maxCrewSize {
+			fail("crew is full")
+			return
+		}
+		h.crewInvites[target.ID] = &CrewInvite{Crew: cr.Name, From: player.ID, CreatedAt: now}
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type": "crew_invite",
+			"crew": cr.Name,
+			"from": player.ID,
+		})
+		h.sendToPlayerUnsafe(target.ID, msg)
+
+	case "crew_accept":
+		inv, ok := h.crewInvites[player.ID]
+		if !ok || inv.Crew != input.Item || now-inv.CreatedAt > crewInviteTimeout {
+			fail("no open invite from that crew")
+			return
+		}
+		delete(h.crewInvites, player.ID)
+		if cr != nil {
+			fail("leave your crew first")
+			return
+		}
+		target, ok := h.crews[inv.Crew]
+		if !ok {
+			fail("crew no longer exists")
+			return
+		}
+		if len(target.Members) >= maxCrewSize {
+			fail("crew is full")
+			return
+		}
+		next := target.clone()
+		next.Members[player.ID] = CrewMember
+		if err := h.commitCrewUnsafe(next); err != nil {
+			fail(err.Error())
+			return
+		}
+		player.Crew = next.Name
+
+	case "crew_kick":
+		if _, ok := cr.Members[input.Item]; !ok || !cr.outranks(player.ID, input.Item) {
+			fail("you can only kick lower ranked members")
+			return
+		}
+		next := cr.clone()
+		delete(next.Members, input.Item)
+		if err := h.commitCrewUnsafe(next); err != nil {
+			fail(err.Error())
+			return
+		}
+		h.setCrewUnsafe(input.Item, "")
+		h.sendNoCrewUnsafe(input.Item)
+
+	case "crew_promote", "crew_demote":
+		rank, ok := cr.Members[input.Item]
+		if cr.Members[player.ID] != CrewLeader || !ok || input.Item == player.ID {
+			fail("only the leader can change ranks")
+			return
+		}
+		next := cr.clone()
+		switch {
+		case input.Type == "crew_demote" && rank == CrewOfficer:
+			next.Members[input.Item] = CrewMember
+		case input.Type == "crew_promote" && rank == CrewMember:
+			next.Members[input.Item] = CrewOfficer
+		case input.Type == "crew_promote" && rank == CrewOfficer:
+			// Promoting an officer hands over leadership
+			next.Members[input.Item] = CrewLeader
+			next.Members[player.ID] = CrewOfficer
+		default:
+			return
+		}
+		if err := h.commitCrewUnsafe(next); err != nil {
+			fail(err.Error())
+		}
+
+	case "crew_leave":
+		if len(cr.Members) == 1 {
+			if err := h.disbandCrewUnsafe(cr, player); err != nil {
+				fail(err.Error())
+			}
+			return
+		}
+		next := cr.clone()
+		if cr.Members[player.ID] == CrewLeader {
+			next.Members[cr.successor(player.ID)] = CrewLeader
+		}
+		delete(next.Members, player.ID)
+		if err := h.commitCrewUnsafe(next); err != nil {
+			fail(err.Error())
+			return
+		}
+		player.Crew = ""
+		h.sendNoCrewUnsafe(player.ID)
+
+	case "crew_disband":
+		if cr.Members[player.ID] != CrewLeader {
+			fail("only the leader can disband the crew")
+			return
+		}
+		if err := h.disbandCrewUnsafe(cr, player); err != nil {
+			fail(err.Error())
+		}
+
+	case "crew_chat":
+		text := strings.TrimSpace(input.Item)
+		if text == "" {
+			return
+		}
+		if len(text) > crewChatMaxLen {
+			text = text[:crewChatMaxLen]
+		}
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type": "crew_chat",
+			"crew": cr.Name,
+			"id":   player.ID,
+			"rank": cr.Members[player.ID],
+			"item": text,
+		})
+		for id := range cr.Members {
+			h.sendToPlayerUnsafe(id, msg)
+		}
+
+	case "crew_deposit", "crew_withdraw":
+		amount := input.Amount
+		if amount <= 0 {
+			fail("invalid amount")
+			return
+		}
+		if input.Type == "crew_withdraw" {
+			switch cr.Members[player.ID] {
+			case CrewMember:
+				fail("members can't withdraw from the crew bank")
+				return
+			case CrewOfficer:
+				if used := cr.officerWithdrawn(player.ID, now); used+amount > crewOfficerWithdrawLimit {
+					fail(fmt.Sprintf("officers can withdraw at most %d a day (%d left)", crewOfficerWithdrawLimit, max(crewOfficerWithdrawLimit-used, 0)))
+					return
+				}
+			}
+			amount = -amount
+		}
+		if player.Money-amount < 0 || cr.Bank+amount < 0 {
+			fail("not enough money")
+			return
+		}
+
+		// Money only moves once both sides are saved together
+		nextPlayer := *player
+		nextPlayer.Money -= amount
+		next := cr.clone()
+		next.Bank += amount
+		if amount < 0 && next.Members[player.ID] == CrewOfficer {
+			next.recordWithdrawal(player.ID, -amount, now)
+		}
+		if err := SaveCrewBank(&nextPlayer, next); err != nil {
+			log.Printf("Crew %s bank update failed: %v", cr.Name, err)
+			fail("crew bank could not be saved")
+			return
+		}
+		player.Money = nextPlayer.Money
+		h.crews[next.Name] = next
+		h.broadcastCrewUnsafe(next)
+
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type":      "update_stats",
+			"money":     player.Money,
+			"inventory": player.Inventory,
+		})
+		h.sendToPlayerUnsafe(player.ID, msg)
+
+	case "crew_info":
+		h.sendToPlayerUnsafe(player.ID, h.crewUpdateMsgUnsafe(cr))
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func setupCrewTest(t *testing.T, ids ...string) (*Hub, []*Player) {
+	t.Helper()
+	useTestDB(t)
+
+	hub := newHub()
+	var players []*Player
+	for _, id := range ids {
+		if err := RegisterUser(id, "password"); err != nil {
+			t.Fatalf("RegisterUser: %v", err)
+		}
+		p := &Player{ID: id, RoomID: defaultRoomID, Team: "pirate", Health: 100, MaxHealth: 100, Money: 20000, Inventory: NewInventory("melee")}
+		hub.players[id] = p
+		players = append(players, p)
+	}
+	return hub, players
+}
+
+func crewInput(hub *Hub, p *Player, typ, item string, amount int) {
+	hub.handleCrewInput(nil, p, InputMessage{Type: typ, Item: item, Amount: amount})
+}
+
+// joinCrew has leader invite p and p accept.
+func joinCrew(hub *Hub, leader, p *Player) {
+	crewInput(hub, leader, "crew_invite", p.ID, 0)
+	crewInput(hub, p, "crew_accept", leader.Crew, 0)
+}
+
+func TestCrew_CreateInviteAccept(t *testing.T) {
+	hub, ps := setupCrewTest(t, "captain", "mate")
+	captain, mate := ps[0], ps[1]
+
+	crewInput(hub, captain, "crew_create", "Straw Hats", 0)
+	if captain.Crew != "Straw Hats" {
+		t.Fatalf("Crew = %q", captain.Crew)
+	}
+	crewInput(hub, mate, "crew_create", "straw hats", 0)
+	if mate.Crew != "" {
+		t.Error("Crew names must be unique regardless of case")
+	}
+
+	// Accepting without an invite does nothing
+	crewInput(hub, mate, "crew_accept", "Straw Hats", 0)
+	if mate.Crew != "" {
+		t.Fatal("Joined without an invite")
+	}
+	joinCrew(hub, captain, mate)
+	if mate.Crew != "Straw Hats" || hub.crews["Straw Hats"].Members["mate"] != CrewMember {
+		t.Fatalf("mate not a member: %+v", hub.crews["Straw Hats"])
+	}
+
+	// The roster is persisted
+	crews, err := LoadCrews()
+	if err != nil {
+		t.Fatalf("LoadCrews: %v", err)
+	}
+	if cr := crews["Straw Hats"]; cr == nil || cr.Members["captain"] != CrewLeader || cr.Members["mate"] != CrewMember {
+		t.Errorf("Saved crew = %+v", crews["Straw Hats"])
+	}
+}
+
+func TestCrew_RanksAndKick(t *testing.T) {
+	hub, ps := setupCrewTest(t, "captain", "officer", "deckhand")
+	captain, officer, deckhand := ps[0], ps[1], ps[2]
+	crewInput(hub, captain, "crew_create", "Red Hair", 0)
+	joinCrew(hub, captain, officer)
+	joinCrew(hub, captain, deckhand)
+
+	// Members can't kick or promote
+	crewInput(hub, deckhand, "crew_kick", "officer", 0)
+	crewInput(hub, deckhand, "crew_promote", "deckhand", 0)
+	if len(hub.crews["Red Hair"].Members) != 3 || hub.crews["Red Hair"].Members["deckhand"] != CrewMember {
+		t.Fatal("Member changed the roster")
+	}
+
+	crewInput(hub, captain, "crew_promote", "officer", 0)
+	if hub.crews["Red Hair"].Members["officer"] != CrewOfficer {
+		t.Fatal("Promotion failed")
+	}
+
+	// Officers can kick members but not the leader
+	crewInput(hub, officer, "crew_kick", "captain", 0)
+	if _, ok := hub.crews["Red Hair"].Members["captain"]; !ok {
+		t.Fatal("Officer kicked the leader")
+	}
+	crewInput(hub, officer, "crew_kick", "deckhand", 0)
+	if _, ok := hub.crews["Red Hair"].Members["deckhand"]; ok || deckhand.Crew != "" {
+		t.Error("Kick failed")
+	}
+}
+
+func TestCrew_LeaderLeavesPassesLeadership(t *testing.T) {
+	hub, ps := setupCrewTest(t, "captain", "bmate", "amate")
+	captain := ps[0]
+	crewInput(hub, captain, "crew_create", "Heart", 0)
+	joinCrew(hub, captain, ps[1])
+	joinCrew(hub, captain, ps[2])
+	crewInput(hub, captain, "crew_promote", "bmate", 0)
+
+	crewInput(hub, captain, "crew_leave", "", 0)
+	cr := hub.crews["Heart"]
+	if captain.Crew != "" || cr.Members["bmate"] != CrewLeader {
+		t.Errorf("Officer should inherit leadership: %+v", cr.Members)
+	}
+}
+
+func TestCrew_Bank(t *testing.T) {
+	hub, ps := setupCrewTest(t, "captain", "officer", "deckhand")
+	captain, officer, deckhand := ps[0], ps[1], ps[2]
+	clock := NewManualClock(time.UnixMilli(1_000_000))
+	hub.clock = clock
+	crewInput(hub, captain, "crew_create", "Whitebeard", 0)
+	joinCrew(hub, captain, officer)
+	joinCrew(hub, captain, deckhand)
+	crewInput(hub, captain, "crew_promote", "officer", 0)
+
+	crewInput(hub, deckhand, "crew_deposit", "", 15000)
+	if hub.crews["Whitebeard"].Bank != 15000 || deckhand.Money != 5000 {
+		t.Fatalf("Deposit: bank %d, money %d", hub.crews["Whitebeard"].Bank, deckhand.Money)
+	}
+	crewInput(hub, deckhand, "crew_deposit", "", 1000000)
+	if hub.crews["Whitebeard"].Bank != 15000 {
+		t.Error("Deposited more than the player has")
+	}
+
+	crewInput(hub, deckhand, "crew_withdraw", "", 100)
+	if hub.crews["Whitebeard"].Bank != 15000 {
+		t.Error("Members can't withdraw")
+	}
+	crewInput(hub, officer, "crew_withdraw", "", crewOfficerWithdrawLimit+1)
+	if hub.crews["Whitebeard"].Bank != 15000 {
+		t.Error("Officer withdrawal over the limit")
+	}
+	crewInput(hub, officer, "crew_withdraw", "", 5000)
+	crewInput(hub, captain, "crew_withdraw", "", 10000)
+	if hub.crews["Whitebeard"].Bank != 0 || officer.Money != 25000 || captain.Money != 30000 {
+		t.Errorf("Withdrawals: bank %d, officer %d, captain %d", hub.crews["Whitebeard"].Bank, officer.Money, captain.Money)
+	}
+
+	// Money moved is saved with the bank
+	saved, err := LoadUser("captain")
+	if err != nil {
+		t.Fatalf("LoadUser: %v", err)
+	}
+	crews, _ := LoadCrews()
+	if saved.Money != 30000 || crews["Whitebeard"].Bank != 0 {
+		t.Errorf("Saved money %d, bank %d", saved.Money, crews["Whitebeard"].Bank)
+	}
+
+	// The officer limit covers the whole window, not each withdrawal
+	crewInput(hub, captain, "crew_deposit", "", 30000)
+	crewInput(hub, officer, "crew_withdraw", "", 5000)
+	crewInput(hub, officer, "crew_withdraw", "", 1)
+	if hub.crews["Whitebeard"].Bank != 25000 || officer.Money != 30000 {
+		t.Errorf("Officer went past the window limit: bank %d, officer %d", hub.crews["Whitebeard"].Bank, officer.Money)
+	}
+	clock.Advance(crewWithdrawWindow * time.Millisecond)
+	crewInput(hub, officer, "crew_withdraw", "", 10000)
+	if hub.crews["Whitebeard"].Bank != 15000 {
+		t.Error("A new window should allow withdrawing again")
+	}
+}
+
+func TestCrew_DisbandRefundsLeader(t *testing.T) {
+	hub, ps := setupCrewTest(t, "captain", "mate")
+	captain, mate := ps[0], ps[1]
+	crewInput(hub, captain, "crew_create", "Kid Pirates", 0)
+	joinCrew(hub, captain, mate)
+	crewInput(hub, mate, "crew_deposit", "", 4000)
+
+	crewInput(hub, mate, "crew_disband", "", 0)
+	if _, ok := hub.crews["Kid Pirates"]; !ok {
+		t.Fatal("A member disbanded the crew")
+	}
+	crewInput(hub, captain, "crew_disband", "", 0)
+	if _, ok := hub.crews["Kid Pirates"]; ok {
+		t.Fatal("Crew still exists")
+	}
+	if captain.Money != 24000 || captain.Crew != "" || mate.Crew != "" {
+		t.Errorf("After disband: captain money %d, crews %q/%q", captain.Money, captain.Crew, mate.Crew)
+	}
+	if crews, _ := LoadCrews(); len(crews) != 0 {
+		t.Errorf("Saved crews = %v", crews)
+	}
+}
+
+func TestCrew_NoFriendlyFire(t *testing.T) {
+	hub, ps := setupCrewTest(t, "captain", "mate", "rival")
+	captain, mate, rival := ps[0], ps[1], ps[2]
+	for i, p := range ps {
+		p.Team = "neutral"
+		p.PvP = true
+		p.X, p.Z = 300+float64(i), 300 // Away from the safe zone
+	}
+	crewInput(hub, captain, "crew_create", "Buggy", 0)
+	joinCrew(hub, captain, mate)
+
+	handlePlayerDamage(hub, captain, mate.ID, 30, nil)
+	if mate.Health != 100 {
+		t.Errorf("Crewmate took damage: %d", mate.Health)
+	}
+	handlePlayerDamage(hub, captain, rival.ID, 30, nil)
+	if rival.Health == 100 {
+		t.Error("Outsider should take damage")
+	}
+}
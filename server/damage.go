@@ -0,0 +1,175 @@
+package main
+
+// Damage tags describe how a hit was delivered. Modifiers and hooks key
+// off them.
+const (
+	TagMelee   = "melee"   // Contact hit from a mob
+	TagAbility = "ability" // Mob or boss ability
+	TagBoss    = "boss"    // Dealt by a boss
+	TagPvP     = "pvp"     // Dealt by another player
+	TagDoT     = "dot"     // Poison or burn tick; can't be dodged
+)
+
+// DamageEvent is one hit on a player moving through the damage pipeline.
+// Mob or Attacker is set, except on damage over time which only has Source.
+type DamageEvent struct {
+	Mob      *Mob        // Source when a mob hit
+	Attacker *Player     // Source in PvP
+	Source   string      // Who applied the effect, for TagDoT ticks
+	Ability  *MobAbility // Mob ability that caused the hit, if any
+	Target   *Player
+	Base     int      // Damage before any modifier
+	Amount   int      // Running damage as modifiers apply
+	Tags     []string // TagMelee, TagAbility, ...
+	Now      int64    // ms
+
+	// Roll returns a number in [0, 1) for chance-based passives.
+	// dealDamageUnsafe defaults it to the target's room RNG.
+	Roll func() float64
+}
+
+func (e *DamageEvent) has(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *DamageEvent) roll() float64 {
+	return e.Roll()
+}
+
+// sourceName names the source in death messages.
+func (e *DamageEvent) sourceName() string {
+	if e.Mob != nil {
+		return e.Mob.Type
+	}
+	if e.Attacker != nil {
+		return e.Attacker.ID
+	}
+	if e.Source != "" {
+		return e.Source
+	}
+	return "unknown"
+}
+
+// DamageModifier is one step of the pipeline.
+type DamageModifier struct {
+	Name  string
+	Apply func(e *DamageEvent)
+}
+
+// damageModifiers change a hit before it lands, in order: the source's
+// damage buff, immunities and dodges, multiplicative and flat reductions,
+// the target's defense stat, then shields. Processing stops once a hit is reduced
+// to nothing.
+var damageModifiers = []DamageModifier{
+	{"damage_buff", func(e *DamageEvent) {
+		factor := 1.0
+		if e.Mob != nil {
+			factor = e.Mob.Effects.DamageFactor(e.Now)
+		} else if e.Attacker != nil {
+			factor = e.Attacker.Effects.DamageFactor(e.Now)
+		}
+		e.Amount = int(float64(e.Amount) * factor)
+	}},
+	{"bounce", func(e *DamageEvent) {
+		// Blunt hits from ordinary mobs bounce off
+		if e.Target.passiveSet().Bounce != nil && e.Mob != nil && !e.Mob.IsBoss && e.has(TagMelee) {
+			e.Amount = 0
+		}
+	}},
+	{"intangible", func(e *DamageEvent) {
+		// Small cuts pass straight through
+		if in := e.Target.passiveSet().Intangible; in != nil && e.Amount < in.Amount {
+			e.Amount = 0
+		}
+	}},
+	{"dodge", func(e *DamageEvent) {
+		if d := e.Target.passiveSet().Dodge; d != nil && !e.has(TagDoT) && e.roll() < d.Chance {
+			e.Amount = 0
+		}
+	}},
+	{"reduce", func(e *DamageEvent) {
+		if r := e.Target.passiveSet().Reduce; r != nil {
+			e.Amount = int(float64(e.Amount)*r.Factor) - r.Amount
+		}
+	}},
+	{"defense_stat", func(e *DamageEvent) {
+		e.Amount = e.Target.mitigateDamage(e.Amount)
+	}},
+	{"shield", func(e *DamageEvent) {
+		e.Amount = e.Target.Effects.AbsorbShield(e.Amount, e.Now)
+	}},
+}
+
+// damageHooks react to a hit after it resolved, whether or not it did
+// damage. The fruit reactions only answer contact hits from mobs.
+var damageHooks = []DamageModifier{
+	{"ability_effect", func(e *DamageEvent) {
+		if e.Ability != nil && e.Ability.Effect != nil && e.Amount > 0 && !e.Target.Dead {
+			fx := e.Ability.Effect
+			e.Target.Effects.Apply(fx.Kind, fx.Duration, fx.Magnitude, e.sourceName(), e.Now)
+		}
+	}},
+	{"thorns", func(e *DamageEvent) {
+		if th := e.Target.passiveSet().Thorns; th != nil && contactHit(e) && e.Amount > 0 {
+			hurtMobNonLethal(e.Mob, th.Amount)
+		}
+	}},
+	{"proc", func(e *DamageEvent) {
+		if pr := e.Target.passiveSet().Proc; pr != nil && contactHit(e) && e.Amount > 0 && e.roll() < pr.Chance {
+			e.Mob.Effects.Apply(pr.Effect.Kind, pr.Effect.Duration, pr.Effect.Magnitude, e.Target.ID, e.Now)
+		}
+	}},
+}
+
+func contactHit(e *DamageEvent) bool {
+	return e.Mob != nil && e.has(TagMelee)
+}
+
+// hurtMobNonLethal leaves the mob on at least 1 health so the kill, and
+// its rewards, still go through handleMobDamage.
+func hurtMobNonLethal(mob *Mob, damage int) {
+	mob.Health -= damage
+	if mob.Health < 1 {
+		mob.Health = 1
+	}
+}
+
+// resolveDamage runs the modifiers and returns the damage the hit deals.
+func resolveDamage(e *DamageEvent) int {
+	e.Amount = e.Base
+	for _, m := range damageModifiers {
+		if e.Amount <= 0 {
+			e.Amount = 0
+			break
+		}
+		m.Apply(e)
+	}
+	if e.Amount < 0 {
+		e.Amount = 0
+	}
+	return e.Amount
+}
+
+// dealDamageUnsafe is the one way players take damage: it resolves the
+// hit, applies it with death handling, then runs the hooks. Hits on dead
+// players are ignored.
+// Caller MUST hold h.mutex.
+func (h *Hub) dealDamageUnsafe(e *DamageEvent) (dealt int, killed bool) {
+	if e.Target.Dead {
+		return 0, false
+	}
+	if e.Roll == nil {
+		e.Roll = h.mobsUnsafe(e.Target).rng.Float64
+	}
+	dealt = resolveDamage(e)
+	killed = h.damagePlayerUnsafe(e.Target, dealt, e.sourceName(), e.Now)
+	for _, hook := range damageHooks {
+		hook.Apply(e)
+	}
+	return dealt, killed
+}
@@ -0,0 +1,113 @@
+package main
+
+import "testing"
+
+func mobHit(fruit string, base int, tags ...string) *DamageEvent {
+	return &DamageEvent{
+		Mob:    &Mob{Type: "Gorilla", Health: 100, MaxHealth: 100},
+		Target: &Player{ID: "p", CurrentFruit: fruit, Health: 100, MaxHealth: 100},
+		Base:   base,
+		Tags:   tags,
+		Roll:   func() float64 { return 0 }, // Every chance succeeds
+	}
+}
+
+func TestResolveDamage_Passives(t *testing.T) {
+	tests := []struct {
+		fruit string
+		base  int
+		want  int
+	}{
+		{"melee", 30, 30},
+		{"Rubber Fruit", 30, 0},
+		{"Chop Fruit", 19, 0},
+		{"Chop Fruit", 30, 30},
+		{"Smoke Fruit", 30, 0},
+		{"Diamond Fruit", 30, 15},
+		{"Leopard Fruit", 30, 15},
+		{"Dragon Fruit", 30, 25},
+		{"Dragon Fruit", 4, 0},
+	}
+	for _, tt := range tests {
+		if got := resolveDamage(mobHit(tt.fruit, tt.base, TagMelee)); got != tt.want {
+			t.Errorf("%s hit for %d: got %d, want %d", tt.fruit, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestResolveDamage_Order(t *testing.T) {
+	// Defense applies after the fruit: 30 halved by Diamond, then 50 defense halves again
+	e := mobHit("Diamond Fruit", 30, TagMelee)
+	e.Target.Stats.Defense = 50
+	if got := resolveDamage(e); got != 7 {
+		t.Errorf("got %d, want 7", got)
+	}
+
+	// A failed dodge roll lets the hit through
+	e = mobHit("Smoke Fruit", 30, TagMelee)
+	e.Roll = func() float64 { return 0.9 }
+	if got := resolveDamage(e); got != 30 {
+		t.Errorf("Undodged smoke hit: got %d, want 30", got)
+	}
+}
+
+func TestResolveDamage_RubberOnlyStopsOrdinaryMelee(t *testing.T) {
+	boss := mobHit("Rubber Fruit", 30, TagMelee)
+	boss.Mob.IsBoss = true
+	if got := resolveDamage(boss); got != 30 {
+		t.Errorf("Boss melee on Rubber: got %d, want 30", got)
+	}
+	if got := resolveDamage(mobHit("Rubber Fruit", 30, TagAbility)); got != 30 {
+		t.Errorf("Mob ability on Rubber: got %d, want 30", got)
+	}
+}
+
+func TestDealDamage_Hooks(t *testing.T) {
+	hub := newHub()
+
+	e := mobHit("Spike Fruit", 30, TagMelee)
+	hub.dealDamageUnsafe(e)
+	if e.Target.Health != 70 || e.Mob.Health != 90 {
+		t.Errorf("Spike: player %d mob %d, want 70/90", e.Target.Health, e.Mob.Health)
+	}
+
+	// Thorns never finish a mob off
+	e = mobHit("Bomb Fruit", 30, TagMelee)
+	e.Mob.Health = 5
+	hub.dealDamageUnsafe(e)
+	if e.Mob.Health != 1 {
+		t.Errorf("Bomb left the mob on %d, want 1", e.Mob.Health)
+	}
+
+	e = mobHit("Venom Fruit", 30, TagMelee)
+	e.Now = 1000
+	hub.dealDamageUnsafe(e)
+	if fx := e.Mob.Effects.Get(EffectPoison, 1000); fx == nil || fx.ExpiresAt != 6000 {
+		t.Errorf("Venom poison = %+v, want it to last until 6000", fx)
+	}
+
+	// Reactions need contact
+	e = mobHit("Spike Fruit", 30, TagAbility)
+	hub.dealDamageUnsafe(e)
+	if e.Mob.Health != 100 {
+		t.Error("Thorns answered a ranged ability")
+	}
+}
+
+func TestDealDamage_PvPUsesPassives(t *testing.T) {
+	hub := newHub()
+	attacker := &Player{ID: "a", Health: 100, MaxHealth: 100}
+	victim := &Player{ID: "v", CurrentFruit: "Diamond Fruit", Health: 100, MaxHealth: 100}
+	dealt, killed := hub.dealDamageUnsafe(&DamageEvent{Attacker: attacker, Target: victim, Base: 40, Tags: []string{TagPvP}})
+	if dealt != 20 || killed || victim.Health != 80 {
+		t.Errorf("dealt %d killed %v health %d, want 20/false/80", dealt, killed, victim.Health)
+	}
+
+	dealt, killed = hub.dealDamageUnsafe(&DamageEvent{Attacker: attacker, Target: victim, Base: 1000, Tags: []string{TagPvP}})
+	if !killed || !victim.Dead {
+		t.Fatal("Lethal PvP hit should kill")
+	}
+	if dealt, _ = hub.dealDamageUnsafe(&DamageEvent{Attacker: attacker, Target: victim, Base: 10}); dealt != 0 {
+		t.Error("Dead players take no damage")
+	}
+}
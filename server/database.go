@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
@@ -14,10 +15,55 @@ import (
 
 var db *sql.DB
 
+// Audit log of completed trades
+const createTradesTableSQL = `CREATE TABLE IF NOT EXISTS trades (
+	"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+	"player_a" TEXT,
+	"player_b" TEXT,
+	"offer_a" TEXT,
+	"offer_b" TEXT,
+	"created_at" INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_trades_player_a ON trades(player_a);
+CREATE INDEX IF NOT EXISTS idx_trades_player_b ON trades(player_b);`
+
+// Crews and their rosters. A player is in at most one crew.
+const createCrewsTableSQL = `CREATE TABLE IF NOT EXISTS crews (
+	"name" TEXT PRIMARY KEY,
+	"bank" INTEGER,
+	"created_at" INTEGER
+);
+CREATE TABLE IF NOT EXISTS crew_members (
+	"crew" TEXT,
+	"username" TEXT UNIQUE,
+	"rank" TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_crew_members_crew ON crew_members(crew);`
+
+// Leaderboard summary. Each player has a row per stat and period; score is
+// value minus base, where base is the value when the period started (0 for
+// the all-time board).
+const createLeaderboardTableSQL = `CREATE TABLE IF NOT EXISTS leaderboard (
+	"username" TEXT,
+	"stat" TEXT,
+	"period" TEXT,
+	"period_start" INTEGER,
+	"base" INTEGER,
+	"value" INTEGER,
+	"score" INTEGER,
+	PRIMARY KEY (username, stat, period)
+);
+CREATE INDEX IF NOT EXISTS idx_leaderboard_rank ON leaderboard(stat, period, period_start, score DESC);`
+
 func initDB() {
 	LoadAdmins() // Load persistent admins
+	openDB("./bloxfruits.db")
+}
+
+// openDB opens the SQLite database at path and creates any missing tables.
+func openDB(path string) {
 	var err error
-	db, err = sql.Open("sqlite3", "./bloxfruits.db")
+	db, err = sql.Open("sqlite3", path)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -33,6 +79,15 @@ func initDB() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if _, err = db.Exec(createTradesTableSQL); err != nil {
+		log.Fatal(err)
+	}
+	if _, err = db.Exec(createCrewsTableSQL); err != nil {
+		log.Fatal(err)
+	}
+	if _, err = db.Exec(createLeaderboardTableSQL); err != nil {
+		log.Fatal(err)
+	}
 	log.Println("Database initialized.")
 }
 
@@ -44,7 +99,16 @@ func ResetDB() {
 	if err != nil {
 		log.Fatal("Failed to drop users table:", err)
 	}
-	log.Println("Database Reset: Users table dropped.")
+	if _, err = db.Exec("DROP TABLE IF EXISTS trades"); err != nil {
+		log.Fatal("Failed to drop trades table:", err)
+	}
+	if _, err = db.Exec("DROP TABLE IF EXISTS crews; DROP TABLE IF EXISTS crew_members"); err != nil {
+		log.Fatal("Failed to drop crew tables:", err)
+	}
+	if _, err = db.Exec("DROP TABLE IF EXISTS leaderboard"); err != nil {
+		log.Fatal("Failed to drop leaderboard table:", err)
+	}
+	log.Println("Database Reset: Users, trades, crew and leaderboard tables dropped.")
 
 	// Re-create
 	createTableSQL := `CREATE TABLE IF NOT EXISTS users (
@@ -57,6 +121,15 @@ func ResetDB() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if _, err = db.Exec(createTradesTableSQL); err != nil {
+		log.Fatal(err)
+	}
+	if _, err = db.Exec(createCrewsTableSQL); err != nil {
+		log.Fatal(err)
+	}
+	if _, err = db.Exec(createLeaderboardTableSQL); err != nil {
+		log.Fatal(err)
+	}
 	log.Println("Database Re-initialized.")
 }
 
@@ -85,11 +158,14 @@ func RegisterUser(username, password string) error {
 	defaultData := Player{
 		ID:        username,
 		Role:      role,
+		Level:     1,
 		X:         0,
 		Y:         3.5,
 		Z:         0,
 		Health:    100,
 		MaxHealth: 100,
+		Energy:    baseMaxEnergy,
+		MaxEnergy: baseMaxEnergy,
 		Team:      "neutral",
 		Money:     5000,
 		Inventory: NewInventory("melee"),
@@ -182,6 +258,242 @@ func SaveUsersBatch(playerData map[string]string) error {
 	return tx.Commit()
 }
 
+// SaveTrade persists both sides of a completed trade and its audit record in
+// a single transaction.
+func SaveTrade(a, b *Player, rec *TradeRecord) error {
+	dataA, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	dataB, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	offerA, _ := json.Marshal(rec.OfferA)
+	offerB, _ := json.Marshal(rec.OfferB)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, u := range []struct{ id, data string }{{a.ID, string(dataA)}, {b.ID, string(dataB)}} {
+		if _, err := tx.Exec("UPDATE users SET data = ? WHERE username = ?", u.data, u.id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	res, err := tx.Exec("INSERT INTO trades (player_a, player_b, offer_a, offer_b, created_at) VALUES (?, ?, ?, ?, ?)",
+		rec.PlayerA, rec.PlayerB, string(offerA), string(offerB), rec.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	rec.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// LoadTradeHistory returns the most recent trades a player took part in.
+func LoadTradeHistory(username string, limit int) ([]TradeRecord, error) {
+	rows, err := db.Query(`SELECT id, player_a, player_b, offer_a, offer_b, created_at FROM trades
+		WHERE player_a = ? OR player_b = ? ORDER BY id DESC LIMIT ?`, username, username, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []TradeRecord{}
+	for rows.Next() {
+		var rec TradeRecord
+		var offerA, offerB string
+		if err := rows.Scan(&rec.ID, &rec.PlayerA, &rec.PlayerB, &offerA, &offerB, &rec.CreatedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(offerA), &rec.OfferA)
+		json.Unmarshal([]byte(offerB), &rec.OfferB)
+		history = append(history, rec)
+	}
+	return history, rows.Err()
+}
+
+// LoadCrews reads every crew and its roster.
+func LoadCrews() (map[string]*Crew, error) {
+	crews := make(map[string]*Crew)
+	rows, err := db.Query("SELECT name, bank, created_at FROM crews")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		cr := &Crew{Members: make(map[string]string)}
+		if err := rows.Scan(&cr.Name, &cr.Bank, &cr.CreatedAt); err != nil {
+			return nil, err
+		}
+		crews[cr.Name] = cr
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	members, err := db.Query("SELECT crew, username, rank FROM crew_members")
+	if err != nil {
+		return nil, err
+	}
+	defer members.Close()
+	for members.Next() {
+		var crew, username, rank string
+		if err := members.Scan(&crew, &username, &rank); err != nil {
+			return nil, err
+		}
+		if cr, ok := crews[crew]; ok {
+			cr.Members[username] = rank
+		}
+	}
+	return crews, members.Err()
+}
+
+// SaveCrew writes a crew and replaces its roster.
+func SaveCrew(cr *Crew) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO crews (name, bank, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET bank=excluded.bank`, cr.Name, cr.Bank, cr.CreatedAt); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM crew_members WHERE crew = ?", cr.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for username, rank := range cr.Members {
+		if _, err := tx.Exec("INSERT INTO crew_members (crew, username, rank) VALUES (?, ?, ?)", cr.Name, username, rank); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveCrewBank saves a deposit or withdrawal: the player's money and the
+// crew bank change in one transaction.
+func SaveCrewBank(p *Player, cr *Crew) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE users SET data = ? WHERE username = ?", string(data), p.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec("UPDATE crews SET bank = ? WHERE name = ?", cr.Bank, cr.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteCrew removes a crew and its roster and saves the leader, who
+// receives what was left in the bank.
+func DeleteCrew(name string, leader *Player) error {
+	data, err := json.Marshal(leader)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, q := range []string{"DELETE FROM crews WHERE name = ?", "DELETE FROM crew_members WHERE crew = ?"} {
+		if _, err := tx.Exec(q, name); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec("UPDATE users SET data = ? WHERE username = ?", string(data), leader.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordLeaderboardSamples updates every board with the players' current
+// values. A row from an earlier period is reset: its last value becomes the
+// new base.
+func RecordLeaderboardSamples(samples []LeaderboardSample, now time.Time) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO leaderboard (username, stat, period, period_start, base, value, score)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(username, stat, period) DO UPDATE SET
+			base = CASE WHEN period_start = excluded.period_start THEN base ELSE value END,
+			score = excluded.value - CASE WHEN period_start = excluded.period_start THEN base ELSE value END,
+			value = excluded.value,
+			period_start = excluded.period_start`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, period := range leaderboardPeriods {
+		start := periodStart(period, now)
+		for _, s := range samples {
+			for stat, value := range s.Values {
+				// Periodic boards start counting from the first sample
+				base := value
+				if period == PeriodAll {
+					base = 0
+				}
+				if _, err := stmt.Exec(s.Username, stat, period, start, base, value, value-base); err != nil {
+					tx.Rollback()
+					return err
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadLeaderboard returns a page of a board and the number of ranked players.
+func LoadLeaderboard(stat, period string, start int64, offset, limit int) ([]LeaderboardEntry, int, error) {
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM leaderboard WHERE stat = ? AND period = ? AND period_start = ?",
+		stat, period, start).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT username, score FROM leaderboard
+		WHERE stat = ? AND period = ? AND period_start = ?
+		ORDER BY score DESC, username ASC LIMIT ? OFFSET ?`, stat, period, start, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []LeaderboardEntry{}
+	for rows.Next() {
+		e := LeaderboardEntry{Rank: offset + len(entries) + 1}
+		if err := rows.Scan(&e.Username, &e.Score); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
 func LoadUser(username string) (*Player, error) {
 	var data string
 	row := db.QueryRow("SELECT data FROM users WHERE username = ?", username)
@@ -195,6 +507,7 @@ func LoadUser(username string) (*Player, error) {
 		return nil, err
 	}
 	player.ID = username
+	player.applyDefaults()
 	return &player, nil
 }
 
@@ -2,13 +2,21 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
+// useTestDB opens a fresh database in a temporary directory so tests never
+// touch ./bloxfruits.db.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	openDB(filepath.Join(t.TempDir(), "test.db"))
+	t.Cleanup(func() { db.Close() })
+}
+
 func TestRegisterUser_OwnerRole(t *testing.T) {
 	// Initialize a temporary database for testing
-	initDB()
-	defer ResetDB()
+	useTestDB(t)
 
 	// Clean up environment variables after tests
 	defer os.Unsetenv("OWNER_PASSWORD")
@@ -86,8 +94,7 @@ func TestRegisterUser_OwnerRole(t *testing.T) {
 
 func TestSaveUser(t *testing.T) {
 	// Initialize a temporary database for testing
-	initDB()
-	defer ResetDB()
+	useTestDB(t)
 
 	// 1. Setup: Register a dummy user
 	testUser := "SaveTestUser"
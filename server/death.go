@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Respawn points a player can pick.
+const (
+	RespawnSpawn  = "spawn"  // The island set with set_spawn, Start Island by default
+	RespawnIsland = "island" // The last island the player stood on
+)
+
+const respawnY = 3.5
+
+// DeathConfig controls what dying costs. Set from flags in main.
+type DeathConfig struct {
+	RespawnDelay time.Duration
+	MoneyPenalty float64 // Share of carried money lost on death
+	ExpPenalty   float64 // Share of progress toward the next level lost
+}
+
+var deathConfig = DeathConfig{RespawnDelay: 5 * time.Second}
+
+// blockedWhileDead are the inputs a dead player can't send.
+var blockedWhileDead = map[string]bool{
+	"move":        true,
+	"mob_hit":     true,
+	"player_hit":  true,
+	"ability_hit": true,
+	"pickup_item": true,
+	"eat_fruit":   true,
+	"set_spawn":   true,
+}
+
+// damagePlayerUnsafe takes already mitigated damage off the player and
+// runs the death handling if it was fatal. killer names the source for
+// the death message. Dead players take no damage. Returns true on a kill.
+// Caller MUST hold h.mutex.
+func (h *Hub) damagePlayerUnsafe(p *Player, damage int, killer string, now int64) bool {
+	if p.Dead || damage <= 0 {
+		return false
+	}
+	p.Health -= damage
+	if p.Health > 0 {
+		return false
+	}
+	h.killPlayerUnsafe(p, killer, now)
+	return true
+}
+
+// killPlayerUnsafe puts the player in the death state until
+// respawnPlayersUnsafe brings them back, and charges the death penalty.
+// Caller MUST hold h.mutex.
+func (h *Hub) killPlayerUnsafe(p *Player, killer string, now int64) {
+	p.Health = 0
+	p.Dead = true
+	p.RespawnAt = now + deathConfig.RespawnDelay.Milliseconds()
+	p.CombatTagEnd = 0
+	p.Effects = StatusEffects{}
+
+	moneyLost := int(float64(p.Money) * deathConfig.MoneyPenalty)
+	expLost := int(float64(p.Exp) * deathConfig.ExpPenalty)
+	p.Money -= moneyLost
+	p.Exp -= expLost
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":         "player_died",
+		"killer":       killer,
+		"respawnAt":    p.RespawnAt,
+		"delay":        deathConfig.RespawnDelay.Milliseconds(),
+		"respawnPoint": p.respawnPoint(),
+		"points":       p.respawnOptions(),
+		"moneyLost":    moneyLost,
+		"expLost":      expLost,
+	})
+	h.sendToPlayerUnsafe(p.ID, msg)
+}
+
+// respawnPlayersUnsafe brings back every dead player whose timer ran out.
+// Caller MUST hold h.mutex.
+func (h *Hub) respawnPlayersUnsafe(now int64) {
+	for _, p := range h.players {
+		if p.Dead && now >= p.RespawnAt {
+			h.respawnUnsafe(p)
+		}
+	}
+}
+
+// Caller MUST hold h.mutex.
+func (h *Hub) respawnUnsafe(p *Player) {
+	is := p.respawnIsland()
+	p.Dead = false
+	p.RespawnAt = 0
+	p.Health = p.MaxHealth
+	p.X, p.Y, p.Z = is.X, respawnY, is.Z
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type": "teleport",
+		"x":    p.X,
+		"y":    p.Y,
+		"z":    p.Z,
+		"msg":  "Respawned at " + is.Name,
+	})
+	h.sendToPlayerUnsafe(p.ID, msg)
+	h.sendToPlayerUnsafe(p.ID, []byte(`{"type":"player_respawned"}`))
+}
+
+func (p *Player) respawnPoint() string {
+	if p.RespawnPoint == RespawnIsland {
+		return RespawnIsland
+	}
+	return RespawnSpawn
+}
+
+// respawnIsland resolves the chosen respawn point.
+func (p *Player) respawnIsland() *Island {
+	return p.respawnIslandFor(p.respawnPoint())
+}
+
+// respawnIslandFor resolves a respawn point, falling back to Start Island
+// for unknown names or islands above the player's level.
+func (p *Player) respawnIslandFor(point string) *Island {
+	name := p.SpawnIsland
+	if point == RespawnIsland {
+		name = p.LastIsland
+	}
+	if is := islandByName(name); is != nil && p.Level >= is.MinLevel {
+		return is
+	}
+	return &islands[0]
+}
+
+// respawnOptions lists where each respawn point would put the player.
+func (p *Player) respawnOptions() map[string]string {
+	return map[string]string{
+		RespawnSpawn:  p.respawnIslandFor(RespawnSpawn).Name,
+		RespawnIsland: p.respawnIslandFor(RespawnIsland).Name,
+	}
+}
+
+func (p *Player) setRespawnPoint(point string) error {
+	if point != RespawnSpawn && point != RespawnIsland {
+		return fmt.Errorf("unknown respawn point %q", point)
+	}
+	p.RespawnPoint = point
+	return nil
+}
+
+// setSpawn makes the island the player stands on their spawn.
+func (p *Player) setSpawn() (*Island, error) {
+	is := islandAt(p.X, p.Z)
+	if is == nil {
+		return nil, fmt.Errorf("stand on an island to set your spawn")
+	}
+	p.SpawnIsland = is.Name
+	return is, nil
+}
+
+func islandByName(name string) *Island {
+	for i := range islands {
+		if islands[i].Name == name {
+			return &islands[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func setupDeathTest(t *testing.T) (*Hub, *Player) {
+	t.Helper()
+	saved := deathConfig
+	t.Cleanup(func() { deathConfig = saved })
+	deathConfig = DeathConfig{RespawnDelay: 5 * time.Second}
+
+	hub := newHub()
+	p := &Player{ID: "p", RoomID: defaultRoomID, Level: 20, Health: 100, MaxHealth: 100, X: -50, Z: -50, Money: 1000, Exp: 200, Inventory: NewInventory()}
+	hub.players[p.ID] = p
+	return hub, p
+}
+
+func TestDeath_MobKillAndRespawnTimer(t *testing.T) {
+	hub, p := setupDeathTest(t)
+	now := time.Now().UnixMilli()
+
+	if !hub.damagePlayerUnsafe(p, 150, "Gorilla", now) {
+		t.Fatal("Fatal damage should kill")
+	}
+	if !p.Dead || p.Health != 0 || p.RespawnAt != now+5000 {
+		t.Fatalf("After death: dead=%v health=%d respawnAt=%d", p.Dead, p.Health, p.RespawnAt)
+	}
+	if hub.damagePlayerUnsafe(p, 10, "Gorilla", now) {
+		t.Error("The dead can't die again")
+	}
+
+	hub.respawnPlayersUnsafe(now + 4999)
+	if !p.Dead {
+		t.Fatal("Respawned before the delay")
+	}
+	hub.respawnPlayersUnsafe(now + 5000)
+	if p.Dead || p.Health != p.MaxHealth || p.X != 0 || p.Z != 0 || p.Y != respawnY {
+		t.Errorf("After respawn: dead=%v health=%d at (%v,%v,%v)", p.Dead, p.Health, p.X, p.Y, p.Z)
+	}
+}
+
+func TestDeath_BlocksInput(t *testing.T) {
+	hub, p := setupDeathTest(t)
+	hub.killPlayerUnsafe(p, "Gorilla", time.Now().UnixMilli())
+	hub.handleInput(nil, p, InputMessage{Type: "move", X: -40, Z: -40})
+	if p.X != -50 {
+		t.Error("Dead player moved")
+	}
+	hub.handleInput(nil, p, InputMessage{Type: "set_respawn_point", Item: RespawnIsland})
+	if p.RespawnPoint != RespawnIsland {
+		t.Error("Choosing a respawn point should work while dead")
+	}
+}
+
+func TestDeath_RespawnPoints(t *testing.T) {
+	hub, p := setupDeathTest(t)
+
+	// Walking onto an island remembers it; set_spawn makes it the spawn
+	hub.handleInput(nil, p, InputMessage{Type: "move", X: 50, Z: 50})
+	if p.LastIsland != "Snow Island" {
+		t.Fatalf("LastIsland = %q", p.LastIsland)
+	}
+	hub.handleInput(nil, p, InputMessage{Type: "move", X: -50, Z: -50})
+	hub.handleInput(nil, p, InputMessage{Type: "set_spawn"})
+	if p.SpawnIsland != "Jungle Island" {
+		t.Fatalf("SpawnIsland = %q", p.SpawnIsland)
+	}
+	hub.handleInput(nil, p, InputMessage{Type: "move", X: 50, Z: 50})
+
+	hub.killPlayerUnsafe(p, "Gorilla", 0)
+	hub.respawnUnsafe(p)
+	if p.X != -50 || p.Z != -50 {
+		t.Errorf("Spawn respawn at (%v,%v), want Jungle Island", p.X, p.Z)
+	}
+
+	p.RespawnPoint = RespawnIsland
+	p.LastIsland = "Snow Island"
+	hub.respawnUnsafe(p)
+	if p.X != 50 || p.Z != 50 {
+		t.Errorf("Island respawn at (%v,%v), want Snow Island", p.X, p.Z)
+	}
+
+	// Islands above the player's level fall back to Start Island
+	p.Level = 1
+	hub.respawnUnsafe(p)
+	if p.X != 0 || p.Z != 0 {
+		t.Errorf("Locked island respawn at (%v,%v), want Start Island", p.X, p.Z)
+	}
+}
+
+func TestDeath_Penalty(t *testing.T) {
+	hub, p := setupDeathTest(t)
+	hub.killPlayerUnsafe(p, "Gorilla", 0)
+	if p.Money != 1000 || p.Exp != 200 {
+		t.Errorf("No penalty configured, lost money/exp: %d/%d", p.Money, p.Exp)
+	}
+
+	deathConfig.MoneyPenalty = 0.1
+	deathConfig.ExpPenalty = 0.5
+	hub.respawnUnsafe(p)
+	hub.killPlayerUnsafe(p, "Gorilla", 0)
+	if p.Money != 900 || p.Exp != 100 || p.Level != 20 {
+		t.Errorf("Money %d exp %d level %d, want 900/100/20", p.Money, p.Exp, p.Level)
+	}
+}
+
+func TestDeath_PvPKill(t *testing.T) {
+	hub, victim := setupDeathTest(t)
+	attacker := &Player{ID: "a", RoomID: defaultRoomID, Team: "pirate", PvP: true, Level: 20, Health: 100, MaxHealth: 100, X: -50, Z: -50}
+	hub.players[attacker.ID] = attacker
+	victim.PvP = true
+	victim.Team = "marine"
+
+	handlePlayerDamage(hub, attacker, victim.ID, 1000, nil)
+	if !victim.Dead || victim.inCombat(time.Now().UnixMilli()) {
+		t.Fatalf("Victim dead=%v, combat tag should clear on death", victim.Dead)
+	}
+	money := attacker.Money
+	handlePlayerDamage(hub, attacker, victim.ID, 1000, nil)
+	if attacker.Money != money {
+		t.Error("A dead player was killed twice")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+// fruitAbilities lists the abilities each devil fruit grants, using the
+// ability names the client sends in ability_hit.
+var fruitAbilities = map[string][]string{
+	"Rocket Fruit":   {"Missile", "RocketCrash"},
+	"Spin Fruit":     {"Tornado", "RazorWind", "TornadoSpin"},
+	"Chop Fruit":     {"ChopPunch", "ChopFestival"},
+	"Spring Fruit":   {"SpringSnipe", "SpringLeap"},
+	"Bomb Fruit":     {"BombShot", "SelfDestruct", "Explosion"},
+	"Smoke Fruit":    {"SmokeBomber", "SmokeTornado"},
+	"Spike Fruit":    {"SpikeShot", "SpikeField"},
+	"Flame Fruit":    {"Fireball", "FlamePillar", "FireBullet", "FirePillar"},
+	"Falcon Fruit":   {"TalonRush", "TalonHit"},
+	"Ice Fruit":      {"IceShards", "IceSurge", "IceSpear", "IceAge"},
+	"Sand Fruit":     {"DesertSword", "SandTornado"},
+	"Dark Fruit":     {"BlackHole", "DarkBomb"},
+	"Diamond Fruit":  {"DiamondBody", "DiamondBolt"},
+	"Light Fruit":    {"LightSpeed", "LightBeam", "LightFlight"},
+	"Love Fruit":     {"LoveBeam", "MellowShot", "LoveZone"},
+	"Rubber Fruit":   {"RubberPistol"},
+	"Barrier Fruit":  {"Barrier"},
+	"Magma Fruit":    {"MagmaRain"},
+	"Quake Fruit":    {"Tsunami"},
+	"Buddha Fruit":   {"Transform"},
+	"String Fruit":   {"Parasite"},
+	"Phoenix Fruit":  {"Heal"},
+	"Rumble Fruit":   {"Thunder"},
+	"Paw Fruit":      {"PawShot", "PainRepel"},
+	"Gravity Fruit":  {"Meteor"},
+	"Dough Fruit":    {"MochiPunch", "Donut"},
+	"Shadow Fruit":   {"ShadowBall", "ShadowEruption"},
+	"Venom Fruit":    {"PoisonDagger", "VenomTransform"},
+	"Control Fruit":  {"Room"},
+	"Dragon Fruit":   {"DragonBreath"},
+	"Leopard Fruit":  {"Transform"},
+	"Soul Fruit":     {"SoulBeam", "SoulSnatch"},
+	"Spirit Fruit":   {"SpiritBarrage", "SpiritWrath"},
+	"Portal Fruit":   {"PortalDash", "Banishment"},
+	"Blizzard Fruit": {"Snowflake", "BlizzardDomain"},
+	"Sound Fruit":    {"SoundBlast", "Symphony"},
+	"Mammoth Fruit":  {"Transform", "AncientRoar"},
+	"T-Rex Fruit":    {"Transform", "TailSwipe"},
+	"Kitsune Fruit":  {"FoxFire", "Transform"},
+	"Gas Fruit":      {"GasZone", "GasBlast"},
+}
+
+// abilityEffects are the status effects fruit abilities leave on the mob
+// they hit.
+var abilityEffects = map[string]EffectSpec{
+	"LoveBeam":     {Kind: EffectCharm, Duration: 5000},
+	"Fireball":     {Kind: EffectBurn, Duration: 3000, Magnitude: 3},
+	"FlamePillar":  {Kind: EffectBurn, Duration: 4000, Magnitude: 4},
+	"IceShards":    {Kind: EffectSlow, Duration: 3000, Magnitude: 0.5},
+	"IceSurge":     {Kind: EffectFreeze, Duration: 2000},
+	"PoisonDagger": {Kind: EffectPoison, Duration: 5000, Magnitude: 2},
+}
+
+// selfEffects are fruit abilities that buff the caster instead of hitting
+// a mob.
+var selfEffects = map[string]EffectSpec{
+	"Barrier":        {Kind: EffectShield, Duration: 10000, Magnitude: 60},
+	"DiamondBody":    {Kind: EffectShield, Duration: 8000, Magnitude: 40},
+	"VenomTransform": {Kind: EffectDamageBuff, Duration: 15000, Magnitude: 0.25},
+}
+
+// isFruit reports whether an item is an edible devil fruit.
+func isFruit(item string) bool {
+	_, ok := fruitAbilities[item]
+	return ok
+}
+
+// fruitGrantsAbility reports whether a fruit allows the ability.
+func fruitGrantsAbility(fruit, ability string) bool {
+	for _, a := range fruitAbilities[fruit] {
+		if a == ability {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFruitAbilities_CoverRollableFruits(t *testing.T) {
+	// Every fruit a player can roll must be edible
+	for _, fruit := range rollableFruits {
+		if !isFruit(fruit) {
+			t.Fatalf("%s has no ability mapping", fruit)
+		}
+	}
+}
+
+func TestRollRandomFruit_ReplaysSeed(t *testing.T) {
+	a, b := rand.New(rand.NewSource(3)), rand.New(rand.NewSource(3))
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		fruit := rollRandomFruit(a, 1)
+		if other := rollRandomFruit(b, 1); other != fruit {
+			t.Fatalf("Roll %d: %s vs %s from the same seed", i, fruit, other)
+		}
+		seen[fruit] = true
+	}
+	if len(seen) < 2 {
+		t.Error("Rolls should still vary within a run")
+	}
+}
+
+func TestFruitGrantsAbility(t *testing.T) {
+	tests := []struct {
+		fruit, ability string
+		want           bool
+	}{
+		{"Flame Fruit", "Fireball", true},
+		{"Flame Fruit", "DragonBreath", false},
+		{"Dragon Fruit", "DragonBreath", true},
+		{"", "Fireball", false},
+		{"Unknown Fruit", "Fireball", false},
+	}
+	for _, tc := range tests {
+		if got := fruitGrantsAbility(tc.fruit, tc.ability); got != tc.want {
+			t.Errorf("fruitGrantsAbility(%q, %q) = %v, want %v", tc.fruit, tc.ability, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PvP kill rewards. Marines and pirates climb separate tracks: marines earn
+// honor for hunting pirates with a bounty and lose it for killing civilians
+// (neutral players); pirates (and anyone else who kills players) earn
+// bounty. Marines can't fight each other, see handlePlayerDamage.
+const (
+	pvpKillMoney       = 1000
+	pvpBaseBounty      = 2500 // Bounty for any player kill
+	pvpBountyShare     = 0.1  // Share of the victim's bounty added on top
+	pvpBountyLoss      = 1000 // Bounty the victim loses, at most
+	marineBaseHonor    = 100  // Honor for taking down a wanted pirate
+	marineHonorShare   = 0.05 // Share of the pirate's bounty added on top
+	marineHonorPenalty = 500  // Honor lost for killing a civilian
+)
+
+// pvpKillRewards returns the bounty and honor the attacker gains (negative
+// honor is a loss) for killing victim. It reads the victim's bounty before
+// any loss is applied.
+func pvpKillRewards(attacker, victim *Player) (bounty, honor int) {
+	if attacker.Team != "marine" {
+		return pvpBaseBounty + int(float64(victim.Bounty)*pvpBountyShare), 0
+	}
+	if victim.Team == "neutral" {
+		return 0, -marineHonorPenalty
+	}
+	if victim.Team != "pirate" || victim.Bounty <= 0 {
+		return 0, 0
+	}
+	return 0, marineBaseHonor + int(float64(victim.Bounty)*marineHonorShare)
+}
+
+// applyPvPKill pays the attacker and takes the victim's bounty loss. It
+// returns the attacker's bounty and honor changes.
+func applyPvPKill(attacker, victim *Player) (bounty, honor int) {
+	bounty, honor = pvpKillRewards(attacker, victim)
+	attacker.Bounty += bounty
+	attacker.Honor += honor
+	if attacker.Honor < 0 {
+		attacker.Honor = 0
+	}
+	attacker.Money += pvpKillMoney
+
+	loss := pvpBountyLoss
+	if victim.Bounty < loss {
+		loss = victim.Bounty
+	}
+	if loss > 0 {
+		victim.Bounty -= loss
+	}
+	return bounty, honor
+}
+
+func pvpKillNotification(bounty, honor int) []byte {
+	text := fmt.Sprintf("Bounty +%d", bounty)
+	switch {
+	case honor > 0:
+		text = fmt.Sprintf("Honor +%d", honor)
+	case honor < 0:
+		text = fmt.Sprintf("Honor %d: marines protect civilians!", honor)
+	case bounty == 0:
+		text = "No honor for that kill"
+	}
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type": "notification",
+		"msg":  text,
+	})
+	return msg
+}
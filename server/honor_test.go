@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestPvPKillRewards(t *testing.T) {
+	tests := []struct {
+		name          string
+		attacker      string
+		victim        string
+		victimBounty  int
+		bounty, honor int
+	}{
+		{"pirate kills pirate", "pirate", "pirate", 10000, pvpBaseBounty + 1000, 0},
+		{"pirate kills marine", "pirate", "marine", 0, pvpBaseBounty, 0},
+		{"marine hunts wanted pirate", "marine", "pirate", 10000, 0, marineBaseHonor + 500},
+		{"marine kills clean pirate", "marine", "pirate", 0, 0, 0},
+		{"marine kills civilian", "marine", "neutral", 5000, 0, -marineHonorPenalty},
+	}
+	for _, tt := range tests {
+		a := &Player{Team: tt.attacker}
+		v := &Player{Team: tt.victim, Bounty: tt.victimBounty}
+		bounty, honor := pvpKillRewards(a, v)
+		if bounty != tt.bounty || honor != tt.honor {
+			t.Errorf("%s: got bounty %d honor %d, want %d/%d", tt.name, bounty, honor, tt.bounty, tt.honor)
+		}
+	}
+}
+
+func TestHandlePlayerDamage_HonorAndBounty(t *testing.T) {
+	hub := newHub()
+	marine := &Player{ID: "marine", RoomID: defaultRoomID, Team: "marine", Health: 100, MaxHealth: 100, X: 100, Honor: 200}
+	pirate := &Player{ID: "pirate", RoomID: defaultRoomID, Team: "pirate", Health: 100, MaxHealth: 100, X: 100, Bounty: 4000}
+	civilian := &Player{ID: "civilian", RoomID: defaultRoomID, Team: "neutral", Health: 100, MaxHealth: 100, X: 100}
+	for _, p := range []*Player{marine, pirate, civilian} {
+		p.PvP = true
+		hub.players[p.ID] = p
+	}
+
+	handlePlayerDamage(hub, marine, "pirate", 1000, nil)
+	if marine.Honor != 200+marineBaseHonor+200 || marine.Bounty != 0 {
+		t.Errorf("Marine honor %d bounty %d after hunting a pirate", marine.Honor, marine.Bounty)
+	}
+	if pirate.Bounty != 4000-pvpBountyLoss {
+		t.Errorf("Pirate bounty = %d, want %d", pirate.Bounty, 4000-pvpBountyLoss)
+	}
+
+	// Marines can't hurt each other, so the penalty is for civilians only
+	other := &Player{ID: "other", RoomID: defaultRoomID, Team: "marine", Health: 100, MaxHealth: 100, X: 100, PvP: true}
+	hub.players[other.ID] = other
+	handlePlayerDamage(hub, marine, "other", 1000, nil)
+	if other.Dead || marine.Honor != 200+marineBaseHonor+200 {
+		t.Errorf("Marine friendly fire: other dead=%v, honor %d", other.Dead, marine.Honor)
+	}
+
+	// Honor never drops below zero
+	handlePlayerDamage(hub, marine, "civilian", 1000, nil)
+	if marine.Honor != 0 {
+		t.Errorf("Marine honor = %d after killing a civilian, want 0", marine.Honor)
+	}
+
+	hub.respawnUnsafe(pirate)
+	pirate.X = 100 // Back out of the safe zone
+	handlePlayerDamage(hub, pirate, "marine", 1000, nil)
+	if pirate.Bounty != 3000+pvpBaseBounty || pirate.Honor != 0 {
+		t.Errorf("Pirate bounty %d honor %d", pirate.Bounty, pirate.Honor)
+	}
+}
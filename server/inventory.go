@@ -2,21 +2,53 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"sync"
 )
 
-// Inventory manages a player's items, providing O(1) lookups via a map while preserving the insertion order of items in a slice.
+// DefaultInventoryCapacity is the number of distinct stacks a player can hold.
+const DefaultInventoryCapacity = 50
+
+var (
+	ErrInventoryFull = errors.New("inventory is full")
+	ErrStackFull     = errors.New("stack is full")
+	ErrNotEnough     = errors.New("not enough items")
+)
+
+// ItemStack is a quantity of one item plus optional per-item metadata.
+type ItemStack struct {
+	ID         string `json:"id"`
+	Quantity   int    `json:"qty"`
+	Durability int    `json:"durability,omitempty"`
+	Mastery    int    `json:"mastery,omitempty"`
+}
+
+// maxStackSize returns how many of an item fit in a single stack. Weapons
+// are unique, fruits and materials stack.
+func maxStackSize(item string) int {
+	switch {
+	case item == "melee" || getWeaponPrice(item) > 0:
+		return 1
+	case strings.HasSuffix(item, " Fruit"):
+		return 10
+	}
+	return 999
+}
+
+// Inventory manages a player's item stacks, providing O(1) lookups via a map while preserving the insertion order of stacks in a slice.
 type Inventory struct {
-	Items []string
-	Map   map[string]struct{}
-	mu    sync.RWMutex
+	Items    []*ItemStack
+	Map      map[string]*ItemStack
+	Capacity int // Max distinct stacks; 0 means DefaultInventoryCapacity
+	mu       sync.RWMutex
 }
 
 // NewInventory creates a new initialized Inventory with optional starting items.
 func NewInventory(items ...string) *Inventory {
 	inv := &Inventory{
-		Items: make([]string, 0, len(items)),
-		Map:   make(map[string]struct{}, len(items)),
+		Items: make([]*ItemStack, 0, len(items)),
+		Map:   make(map[string]*ItemStack, len(items)),
 	}
 	for _, item := range items {
 		inv.Add(item)
@@ -24,60 +56,218 @@ func NewInventory(items ...string) *Inventory {
 	return inv
 }
 
-// Add appends an item to the inventory if it doesn't already exist.
-func (inv *Inventory) Add(item string) {
+func (inv *Inventory) capacity() int {
+	if inv.Capacity > 0 {
+		return inv.Capacity
+	}
+	return DefaultInventoryCapacity
+}
+
+// Add adds a single item to the inventory.
+func (inv *Inventory) Add(item string) error {
+	return inv.AddQuantity(item, 1)
+}
+
+// AddQuantity adds qty of an item, stacking onto an existing stack. Nothing
+// is added if the stack or the inventory would overflow.
+func (inv *Inventory) AddQuantity(item string, qty int) error {
+	if qty <= 0 {
+		return nil
+	}
 	inv.mu.Lock()
 	defer inv.mu.Unlock()
 	if inv.Map == nil {
-		inv.Map = make(map[string]struct{})
+		inv.Map = make(map[string]*ItemStack)
+	}
+	if stack, exists := inv.Map[item]; exists {
+		if stack.Quantity+qty > maxStackSize(item) {
+			return ErrStackFull
+		}
+		stack.Quantity += qty
+		return nil
+	}
+	if qty > maxStackSize(item) {
+		return ErrStackFull
 	}
-	if _, exists := inv.Map[item]; !exists {
-		inv.Items = append(inv.Items, item)
-		inv.Map[item] = struct{}{}
+	if len(inv.Items) >= inv.capacity() {
+		return ErrInventoryFull
 	}
+	stack := &ItemStack{ID: item, Quantity: qty}
+	inv.Items = append(inv.Items, stack)
+	inv.Map[item] = stack
+	return nil
+}
+
+// CanAdd reports whether qty of an item would fit.
+func (inv *Inventory) CanAdd(item string, qty int) bool {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+	if stack, exists := inv.Map[item]; exists {
+		return stack.Quantity+qty <= maxStackSize(item)
+	}
+	return qty <= maxStackSize(item) && len(inv.Items) < inv.capacity()
+}
+
+// Remove takes qty of an item out of the inventory, dropping the stack once
+// it is empty. Nothing is removed if there are fewer than qty.
+func (inv *Inventory) Remove(item string, qty int) error {
+	if inv == nil {
+		return ErrNotEnough
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	stack, exists := inv.Map[item]
+	if !exists || stack.Quantity < qty || qty <= 0 {
+		return ErrNotEnough
+	}
+	stack.Quantity -= qty
+	if stack.Quantity == 0 {
+		delete(inv.Map, item)
+		for i, s := range inv.Items {
+			if s == stack {
+				inv.Items = append(inv.Items[:i], inv.Items[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Consume uses up a single item.
+func (inv *Inventory) Consume(item string) error {
+	return inv.Remove(item, 1)
 }
 
 // Has returns true if the inventory contains the item.
 func (inv *Inventory) Has(item string) bool {
+	return inv.Count(item) > 0
+}
+
+// Count returns how many of the item the inventory holds.
+func (inv *Inventory) Count(item string) int {
 	if inv == nil {
-		return false
+		return 0
 	}
 	inv.mu.RLock()
 	defer inv.mu.RUnlock()
-	if inv.Map == nil {
-		return false
+	if stack, exists := inv.Map[item]; exists {
+		return stack.Quantity
 	}
-	_, exists := inv.Map[item]
-	return exists
+	return 0
 }
 
-// MarshalJSON serializes the inventory as a JSON array of strings.
+// MarshalJSON serializes the inventory as a JSON array of stacks.
 func (inv *Inventory) MarshalJSON() ([]byte, error) {
 	if inv == nil {
-		return json.Marshal([]string{})
+		return json.Marshal([]*ItemStack{})
 	}
 	inv.mu.RLock()
 	defer inv.mu.RUnlock()
 	// Return the ordered slice. If nil, return empty slice.
 	if inv.Items == nil {
-		return json.Marshal([]string{})
+		return json.Marshal([]*ItemStack{})
 	}
 	return json.Marshal(inv.Items)
 }
 
-// UnmarshalJSON deserializes a JSON array of strings into the inventory.
+// UnmarshalJSON deserializes a JSON array of stacks into the inventory. The
+// legacy format, a JSON array of item names, is also accepted with each name
+// counting as one item. Stacks over maxStackSize are clamped to it.
 func (inv *Inventory) UnmarshalJSON(data []byte) error {
-	var items []string
-	if err := json.Unmarshal(data, &items); err != nil {
-		return err
+	var stacks []*ItemStack
+	if err := json.Unmarshal(data, &stacks); err != nil {
+		var legacy []string
+		if legacyErr := json.Unmarshal(data, &legacy); legacyErr != nil {
+			return err
+		}
+		stacks = make([]*ItemStack, 0, len(legacy))
+		for _, item := range legacy {
+			stacks = append(stacks, &ItemStack{ID: item, Quantity: 1})
+		}
 	}
 
 	inv.mu.Lock()
 	defer inv.mu.Unlock()
-	inv.Items = items
-	inv.Map = make(map[string]struct{}, len(items))
-	for _, item := range items {
-		inv.Map[item] = struct{}{}
+	inv.Items = make([]*ItemStack, 0, len(stacks))
+	inv.Map = make(map[string]*ItemStack, len(stacks))
+	for _, s := range stacks {
+		if s == nil || s.ID == "" || s.Quantity <= 0 {
+			continue
+		}
+		if existing, ok := inv.Map[s.ID]; ok {
+			existing.Quantity = min(existing.Quantity+s.Quantity, maxStackSize(s.ID)) // Merge duplicates from older saves
+			continue
+		}
+		s.Quantity = min(s.Quantity, maxStackSize(s.ID))
+		inv.Items = append(inv.Items, s)
+		inv.Map[s.ID] = s
 	}
 	return nil
 }
+
+// Clone returns a deep copy of the inventory.
+func (inv *Inventory) Clone() *Inventory {
+	clone := &Inventory{Map: make(map[string]*ItemStack)}
+	if inv == nil {
+		return clone
+	}
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+	clone.Capacity = inv.Capacity
+	clone.Items = make([]*ItemStack, 0, len(inv.Items))
+	for _, s := range inv.Items {
+		c := *s
+		clone.Items = append(clone.Items, &c)
+		clone.Map[c.ID] = &c
+	}
+	return clone
+}
+
+// TransferTo moves qty of an item into another inventory. A stack created in
+// the destination keeps the source stack's metadata. The source is left
+// untouched if the destination can't take the items.
+func (inv *Inventory) TransferTo(dst *Inventory, item string, qty int) error {
+	if inv.Count(item) < qty {
+		return ErrNotEnough
+	}
+	if !dst.CanAdd(item, qty) {
+		if dst.Has(item) {
+			return ErrStackFull
+		}
+		return ErrInventoryFull
+	}
+
+	inv.mu.RLock()
+	meta := *inv.Map[item]
+	inv.mu.RUnlock()
+
+	if err := inv.Remove(item, qty); err != nil {
+		return err
+	}
+	isNew := !dst.Has(item)
+	if err := dst.AddQuantity(item, qty); err != nil {
+		inv.restore(meta, qty)
+		return err
+	}
+	if isNew {
+		dst.mu.Lock()
+		dst.Map[item].Durability = meta.Durability
+		dst.Map[item].Mastery = meta.Mastery
+		dst.mu.Unlock()
+	}
+	return nil
+}
+
+// restore puts back qty of a stack taken out by a failed transfer,
+// including its metadata if the stack was emptied.
+func (inv *Inventory) restore(meta ItemStack, qty int) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if stack, ok := inv.Map[meta.ID]; ok {
+		stack.Quantity += qty
+		return
+	}
+	meta.Quantity = qty
+	inv.Items = append(inv.Items, &meta)
+	inv.Map[meta.ID] = &meta
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInventory_Stacking(t *testing.T) {
+	inv := NewInventory("melee")
+
+	if err := inv.AddQuantity("Iron Scrap", 5); err != nil {
+		t.Fatalf("AddQuantity: %v", err)
+	}
+	if err := inv.AddQuantity("Iron Scrap", 3); err != nil {
+		t.Fatalf("AddQuantity: %v", err)
+	}
+	if got := inv.Count("Iron Scrap"); got != 8 {
+		t.Errorf("Count = %d, want 8", got)
+	}
+	if len(inv.Items) != 2 {
+		t.Errorf("Expected 2 stacks, got %d", len(inv.Items))
+	}
+
+	// Weapons don't stack
+	if err := inv.Add("melee"); err != ErrStackFull {
+		t.Errorf("Add duplicate weapon: got %v, want ErrStackFull", err)
+	}
+	if got := inv.Count("melee"); got != 1 {
+		t.Errorf("melee Count = %d, want 1", got)
+	}
+}
+
+func TestInventory_RemoveAndConsume(t *testing.T) {
+	inv := NewInventory()
+	inv.AddQuantity("Flame Fruit", 2)
+
+	if err := inv.Remove("Flame Fruit", 3); err != ErrNotEnough {
+		t.Errorf("Remove too many: got %v, want ErrNotEnough", err)
+	}
+	if got := inv.Count("Flame Fruit"); got != 2 {
+		t.Errorf("Failed remove changed count to %d", got)
+	}
+
+	if err := inv.Consume("Flame Fruit"); err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	if err := inv.Consume("Flame Fruit"); err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	if inv.Has("Flame Fruit") || len(inv.Items) != 0 {
+		t.Errorf("Empty stack should be removed, items: %v", inv.Items)
+	}
+	if err := inv.Consume("Flame Fruit"); err != ErrNotEnough {
+		t.Errorf("Consume from empty: got %v, want ErrNotEnough", err)
+	}
+}
+
+func TestInventory_Capacity(t *testing.T) {
+	inv := NewInventory()
+	inv.Capacity = 2
+	inv.Add("melee")
+	inv.Add("katana")
+
+	if err := inv.Add("cutlass"); err != ErrInventoryFull {
+		t.Errorf("Add past capacity: got %v, want ErrInventoryFull", err)
+	}
+	if inv.CanAdd("cutlass", 1) {
+		t.Error("CanAdd should be false when full")
+	}
+	if inv.Has("cutlass") {
+		t.Error("Item added despite full inventory")
+	}
+}
+
+func TestInventory_UnmarshalLegacy(t *testing.T) {
+	var inv Inventory
+	if err := json.Unmarshal([]byte(`["melee","Iron Scrap","Iron Scrap"]`), &inv); err != nil {
+		t.Fatalf("Unmarshal legacy: %v", err)
+	}
+	if !inv.Has("melee") || inv.Count("Iron Scrap") != 2 {
+		t.Errorf("Unexpected legacy inventory: %+v", inv.Items)
+	}
+
+	// Round trip through the new format
+	data, err := json.Marshal(&inv)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var restored Inventory
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if restored.Count("Iron Scrap") != 2 || len(restored.Items) != 2 {
+		t.Errorf("Round trip mismatch: %s", data)
+	}
+}
+
+func TestInventory_UnmarshalMetadata(t *testing.T) {
+	var inv Inventory
+	data := `[{"id":"katana","qty":1,"durability":80,"mastery":12}]`
+	if err := json.Unmarshal([]byte(data), &inv); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	stack := inv.Map["katana"]
+	if stack == nil || stack.Durability != 80 || stack.Mastery != 12 {
+		t.Errorf("Metadata not restored: %+v", stack)
+	}
+}
+
+func TestInventory_UnmarshalClampsStacks(t *testing.T) {
+	var inv Inventory
+	data := `[{"id":"katana","qty":1},{"id":"katana","qty":1},{"id":"Flame Fruit","qty":25}]`
+	if err := json.Unmarshal([]byte(data), &inv); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if inv.Count("katana") != 1 || inv.Count("Flame Fruit") != maxStackSize("Flame Fruit") {
+		t.Errorf("Stacks past the cap from %s: %+v", data, inv.Items)
+	}
+}
+
+func TestInventory_TransferTo(t *testing.T) {
+	src, dst := NewInventory(), NewInventory()
+	src.AddQuantity("Iron Scrap", 5)
+	src.Map["Iron Scrap"].Mastery = 3
+	dst.Capacity = 1
+	dst.Add("melee")
+
+	if err := src.TransferTo(dst, "Iron Scrap", 5); err != ErrInventoryFull {
+		t.Fatalf("Transfer into a full inventory: got %v", err)
+	}
+	if src.Count("Iron Scrap") != 5 {
+		t.Error("A failed transfer must leave the source untouched")
+	}
+
+	dst.Capacity = 0
+	if err := src.TransferTo(dst, "Iron Scrap", 5); err != nil {
+		t.Fatalf("TransferTo: %v", err)
+	}
+	if src.Has("Iron Scrap") || dst.Count("Iron Scrap") != 5 || dst.Map["Iron Scrap"].Mastery != 3 {
+		t.Errorf("After transfer: src %+v, dst %+v", src.Items, dst.Items)
+	}
+
+	// A rolled back transfer puts the emptied stack back with its metadata
+	meta := *dst.Map["Iron Scrap"]
+	dst.Remove("Iron Scrap", 5)
+	dst.restore(meta, 5)
+	if dst.Count("Iron Scrap") != 5 || dst.Map["Iron Scrap"].Mastery != 3 {
+		t.Errorf("restore lost the stack: %+v", dst.Map["Iron Scrap"])
+	}
+}
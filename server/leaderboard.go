@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Leaderboard periods. Daily and weekly boards rank what was gained since
+// the period started (UTC midnight, Monday for weekly); "all" ranks the
+// current value.
+const (
+	PeriodAll    = "all"
+	PeriodDaily  = "daily"
+	PeriodWeekly = "weekly"
+)
+
+const (
+	leaderboardTopN         = 10 // Changes to the top N are pushed over the websocket
+	leaderboardDefaultLimit = 10
+	leaderboardMaxLimit     = 50
+)
+
+var leaderboardPeriods = []string{PeriodAll, PeriodDaily, PeriodWeekly}
+
+// leaderboardStats are the rankable player values.
+var leaderboardStats = map[string]func(p *Player) int{
+	"bounty": func(p *Player) int { return p.Bounty },
+	"honor":  func(p *Player) int { return p.Honor },
+	"level":  func(p *Player) int { return p.Level },
+	"money":  func(p *Player) int { return p.Money },
+}
+
+// LeaderboardSample is one player's rankable values at save time.
+type LeaderboardSample struct {
+	Username string
+	Values   map[string]int // Stat -> value
+}
+
+// LeaderboardEntry is one row of a leaderboard page.
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Score    int    `json:"score"`
+}
+
+func leaderboardSample(p *Player) LeaderboardSample {
+	s := LeaderboardSample{Username: p.ID, Values: make(map[string]int, len(leaderboardStats))}
+	for stat, value := range leaderboardStats {
+		s.Values[stat] = value(p)
+	}
+	return s
+}
+
+// periodStart returns when the period containing now began, in unix
+// seconds. The all-time board never resets.
+func periodStart(period string, now time.Time) int64 {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodDaily:
+		return day.Unix()
+	case PeriodWeekly:
+		// Weeks start on Monday
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset).Unix()
+	}
+	return 0
+}
+
+// periodEnd returns when the period containing now resets, or 0 for the
+// all-time board.
+func periodEnd(period string, now time.Time) int64 {
+	start := time.Unix(periodStart(period, now), 0).UTC()
+	switch period {
+	case PeriodDaily:
+		return start.AddDate(0, 0, 1).Unix()
+	case PeriodWeekly:
+		return start.AddDate(0, 0, 7).Unix()
+	}
+	return 0
+}
+
+func validateLeaderboard(stat, period string) error {
+	if _, ok := leaderboardStats[stat]; !ok {
+		return fmt.Errorf("unknown stat %q", stat)
+	}
+	for _, p := range leaderboardPeriods {
+		if p == period {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown period %q", period)
+}
+
+// LeaderboardCache remembers the last top N of every board so only
+// changes are pushed to clients.
+type LeaderboardCache struct {
+	top map[string][]LeaderboardEntry // "stat/period" -> top N
+	mu  sync.Mutex
+}
+
+func newLeaderboardCache() *LeaderboardCache {
+	return &LeaderboardCache{top: make(map[string][]LeaderboardEntry)}
+}
+
+// updateLeaderboards records the samples and pushes every board whose top
+// N changed. Runs outside h.mutex, after the periodic save.
+func (h *Hub) updateLeaderboards(samples []LeaderboardSample, now time.Time) {
+	if err := RecordLeaderboardSamples(samples, now); err != nil {
+		log.Printf("Error updating leaderboards: %v", err)
+		return
+	}
+
+	h.leaderboards.mu.Lock()
+	defer h.leaderboards.mu.Unlock()
+	for stat := range leaderboardStats {
+		for _, period := range leaderboardPeriods {
+			top, _, err := LoadLeaderboard(stat, period, periodStart(period, now), 0, leaderboardTopN)
+			if err != nil {
+				log.Printf("Error reading leaderboard %s/%s: %v", stat, period, err)
+				continue
+			}
+			key := stat + "/" + period
+			if reflect.DeepEqual(h.leaderboards.top[key], top) {
+				continue
+			}
+			h.leaderboards.top[key] = top
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type":    "leaderboard_update",
+				"stat":    stat,
+				"period":  period,
+				"entries": top,
+			})
+			select {
+			case h.broadcast <- msg:
+			default:
+				// Dropped under load; the next change is pushed again
+			}
+		}
+	}
+}
+
+// handleLeaderboard serves GET /api/leaderboard?stat=bounty&period=weekly&page=1&limit=10.
+func (h *Hub) handleLeaderboard(c *fiber.Ctx) error {
+	stat := c.Query("stat", "bounty")
+	period := c.Query("period", PeriodAll)
+	if err := validateLeaderboard(stat, period); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	page, limit, err := leaderboardPage(c.Query("page", "1"), c.Query("limit", ""))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	now := h.clock.Now()
+	start := periodStart(period, now)
+	entries, total, err := LoadLeaderboard(stat, period, start, (page-1)*limit, limit)
+	if err != nil {
+		log.Printf("Error reading leaderboard %s/%s: %v", stat, period, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Could not load leaderboard"})
+	}
+	return c.JSON(fiber.Map{
+		"stat":        stat,
+		"period":      period,
+		"periodStart": start,
+		"resetsAt":    periodEnd(period, now),
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"entries":     entries,
+	})
+}
+
+func leaderboardPage(pageParam, limitParam string) (page, limit int, err error) {
+	page, err = strconv.Atoi(pageParam)
+	if err != nil || page < 1 {
+		return 0, 0, errors.New("page must be a positive number")
+	}
+	limit = leaderboardDefaultLimit
+	if limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > leaderboardMaxLimit {
+			return 0, 0, fmt.Errorf("limit must be 1-%d", leaderboardMaxLimit)
+		}
+	}
+	return page, limit, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupLeaderboardTest(t *testing.T) {
+	t.Helper()
+	useTestDB(t)
+}
+
+func sample(name string, bounty int) LeaderboardSample {
+	return LeaderboardSample{Username: name, Values: map[string]int{"bounty": bounty}}
+}
+
+func TestPeriodStart(t *testing.T) {
+	wed := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	if got := time.Unix(periodStart(PeriodDaily, wed), 0).UTC(); got != time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("daily start = %v", got)
+	}
+	if got := time.Unix(periodStart(PeriodWeekly, wed), 0).UTC(); got != time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("weekly start = %v, want Monday", got)
+	}
+	sun := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	if periodStart(PeriodWeekly, sun) != periodStart(PeriodWeekly, wed) {
+		t.Error("Sunday belongs to the week that started on Monday")
+	}
+	if periodStart(PeriodAll, wed) != 0 || periodEnd(PeriodAll, wed) != 0 {
+		t.Error("The all-time board never resets")
+	}
+}
+
+func TestLeaderboard_PeriodGainsAndReset(t *testing.T) {
+	setupLeaderboardTest(t)
+	day1 := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	RecordLeaderboardSamples([]LeaderboardSample{sample("alice", 1000), sample("bob", 500)}, day1)
+	RecordLeaderboardSamples([]LeaderboardSample{sample("alice", 1200), sample("bob", 900)}, day1.Add(time.Hour))
+
+	daily, _, err := LoadLeaderboard("bounty", PeriodDaily, periodStart(PeriodDaily, day1), 0, 10)
+	if err != nil {
+		t.Fatalf("LoadLeaderboard: %v", err)
+	}
+	// Daily ranks gains: bob +400, alice +200
+	if len(daily) != 2 || daily[0].Username != "bob" || daily[0].Score != 400 || daily[1].Score != 200 {
+		t.Errorf("daily = %+v", daily)
+	}
+	all, _, _ := LoadLeaderboard("bounty", PeriodAll, 0, 0, 10)
+	if all[0].Username != "alice" || all[0].Score != 1200 {
+		t.Errorf("all = %+v", all)
+	}
+
+	// The next day starts from zero
+	day2 := day1.Add(24 * time.Hour)
+	RecordLeaderboardSamples([]LeaderboardSample{sample("alice", 1500)}, day2)
+	daily, total, _ := LoadLeaderboard("bounty", PeriodDaily, periodStart(PeriodDaily, day2), 0, 10)
+	if total != 1 || daily[0].Username != "alice" || daily[0].Score != 300 {
+		t.Errorf("day 2 = %+v (total %d)", daily, total)
+	}
+}
+
+func TestLeaderboard_Pagination(t *testing.T) {
+	setupLeaderboardTest(t)
+	var samples []LeaderboardSample
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		samples = append(samples, sample(name, (i+1)*100))
+	}
+	RecordLeaderboardSamples(samples, time.Now())
+
+	page, total, _ := LoadLeaderboard("bounty", PeriodAll, 0, 2, 2)
+	if total != 5 || len(page) != 2 || page[0].Rank != 3 || page[0].Username != "c" {
+		t.Errorf("page 2 = %+v (total %d)", page, total)
+	}
+}
+
+func TestLeaderboard_PushOnTopChange(t *testing.T) {
+	setupLeaderboardTest(t)
+	hub := newHub()
+	now := time.Now()
+
+	drain := func() int {
+		n := 0
+		for len(hub.broadcast) > 0 {
+			<-hub.broadcast
+			n++
+		}
+		return n
+	}
+
+	hub.updateLeaderboards([]LeaderboardSample{leaderboardSample(&Player{ID: "alice", Bounty: 100, Level: 1})}, now)
+	if drain() == 0 {
+		t.Fatal("First update should push the boards")
+	}
+	hub.updateLeaderboards([]LeaderboardSample{leaderboardSample(&Player{ID: "alice", Bounty: 100, Level: 1})}, now)
+	if n := drain(); n != 0 {
+		t.Errorf("Unchanged boards pushed %d updates", n)
+	}
+	hub.updateLeaderboards([]LeaderboardSample{leaderboardSample(&Player{ID: "alice", Bounty: 300, Level: 1})}, now)
+	// bounty changed on all three periods
+	if n := drain(); n != 3 {
+		t.Errorf("Pushed %d updates, want 3", n)
+	}
+}
+
+func TestHandleLeaderboard(t *testing.T) {
+	setupLeaderboardTest(t)
+	hub, _ := setupClockTest()
+	RecordLeaderboardSamples([]LeaderboardSample{sample("alice", 100)}, hub.clock.Now())
+
+	app := fiber.New()
+	app.Get("/api/leaderboard", hub.handleLeaderboard)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/leaderboard?stat=bounty&period=weekly", nil))
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("status %v, err %v", resp.StatusCode, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	var got struct {
+		Total   int                `json:"total"`
+		Entries []LeaderboardEntry `json:"entries"`
+	}
+	json.Unmarshal(body, &got)
+	if got.Total != 1 || got.Entries[0].Username != "alice" {
+		t.Errorf("body = %s", body)
+	}
+
+	for _, q := range []string{"stat=luck", "period=monthly", "page=0", "limit=500"} {
+		resp, _ := app.Test(httptest.NewRequest("GET", "/api/leaderboard?"+q, nil))
+		if resp.StatusCode != 400 {
+			t.Errorf("%s: status %d, want 400", q, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	maxLevel           = 100
+	healthPerLevel     = 10
+	energyPerLevel     = 5
+	statPointsPerLevel = 3
+)
+
+// expToNextLevel is the exp needed to advance from level to level+1.
+func expToNextLevel(level int) int {
+	return int(100 * math.Pow(float64(level), 1.5))
+}
+
+// addExp adds exp, processing any level ups, and returns the number of
+// levels gained. Exp holds the progress toward the next level and is not
+// kept at the level cap.
+func (p *Player) addExp(amount int) int {
+	if amount <= 0 || p.Level >= maxLevel {
+		return 0
+	}
+	p.Exp += amount
+
+	gained := 0
+	for p.Level < maxLevel && p.Exp >= expToNextLevel(p.Level) {
+		p.Exp -= expToNextLevel(p.Level)
+		p.Level++
+		p.MaxHealth += healthPerLevel
+		p.MaxEnergy += energyPerLevel
+		p.StatPoints += statPointsPerLevel
+		gained++
+	}
+	if p.Level >= maxLevel {
+		p.Exp = 0
+	}
+	if gained > 0 {
+		// A level up is a full heal
+		p.Health = p.MaxHealth
+		p.Energy = p.MaxEnergy
+	}
+	return gained
+}
+
+// grantExp adds exp and tells the player about any level up.
+func grantExp(p *Player, amount int, c *websocket.Conn) {
+	if p.addExp(amount) == 0 || c == nil {
+		return
+	}
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":       "level_up",
+		"level":      p.Level,
+		"exp":        p.Exp,
+		"nextExp":    expToNextLevel(p.Level),
+		"maxHealth":  p.MaxHealth,
+		"maxEnergy":  p.MaxEnergy,
+		"statPoints": p.StatPoints,
+	})
+	c.WriteMessage(websocket.TextMessage, msg)
+}
+
+// weaponLevelRequirements is the level needed to buy or wield a weapon.
+var weaponLevelRequirements = map[string]int{
+	"katana":  5,
+	"cutlass": 15,
+	"pipe":    30,
+	"bazooka": 50,
+}
+
+func weaponLevelRequirement(weapon string) int {
+	if lvl, ok := weaponLevelRequirements[weapon]; ok {
+		return lvl
+	}
+	return 1
+}
+
+// Island is a named area of the map. Players below MinLevel can't enter.
+type Island struct {
+	Name     string
+	X, Z     float64
+	Radius   float64
+	MinLevel int
+}
+
+// islands mirror the zones the client registers in script.js. Earlier
+// entries win where islands overlap.
+var islands = []Island{
+	{Name: "Start Island", X: 0, Z: 0, Radius: 45, MinLevel: 1},
+	{Name: "Jungle Island", X: -50, Z: -50, Radius: 60, MinLevel: 1},
+	{Name: "Snow Island", X: 50, Z: 50, Radius: 60, MinLevel: 15},
+}
+
+// islandAt returns the island containing the point, if any.
+func islandAt(x, z float64) *Island {
+	for i := range islands {
+		is := &islands[i]
+		if distanceSq(x, z, is.X, is.Z) <= is.Radius*is.Radius {
+			return is
+		}
+	}
+	return nil
+}
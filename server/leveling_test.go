@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestAddExp_LevelUp(t *testing.T) {
+	p := &Player{Level: 1, Health: 50, MaxHealth: 100, MaxEnergy: 100}
+
+	// Enough for level 2 plus a bit
+	gained := p.addExp(expToNextLevel(1) + 10)
+	if gained != 1 || p.Level != 2 {
+		t.Fatalf("gained %d, level %d; want 1, 2", gained, p.Level)
+	}
+	if p.Exp != 10 {
+		t.Errorf("Leftover exp = %d, want 10", p.Exp)
+	}
+	if p.MaxHealth != 100+healthPerLevel || p.MaxEnergy != 100+energyPerLevel {
+		t.Errorf("MaxHealth/MaxEnergy = %d/%d", p.MaxHealth, p.MaxEnergy)
+	}
+	if p.Health != p.MaxHealth {
+		t.Error("Level up should heal to full")
+	}
+	if p.StatPoints != statPointsPerLevel {
+		t.Errorf("StatPoints = %d, want %d", p.StatPoints, statPointsPerLevel)
+	}
+}
+
+func TestAddExp_MultipleLevels(t *testing.T) {
+	p := &Player{Level: 1, MaxHealth: 100}
+	need := expToNextLevel(1) + expToNextLevel(2) + expToNextLevel(3)
+
+	if gained := p.addExp(need); gained != 3 || p.Level != 4 {
+		t.Errorf("gained %d, level %d; want 3, 4", gained, p.Level)
+	}
+}
+
+func TestAddExp_MaxLevel(t *testing.T) {
+	p := &Player{Level: maxLevel - 1}
+	p.addExp(expToNextLevel(maxLevel-1) * 10)
+
+	if p.Level != maxLevel {
+		t.Errorf("Level = %d, want cap %d", p.Level, maxLevel)
+	}
+	if p.Exp != 0 {
+		t.Errorf("Exp at cap = %d, want 0", p.Exp)
+	}
+	if gained := p.addExp(1000); gained != 0 {
+		t.Error("No levels past the cap")
+	}
+}
+
+func TestIslandAt(t *testing.T) {
+	if is := islandAt(0, 0); is == nil || is.Name != "Start Island" {
+		t.Errorf("islandAt(0,0) = %v", is)
+	}
+	if is := islandAt(55, 55); is == nil || is.Name != "Snow Island" {
+		t.Errorf("islandAt(55,55) = %v", is)
+	}
+	if is := islandAt(200, -200); is != nil {
+		t.Errorf("Open ocean should have no island, got %s", is.Name)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+const (
+	lootOwnerWindow = 15000 // ms a drop is reserved for its owner
+	lootLifetime    = 60000 // ms before an unclaimed drop despawns
+	lootPickupRange = 6.0
+)
+
+// Loot kinds
+const (
+	LootMoney    = "money"
+	LootWeapon   = "weapon"
+	LootFruit    = "fruit"
+	LootMaterial = "material"
+)
+
+// LootEntry is one weighted outcome of a loot roll.
+type LootEntry struct {
+	Item   string `json:"item"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight"`
+	Min    int    `json:"min"`  // Quantity range; both default to 1
+	Max    int    `json:"max"`  //
+	Rare   bool   `json:"rare"` // Weight is scaled by the player's luck
+}
+
+// LootTable describes what a mob type drops on death.
+type LootTable struct {
+	Rolls      int         `json:"rolls"`      // Number of rolls per kill
+	DropChance float64     `json:"dropChance"` // Chance (0-1] that a roll drops anything
+	Entries    []LootEntry `json:"entries"`
+}
+
+func (lt *LootTable) validate() error {
+	if lt.Rolls <= 0 {
+		return errors.New("loot rolls must be positive")
+	}
+	if lt.DropChance <= 0 || lt.DropChance > 1 {
+		return errors.New("loot dropChance must be within (0, 1]")
+	}
+	if len(lt.Entries) == 0 {
+		return errors.New("loot table needs at least one entry")
+	}
+	for i := range lt.Entries {
+		e := &lt.Entries[i]
+		switch e.Kind {
+		case LootMoney, LootWeapon, LootFruit, LootMaterial:
+		default:
+			return fmt.Errorf("loot entry %q: unknown kind %q", e.Item, e.Kind)
+		}
+		if e.Item == "" || e.Weight <= 0 {
+			return fmt.Errorf("loot entry %d: item and a positive weight are required", i)
+		}
+		if e.Min == 0 && e.Max == 0 {
+			e.Min, e.Max = 1, 1
+		}
+		if e.Min < 1 || e.Max < e.Min {
+			return fmt.Errorf("loot entry %q: invalid quantity range", e.Item)
+		}
+	}
+	return nil
+}
+
+// LootItem is a single rolled reward.
+type LootItem struct {
+	Item     string `json:"item"`
+	Kind     string `json:"kind"`
+	Quantity int    `json:"quantity"`
+}
+
+// Roll rolls the table for a player with the given luck (1.0 = normal).
+func (lt *LootTable) Roll(rng *rand.Rand, luck float64) []LootItem {
+	if luck < 1 {
+		luck = 1
+	}
+
+	weights := make([]float64, len(lt.Entries))
+	total := 0.0
+	for i, e := range lt.Entries {
+		weights[i] = float64(e.Weight)
+		if e.Rare {
+			weights[i] *= luck
+		}
+		total += weights[i]
+	}
+
+	items := make([]LootItem, 0, lt.Rolls)
+	for r := 0; r < lt.Rolls; r++ {
+		if rng.Float64() >= lt.DropChance {
+			continue
+		}
+		pick := rng.Float64() * total
+		for i, e := range lt.Entries {
+			pick -= weights[i]
+			if pick < 0 {
+				items = append(items, LootItem{
+					Item:     e.Item,
+					Kind:     e.Kind,
+					Quantity: e.Min + rng.Intn(e.Max-e.Min+1),
+				})
+				break
+			}
+		}
+	}
+	return items
+}
+
+// GroundDrop is loot lying in the world. Only its owner can see and pick it
+// up until PublicAt, after which it is offered to the whole room.
+type GroundDrop struct {
+	ID       string  `json:"id"`
+	Item     string  `json:"item"`
+	Kind     string  `json:"kind"`
+	Quantity int     `json:"quantity"`
+	X        float64 `json:"x"`
+	Z        float64 `json:"z"`
+	OwnerID  string  `json:"ownerId"`
+	PublicAt int64   `json:"publicAt"`
+
+	expiresAt int64
+	public    bool // Announced to the room
+}
+
+// luckUnsafe returns a player's luck including the current event.
+// Caller MUST hold h.mutex.
+func (h *Hub) luckUnsafe(p *Player) float64 {
+	luck := p.Luck
+	if h.CurrentEvent == "Double Luck" {
+		luck *= 2.0
+	}
+	return luck
+}
+
+// dropLootLocked rolls the mob's loot table for a player and places the
+// results on the ground at the mob's position.
+// Caller MUST hold mm.mutex and mm.hub.mutex.
+func (mm *MobManager) dropLootLocked(mob *Mob, owner *Player, luck float64, now int64) []*GroundDrop {
+	def, ok := mobRegistry.Get(mob.Type)
+	if !ok || def.Loot == nil {
+		return nil
+	}
+
+	var drops []*GroundDrop
+	for _, item := range def.Loot.Roll(mm.rng, luck) {
+		mm.nextID++
+		drop := &GroundDrop{
+			ID:        fmt.Sprintf("%s_drop_%d", mm.RoomID, mm.nextID),
+			Item:      item.Item,
+			Kind:      item.Kind,
+			Quantity:  item.Quantity,
+			X:         mob.X + (mm.rng.Float64()-0.5)*2,
+			Z:         mob.Z + (mm.rng.Float64()-0.5)*2,
+			OwnerID:   owner.ID,
+			PublicAt:  now + lootOwnerWindow,
+			expiresAt: now + lootLifetime,
+		}
+		mm.Drops[drop.ID] = drop
+		drops = append(drops, drop)
+	}
+	return drops
+}
+
+// updateDropsLocked opens expired owner windows to the room and removes
+// drops nobody picked up.
+// Caller MUST hold mm.mutex.
+func (mm *MobManager) updateDropsLocked(now int64) {
+	for id, drop := range mm.Drops {
+		if now >= drop.expiresAt {
+			delete(mm.Drops, id)
+			mm.queueLootRemoved(id)
+			continue
+		}
+		if !drop.public && now >= drop.PublicAt {
+			drop.public = true
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type":  "loot_drop",
+				"drops": []*GroundDrop{drop},
+			})
+			mm.queue(msg)
+		}
+	}
+}
+
+func (mm *MobManager) queueLootRemoved(dropID string) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type": "loot_removed",
+		"id":   dropID,
+	})
+	mm.queue(msg)
+}
+
+// PickupDrop removes a drop the player is allowed to take and returns it.
+func (mm *MobManager) PickupDrop(p *Player, dropID string, now int64) (*GroundDrop, error) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	drop, ok := mm.Drops[dropID]
+	if !ok {
+		return nil, errors.New("drop not found")
+	}
+	if now < drop.PublicAt && drop.OwnerID != p.ID {
+		return nil, errors.New("drop belongs to another player")
+	}
+	if distanceSq(p.X, p.Z, drop.X, drop.Z) > lootPickupRange*lootPickupRange {
+		return nil, errors.New("too far away")
+	}
+	if drop.Kind != LootMoney && !p.Inventory.CanAdd(drop.Item, drop.Quantity) {
+		return nil, ErrInventoryFull
+	}
+
+	delete(mm.Drops, dropID)
+	mm.queueLootRemoved(dropID)
+	return drop, nil
+}
+
+// grantLoot adds a picked up drop to the player.
+func grantLoot(p *Player, drop *GroundDrop) error {
+	if drop.Kind == LootMoney {
+		p.Money += drop.Quantity
+		return nil
+	}
+	return p.Inventory.AddQuantity(drop.Item, drop.Quantity)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func testLootTable() *LootTable {
+	return &LootTable{
+		Rolls:      1,
+		DropChance: 1.0,
+		Entries: []LootEntry{
+			{Item: "money", Kind: LootMoney, Weight: 90, Min: 10, Max: 20},
+			{Item: "Dragon Fruit", Kind: LootFruit, Weight: 10, Rare: true},
+		},
+	}
+}
+
+func TestLootTable_Validate(t *testing.T) {
+	lt := testLootTable()
+	if err := lt.validate(); err != nil {
+		t.Fatalf("valid table rejected: %v", err)
+	}
+	if lt.Entries[1].Min != 1 || lt.Entries[1].Max != 1 {
+		t.Errorf("quantity should default to 1, got %d-%d", lt.Entries[1].Min, lt.Entries[1].Max)
+	}
+
+	bad := testLootTable()
+	bad.Entries[0].Kind = "pet"
+	if err := bad.validate(); err == nil {
+		t.Error("expected error for unknown loot kind")
+	}
+}
+
+func TestLootTable_RollQuantities(t *testing.T) {
+	lt := testLootTable()
+	lt.validate()
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		items := lt.Roll(rng, 1.0)
+		if len(items) != 1 {
+			t.Fatalf("dropChance 1 should always drop, got %d items", len(items))
+		}
+		if items[0].Kind == LootMoney && (items[0].Quantity < 10 || items[0].Quantity > 20) {
+			t.Fatalf("quantity %d outside range", items[0].Quantity)
+		}
+	}
+}
+
+func TestLootTable_LuckFavorsRareEntries(t *testing.T) {
+	lt := testLootTable()
+	lt.validate()
+	rng := rand.New(rand.NewSource(1))
+
+	countRare := func(luck float64) int {
+		n := 0
+		for i := 0; i < 5000; i++ {
+			for _, item := range lt.Roll(rng, luck) {
+				if item.Kind == LootFruit {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	// 10% base vs ~31% at 4x luck
+	if normal, lucky := countRare(1.0), countRare(4.0); lucky <= normal*2 {
+		t.Errorf("luck should raise rare drops: normal=%d lucky=%d", normal, lucky)
+	}
+}
+
+func TestPickupDrop_OwnerWindow(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	owner := &Player{ID: "owner", X: 100, Z: 100, Inventory: NewInventory()}
+	other := &Player{ID: "other", X: 100, Z: 100, Inventory: NewInventory()}
+
+	now := int64(1000000)
+	mm.Drops["d1"] = &GroundDrop{ID: "d1", Item: "Gorilla Fur", Kind: LootMaterial, Quantity: 1, X: 101, Z: 100,
+		OwnerID: owner.ID, PublicAt: now + lootOwnerWindow, expiresAt: now + lootLifetime}
+
+	if _, err := mm.PickupDrop(other, "d1", now); err == nil {
+		t.Fatal("other players must not take a reserved drop")
+	}
+	if _, err := mm.PickupDrop(other, "d1", now+lootOwnerWindow); err != nil {
+		t.Fatalf("drop should be public after the owner window: %v", err)
+	}
+	if _, err := mm.PickupDrop(owner, "d1", now+lootOwnerWindow); err == nil {
+		t.Error("a drop can only be picked up once")
+	}
+}
+
+func TestPickupDrop_Range(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	p := &Player{ID: "owner", X: 0, Z: 0, Inventory: NewInventory()}
+	mm.Drops["d1"] = &GroundDrop{ID: "d1", Item: "money", Kind: LootMoney, Quantity: 50, X: 50, Z: 0, OwnerID: p.ID}
+
+	if _, err := mm.PickupDrop(p, "d1", 0); err == nil {
+		t.Error("drops out of range must be rejected")
+	}
+}
+
+func TestMobManager_Update_DropLifecycle(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	mm.Drops["d1"] = &GroundDrop{ID: "d1", PublicAt: 0, expiresAt: 1}
+
+	mm.updateDropsLocked(0)
+	if types := drainTypes(mm); len(types) != 1 || types[0] != "loot_drop" {
+		t.Fatalf("expected drop to be announced to the room, got %v", types)
+	}
+
+	mm.updateDropsLocked(1)
+	if _, ok := mm.Drops["d1"]; ok {
+		t.Error("expired drop should be removed")
+	}
+	if types := drainTypes(mm); len(types) != 1 || types[0] != "loot_removed" {
+		t.Errorf("expected loot_removed, got %v", types)
+	}
+}
+
+func TestLootTable_SameSeedSameDrops(t *testing.T) {
+	lt := testLootTable()
+	lt.validate()
+	roll := func(seed int64) [][]LootItem {
+		rng := rand.New(rand.NewSource(seed))
+		var out [][]LootItem
+		for i := 0; i < 50; i++ {
+			out = append(out, lt.Roll(rng, 1.0))
+		}
+		return out
+	}
+	if !reflect.DeepEqual(roll(42), roll(42)) {
+		t.Error("The same seed should replay the same drops")
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"maps"
+	mrand "math/rand"
 	"os"
 	"regexp"
+	"slices"
 	"sync"
 	"time"
 
@@ -30,18 +33,39 @@ type Player struct {
 	Energy    int     `json:"energy"`
 	MaxEnergy int     `json:"maxEnergy"` // Added for completeness if needed logic
 
+	energyRegen   float64           // Fractional energy carried between ticks
+	passiveTimers map[string]*Timer // Passive kind -> next application
+	lastMoveAt    int64             // ms, for capping moves while slowed
+	combatLogged  bool              // Disconnected while combat tagged
+
 	// Gameplay Stats
-	Team         string     `json:"team"`   // "marine" or "pirate"
-	Weapon       string     `json:"weapon"` // "katana", etc
-	Level        int        `json:"level"`
-	Exp          int        `json:"exp"`
-	Money        int        `json:"money"`
-	Bounty       int        `json:"bounty"`
-	Inventory    *Inventory `json:"inventory"`
-	CurrentFruit string     `json:"currentFruit"`
-	Luck         float64    `json:"luck"`
-	ActiveQuest  *Quest     `json:"activeQuest"`
-	LastAttack   int64      `json:"-"`
+	Team              string           `json:"team"`   // "marine" or "pirate"
+	Weapon            string           `json:"weapon"` // "katana", etc
+	Level             int              `json:"level"`
+	Exp               int              `json:"exp"`        // Progress toward the next level
+	StatPoints        int              `json:"statPoints"` // Unspent
+	Stats             PlayerStats      `json:"stats"`
+	Money             int              `json:"money"`
+	Bounty            int              `json:"bounty"`
+	Honor             int              `json:"honor"`                  // Marines only, see pvpKillRewards
+	PvP               bool             `json:"pvp"`                    // Opted in to PvP
+	PvPToggledAt      int64            `json:"pvpToggledAt,omitempty"` // ms, for the switch cooldown
+	CombatTagEnd      int64            `json:"combatTagEnd,omitempty"` // ms, see inCombat
+	Dead              bool             `json:"dead"`
+	RespawnAt         int64            `json:"respawnAt,omitempty"`    // ms, while dead
+	RespawnPoint      string           `json:"respawnPoint,omitempty"` // RespawnSpawn or RespawnIsland
+	SpawnIsland       string           `json:"spawnIsland,omitempty"`  // Set with set_spawn
+	LastIsland        string           `json:"lastIsland,omitempty"`   // Last island stood on
+	Effects           StatusEffects    `json:"effects"`
+	Inventory         *Inventory       `json:"inventory"`
+	CurrentFruit      string           `json:"currentFruit"`
+	Crew              string           `json:"crew"` // Crew name; the crew tables are authoritative
+	Luck              float64          `json:"luck"`
+	Quests            []*Quest         `json:"quests"`                    // Quest log, in accept order
+	ActiveQuest       *Quest           `json:"activeQuest,omitempty"`     // Legacy single quest, see migrateLegacyQuest
+	CompletedQuests   map[string]int64 `json:"completedQuests,omitempty"` // Quest id -> last completed (ms)
+	Cooldowns         map[string]int64 `json:"-"`                         // Ability/weapon -> ready at (ms)
+	GlobalCooldownEnd int64            `json:"-"`
 
 	MsgChan      chan []byte `json:"-"`
 	EquippedItem string      `json:"equipped"` // Redundant with Weapon but used in struct?
@@ -49,13 +73,40 @@ type Player struct {
 	HakiActive   bool        `json:"hakiActive"`
 }
 
+// applyDefaults fills in fields missing from older saves.
+func (p *Player) applyDefaults() {
+	if p.Level < 1 {
+		p.Level = 1
+	}
+	if p.MaxEnergy <= 0 {
+		p.MaxEnergy = baseMaxEnergy
+		p.Energy = baseMaxEnergy
+	}
+	p.migrateLegacyQuest()
+}
+
+// Quest is a player's progress on a quest from questCatalog.
 type Quest struct {
+	ID          string `json:"id"`
 	Name        string `json:"name"`        // "Defeat Gorillas"
+	Type        string `json:"type"`        // QuestKill, QuestCollect, ...
 	Target      string `json:"target"`      // "Gorilla"
 	TargetCount int    `json:"targetCount"` // 5
 	Current     int    `json:"current"`     // 0
 	RewardExp   int    `json:"rewardExp"`
 	RewardMoney int    `json:"rewardMoney"`
+	MinLevel    int    `json:"minLevel"`
+	NPC         string `json:"npc,omitempty"` // Giver that collect quests are handed in to
+
+	// Destination of reach and escort quests
+	X      float64 `json:"x,omitempty"`
+	Z      float64 `json:"z,omitempty"`
+	Radius float64 `json:"radius,omitempty"`
+
+	// Escortee position and deadline (ms)
+	EscortX   float64 `json:"escortX,omitempty"`
+	EscortZ   float64 `json:"escortZ,omitempty"`
+	ExpiresAt int64   `json:"expiresAt,omitempty"`
 }
 
 // Hub maintains the set of active clients and broadcasts messages to the clients.
@@ -68,8 +119,17 @@ type Hub struct {
 	broadcast    chan []byte
 	mutex        sync.Mutex
 	CurrentEvent string
-	tokens       map[string]string // Token -> Username
-	MobManager   *MobManager
+	tokens       map[string]string        // Token -> Username
+	rooms        map[string]*Room         // RoomID -> Room, created on first join
+	trades       map[string]*TradeSession // PlayerID -> open trade (both sides)
+	crews        map[string]*Crew         // Name -> crew, loaded at startup
+	crewInvites  map[string]*CrewInvite   // PlayerID -> open invite
+	parties      map[string]*Party        // PlayerID -> party (every member)
+	partyInvites map[string]*PartyInvite  // PlayerID -> open invite
+	leaderboards *LeaderboardCache
+	clock        Clock       // Game time; tests swap in a ManualClock
+	rng          *mrand.Rand // Hub-wide rolls (events); rooms own the rest. Caller MUST hold h.mutex.
+	seed         int64       // Rooms derive their RNG from it
 }
 
 // clientsUnsafe searches for a connection by playerID.
@@ -84,7 +144,7 @@ func (h *Hub) clientsUnsafe(playerID string) (*websocket.Conn, bool) {
 }
 
 func newHub() *Hub {
-	return &Hub{
+	h := &Hub{
 		clients:      make(map[*websocket.Conn]string),
 		clientConns:  make([]*websocket.Conn, 0),
 		players:      make(map[string]*Player),
@@ -93,7 +153,17 @@ func newHub() *Hub {
 		broadcast:    make(chan []byte, 256),
 		CurrentEvent: "None",
 		tokens:       make(map[string]string),
+		rooms:        make(map[string]*Room),
+		trades:       make(map[string]*TradeSession),
+		crews:        make(map[string]*Crew),
+		crewInvites:  make(map[string]*CrewInvite),
+		parties:      make(map[string]*Party),
+		partyInvites: make(map[string]*PartyInvite),
+		leaderboards: newLeaderboardCache(),
+		clock:        realClock{},
 	}
+	h.reseed(time.Now().UnixNano())
+	return h
 }
 
 func (h *Hub) run() {
@@ -106,19 +176,21 @@ func (h *Hub) run() {
 	saveTicker := time.NewTicker(10 * time.Second)     // Persistence
 	eventTicker := time.NewTicker(60 * time.Second)    // Change event every minute
 	mobTicker := time.NewTicker(50 * time.Millisecond) // 20 TPS for AI
+	partyTicker := time.NewTicker(250 * time.Millisecond)
+	combatTicker := time.NewTicker(time.Second) // Releases players who disconnected in combat
 
 	defer gameTicker.Stop()
 	defer incomeTicker.Stop()
 	defer saveTicker.Stop()
 	defer eventTicker.Stop()
 	defer mobTicker.Stop()
+	defer partyTicker.Stop()
+	defer combatTicker.Stop()
 
-	// MOB MANAGER INIT
-	h.MobManager = NewMobManager(h)
-	// Spawn Gorillas
-	for i := 0; i < 5; i++ {
-		h.MobManager.SpawnMob(generateID(), "Gorilla", -50+float64(i*5), -50)
-	}
+	// Default room is always simulated; private rooms are created on join
+	h.mutex.Lock()
+	h.roomUnsafe(defaultRoomID)
+	h.mutex.Unlock()
 
 	for {
 		select {
@@ -126,7 +198,7 @@ func (h *Hub) run() {
 			// ... (keep existing register logic) ...
 			h.mutex.Lock()
 			username := ""
-			roomID := "public_1"
+			roomID := defaultRoomID
 
 			if uVal := conn.Locals("username"); uVal != nil {
 				if uname, ok := uVal.(string); ok {
@@ -164,7 +236,9 @@ func (h *Hub) run() {
 						ID:     username,
 						RoomID: roomID,
 						X:      0, Y: 3.5, Z: 0,
+						Level:  1,
 						Health: 100, MaxHealth: 100,
+						Energy: baseMaxEnergy, MaxEnergy: baseMaxEnergy,
 						Team:  "neutral",
 						Money: 5000, Inventory: NewInventory("melee"), Luck: 1.0,
 					}
@@ -172,10 +246,16 @@ func (h *Hub) run() {
 					p.RoomID = roomID // Update room
 					h.players[username] = p
 				}
+				// The saved field may be stale if they were kicked while offline
+				h.players[username].Crew = ""
+				if cr := h.crewOfUnsafe(username); cr != nil {
+					h.players[username].Crew = cr.Name
+				}
 			} else {
 				// Already in memory (maybe reconnected), just update room
 				h.players[username].RoomID = roomID
 			}
+			h.roomUnsafe(roomID)
 
 			h.mutex.Unlock()
 			log.Printf("Player connected: %s", username)
@@ -193,19 +273,9 @@ func (h *Hub) run() {
 
 		case conn := <-h.unregister:
 			h.mutex.Lock()
-			if id, ok := h.clients[conn]; ok {
-				delete(h.clients, conn)
-
-				// Rebuild copy-on-write slice for zero-allocation broadcasts
-				h.clientConns = make([]*websocket.Conn, 0, len(h.clients))
-				for c := range h.clients {
-					h.clientConns = append(h.clientConns, c)
-				}
-
-				delete(h.players, id) // Ideally persist before deleting, but we save regularly
-				log.Printf("Player disconnected: %s", id)
-			}
+			departed, samples := h.disconnectUnsafe(conn, h.now())
 			h.mutex.Unlock()
+			h.saveDeparted(departed, samples)
 
 		case msg := <-h.broadcast:
 			// ⚡ Bolt Optimization: Use pre-allocated slice to avoid O(N) map copy and allocations during every broadcast tick
@@ -221,16 +291,9 @@ func (h *Hub) run() {
 					log.Printf("Broadcast failed: %v", err)
 					h.mutex.Lock()
 					conn.Close()
-					if id, ok := h.clients[conn]; ok {
-						delete(h.clients, conn)
-						// Rebuild copy-on-write slice
-						h.clientConns = make([]*websocket.Conn, 0, len(h.clients))
-						for c := range h.clients {
-							h.clientConns = append(h.clientConns, c)
-						}
-						delete(h.players, id)
-					}
+					departed, samples := h.disconnectUnsafe(conn, h.now())
 					h.mutex.Unlock()
+					h.saveDeparted(departed, samples)
 				}
 			}
 
@@ -247,7 +310,7 @@ func (h *Hub) run() {
 
 		case <-eventTicker.C:
 			h.mutex.Lock()
-			r := time.Now().UnixNano() % 100
+			r := h.rng.Intn(100)
 			if r < 33 {
 				h.CurrentEvent = "None"
 			} else if r < 66 {
@@ -271,47 +334,18 @@ func (h *Hub) run() {
 			go h.saveData()
 
 		case <-mobTicker.C:
-			h.MobManager.Update(0.05) // 50ms = 0.05s
-
-			// Phoenix Regen (Feature 16)
-			// Check every tick? Or slower? 50ms is too fast for massive regen.
-			// Let's do it every 20 ticks -> 1 sec roughly, or just check time.
-			// Or just small amount per tick.
-			// 100 HP max. 1 HP per second?
-			// 50ms ticks. 1/20 chance.
+			h.tick(0.05) // 50ms = 0.05s
+
+		case <-partyTicker.C:
 			h.mutex.Lock()
-			if time.Now().UnixNano()%20 == 0 { // Simple throttle
-				for _, p := range h.players {
-					if p.Weapon == "Phoenix Fruit" && p.Health < p.MaxHealth {
-						p.Health += 5
-						if p.Health > p.MaxHealth {
-							p.Health = p.MaxHealth
-						}
-					}
-				}
-			}
+			h.sendPartyFramesUnsafe()
 			h.mutex.Unlock()
 
-			// Broadcast Mob State
-			h.MobManager.mutex.Lock()
-			mobData := make(map[string]*Mob)
-			for k, v := range h.MobManager.Mobs {
-				if v.State != StateDead {
-					mobData[k] = v
-				}
-			}
-			h.MobManager.mutex.Unlock()
-
-			mobMsg, _ := json.Marshal(map[string]interface{}{
-				"type": "mob_update",
-				"mobs": mobData,
-			})
-
+		case <-combatTicker.C:
 			h.mutex.Lock()
-			for conn := range h.clients {
-				conn.WriteMessage(websocket.TextMessage, mobMsg)
-			}
+			departed, samples := h.releaseCombatLoggersUnsafe(h.now())
 			h.mutex.Unlock()
+			h.saveDeparted(departed, samples)
 
 		case <-gameTicker.C:
 			// Broadcast Game State PER ROOM
@@ -358,10 +392,39 @@ func (h *Hub) run() {
 	}
 }
 
+// tick advances the world by one simulation step of deltaTime seconds,
+// reading the current time from h.clock.
+func (h *Hub) tick(deltaTime float64) {
+	// Each room runs its own AI tick; empty rooms are not simulated
+	h.mutex.Lock()
+	rooms := h.activeRoomsUnsafe()
+	h.mutex.Unlock()
+	for _, room := range rooms {
+		room.MobManager.Update(deltaTime)
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	now := h.now()
+	h.applyRegenUnsafe(now)
+	h.updatePlayerEffectsUnsafe(now)
+	for _, p := range h.players {
+		p.regenEnergy(deltaTime)
+	}
+	h.updateEscortsUnsafe(deltaTime, now)
+	h.respawnPlayersUnsafe(now)
+
+	// Broadcast Mob State (per room)
+	for _, room := range rooms {
+		h.broadcastRoomMobsUnsafe(room)
+	}
+}
+
 func (h *Hub) saveData() {
 	h.mutex.Lock()
 	// Snapshot player data to minimize lock time
 	playerData := make(map[string]string, len(h.players))
+	samples := make([]LeaderboardSample, 0, len(h.players))
 	for id, p := range h.players {
 		data, err := json.Marshal(p)
 		if err != nil {
@@ -369,12 +432,608 @@ func (h *Hub) saveData() {
 			continue
 		}
 		playerData[id] = string(data)
+		samples = append(samples, leaderboardSample(p))
 	}
 	h.mutex.Unlock()
 
 	// Perform batch save outside of the lock
 	if err := SaveUsersBatch(playerData); err != nil {
 		log.Printf("Error in batch save: %v", err)
+		return
+	}
+	h.updateLeaderboards(samples, h.clock.Now())
+}
+
+// handleInput applies a single client message to the sending player.
+// Caller MUST hold h.mutex.
+func (h *Hub) handleInput(c *websocket.Conn, player *Player, input InputMessage) {
+	if player.Dead && blockedWhileDead[input.Type] {
+		return
+	}
+	if blockedWhileDisabled[input.Type] && player.Effects.Disabled(h.now()) {
+		return
+	}
+	switch input.Type {
+	case "move":
+		// Islands above the player's level are closed to them
+		if is := islandAt(input.X, input.Z); is != nil && player.Level < is.MinLevel && islandAt(player.X, player.Z) != is {
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type": "teleport",
+				"x":    player.X,
+				"y":    player.Y,
+				"z":    player.Z,
+				"msg":  fmt.Sprintf("%s requires level %d", is.Name, is.MinLevel),
+			})
+			c.WriteMessage(websocket.TextMessage, msg)
+			return
+		}
+		if now := h.now(); player.inCombat(now) && enteringSafeZone(player, input.X, input.Z) {
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type": "teleport",
+				"x":    player.X,
+				"y":    player.Y,
+				"z":    player.Z,
+				"msg":  "You can't enter a safe zone while in combat!",
+			})
+			h.sendToPlayerUnsafe(player.ID, msg)
+			return
+		}
+		now := h.now()
+		x, z, clamped := player.clampSlowedMove(input.X, input.Z, now)
+		player.lastMoveAt = now
+		player.X = x
+		player.Z = z
+		if clamped {
+			// Slowed: pull the client back to where it could have got to
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type": "teleport",
+				"x":    player.X,
+				"y":    player.Y,
+				"z":    player.Z,
+			})
+			h.sendToPlayerUnsafe(player.ID, msg)
+		}
+		if is := islandAt(player.X, player.Z); is != nil {
+			player.LastIsland = is.Name
+		}
+		checkReachQuest(player, now, c)
+	case "join_team":
+		player.Team = input.Team
+	case "set_pvp":
+		// Input: Item = "on" or "off"
+		reason := ""
+		if err := player.setPvP(input.Item == "on", h.now()); err != nil {
+			reason = err.Error()
+		}
+		h.sendToPlayerUnsafe(player.ID, pvpStatusMsg(player, reason))
+	case "set_respawn_point":
+		// Input: Item = RespawnSpawn or RespawnIsland
+		if err := player.setRespawnPoint(input.Item); err != nil {
+			return
+		}
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type":         "respawn_point",
+			"respawnPoint": player.respawnPoint(),
+			"points":       player.respawnOptions(),
+		})
+		h.sendToPlayerUnsafe(player.ID, msg)
+	case "set_spawn":
+		text := ""
+		if is, err := player.setSpawn(); err != nil {
+			text = "Can't set spawn: " + err.Error()
+		} else {
+			text = "Spawn set to " + is.Name
+		}
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type": "notification",
+			"msg":  text,
+		})
+		h.sendToPlayerUnsafe(player.ID, msg)
+	case "set_weapon":
+		// Verify ownership
+		// Fruits can only be wielded once eaten
+		if isFruit(input.Weapon) {
+			if input.Weapon == player.CurrentFruit {
+				player.Weapon = input.Weapon
+			}
+		} else if (player.Inventory.Has(input.Weapon) || input.Weapon == "melee") &&
+			player.Level >= weaponLevelRequirement(input.Weapon) {
+			player.Weapon = input.Weapon
+		}
+	case "allocate_stat":
+		// Input: Item = Stat name, Amount = Points (default 1)
+		points := input.Amount
+		if points == 0 {
+			points = 1
+		}
+		if err := player.allocateStat(input.Item, points); err != nil {
+			return
+		}
+		updateMsg, _ := json.Marshal(map[string]interface{}{
+			"type":       "update_stats",
+			"money":      player.Money,
+			"inventory":  player.Inventory,
+			"stats":      player.Stats,
+			"statPoints": player.StatPoints,
+		})
+		c.WriteMessage(websocket.TextMessage, updateMsg)
+	case "eat_fruit":
+		// Input: Item = Fruit name. Eating replaces the current fruit.
+		if !isFruit(input.Item) {
+			return
+		}
+		if err := player.Inventory.Consume(input.Item); err != nil {
+			return
+		}
+		player.CurrentFruit = input.Item
+		player.Weapon = input.Item
+
+		updateMsg, _ := json.Marshal(map[string]interface{}{
+			"type":         "update_stats",
+			"money":        player.Money,
+			"inventory":    player.Inventory,
+			"currentFruit": player.CurrentFruit,
+		})
+		c.WriteMessage(websocket.TextMessage, updateMsg)
+	case "roll_fruit":
+		if player.Money >= 1000 {
+			player.Money -= 1000
+
+			fruit := rollRandomFruit(h.mobsUnsafe(player).rng, h.luckUnsafe(player))
+			if err := player.Inventory.Add(fruit); err != nil {
+				player.Money += 1000 // Refund, no room for the fruit
+				return
+			}
+
+			// Send Update
+			updateMsg, _ := json.Marshal(map[string]interface{}{
+				"type":      "update_stats",
+				"money":     player.Money,
+				"inventory": player.Inventory,
+				"new_item":  fruit,
+			})
+			c.WriteMessage(websocket.TextMessage, updateMsg)
+		}
+	case "buy_weapon":
+		price := getWeaponPrice(input.Item)
+		if player.Level < weaponLevelRequirement(input.Item) {
+			return
+		}
+		if price > 0 && player.Money >= price {
+			if !player.Inventory.Has(input.Item) {
+				if err := player.Inventory.Add(input.Item); err != nil {
+					return
+				}
+				player.Money -= price
+
+				// Send Update
+				updateMsg, _ := json.Marshal(map[string]interface{}{
+					"type":      "update_stats",
+					"money":     player.Money,
+					"inventory": player.Inventory,
+					"new_item":  input.Item,
+				})
+				c.WriteMessage(websocket.TextMessage, updateMsg)
+			}
+		}
+	case "list_quests":
+		// Input: Item = NPC id, or empty for every giver
+		c.WriteMessage(websocket.TextMessage, createQuestListMsg(player, input.Item, h.now()))
+
+	case "accept_quest":
+		// Input: Item = quest id
+		now := h.now()
+		if err := acceptQuest(player, input.Item, now); err != nil {
+			errMsg, _ := json.Marshal(map[string]interface{}{
+				"type": "notification",
+				"msg":  "Can't accept quest: " + err.Error(),
+			})
+			c.WriteMessage(websocket.TextMessage, errMsg)
+			return
+		}
+		c.WriteMessage(websocket.TextMessage, createQuestUpdateMsg(player))
+		checkCollectQuest(player, now, c) // Items already in the bag count
+
+	case "turn_in_quest":
+		// Input: Item = quest id
+		if err := turnInQuest(player, input.Item, h.now(), c); err != nil {
+			errMsg, _ := json.Marshal(map[string]interface{}{
+				"type": "notification",
+				"msg":  "Can't turn in quest: " + err.Error(),
+			})
+			c.WriteMessage(websocket.TextMessage, errMsg)
+		}
+
+	case "abandon_quest":
+		// Input: Item = quest id
+		if err := abandonQuest(player, input.Item); err != nil {
+			return
+		}
+		c.WriteMessage(websocket.TextMessage, createQuestUpdateMsg(player))
+	case "mob_hit":
+		// Click Attack (Weapon)
+		// Check Cooldown
+		now := h.now()
+		if !player.useAbility(player.Weapon, now) {
+			return // Too fast
+		}
+		sendCooldown(c, player, player.Weapon)
+
+		mobID := input.Item
+		damage := player.scaleDamage(getWeaponDamage(player.Weapon), weaponStat(player.Weapon))
+
+		// Range Validation
+		mm := h.mobsUnsafe(player)
+		mm.mutex.Lock()
+		if mob, ok := mm.Mobs[mobID]; ok {
+			// ⚡ Bolt Optimization: Replacing math.Pow(x, 2) with x*x for faster range calculations
+			// and removing math.Sqrt by comparing squared distances.
+			dx := mob.X - player.X
+			dz := mob.Z - player.Z
+			// Use direct multiplication instead of math.Pow for performance
+			// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
+			distSq := dx*dx + dz*dz
+			// Weapon Range
+			if distSq > weaponRangeSq(player.Weapon) {
+				mm.mutex.Unlock()
+				return // Out of range
+			}
+		} else {
+			mm.mutex.Unlock()
+			return
+		}
+		mm.mutex.Unlock() // Unlock before processing damage which might lock again?
+
+		handleMobDamage(h, player, mobID, damage, c)
+
+	case "player_hit":
+		// PvP Logic
+		now := h.now()
+		if !player.useAbility(player.Weapon, now) {
+			return
+		}
+		sendCooldown(c, player, player.Weapon)
+
+		victimID := input.Item
+		damage := player.scaleDamage(getWeaponDamage(player.Weapon), weaponStat(player.Weapon))
+
+		// Range Check
+		victim, ok := h.players[victimID]
+		if ok {
+			// ⚡ Bolt Optimization: Replacing math.Pow(x, 2) with x*x for faster range calculations
+			// and removing math.Sqrt by comparing squared distances.
+			dx := victim.X - player.X
+			dz := victim.Z - player.Z
+			// Use direct multiplication instead of math.Pow for performance
+			// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
+			distSq := dx*dx + dz*dz
+			if distSq > weaponRangeSq(player.Weapon) {
+				return
+			}
+		}
+
+		if ok {
+			handlePlayerDamage(h, player, victimID, damage, c)
+		}
+
+	case "ability_hit":
+		// Fruit Ability Hit
+		// Input: Item = MobID
+		// We need ability name... reusing Weapon field? Or separate?
+		// Let's assume input.Item is MobID, input.Weapon is AbilityName (Reuse field for ease)
+
+		mobID := input.Item
+		ability := input.Weapon
+
+		// Only melee and the eaten fruit's own abilities are allowed
+		if ability != "melee" && !fruitGrantsAbility(player.CurrentFruit, ability) {
+			return
+		}
+		// Nothing to cast without rules; don't charge energy for it
+		if _, ok := abilityConfigs[ability]; !ok {
+			return
+		}
+
+		// Self buffs need no target
+		if fx, ok := selfEffects[ability]; ok {
+			now := h.now()
+			if !player.useAbility(ability, now) {
+				return
+			}
+			sendCooldown(c, player, ability)
+			player.Effects.Apply(fx.Kind, fx.Duration, fx.Magnitude, player.ID, now)
+			return
+		}
+
+		// A basic attack sent here is still a weapon swing: the weapon's
+		// cooldown and range apply, as in mob_hit
+		cooldownKey, maxRangeSq := ability, 150.0*150.0 // Generous range for now
+		if ability == "melee" {
+			cooldownKey, maxRangeSq = player.Weapon, weaponRangeSq(player.Weapon)
+		}
+
+		// Range Check Loop for Ability
+		mm := h.mobsUnsafe(player)
+		mm.mutex.Lock()
+		if mob, ok := mm.Mobs[mobID]; ok {
+			// ⚡ Bolt Optimization: Replacing math.Pow(x, 2) with x*x for faster range calculations
+			// and removing math.Sqrt by comparing squared distances.
+			dx := mob.X - player.X
+			dz := mob.Z - player.Z
+			// Use direct multiplication instead of math.Pow for performance
+			// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
+			distSq := dx*dx + dz*dz
+			mm.mutex.Unlock()
+			if distSq > maxRangeSq {
+				return
+			}
+		} else {
+			mm.mutex.Unlock()
+			return // Mob not found
+		}
+
+		// Haki Logic
+
+		// Haki Logic
+		damageMultiplier := 1.0
+		if player.HakiActive {
+			damageMultiplier = 1.2
+		}
+
+		// Check Cooldown
+		now := h.now()
+		if !player.useAbility(cooldownKey, now) {
+			return // Too fast
+		}
+		sendCooldown(c, player, cooldownKey)
+
+		damage := abilityConfigs[ability].Damage
+		if ability == "melee" {
+			damage = getWeaponDamage(player.Weapon)
+		}
+		if damage > 0 {
+			stat := StatFruit
+			if ability == "melee" {
+				stat = weaponStat(player.Weapon)
+			}
+			damage = player.scaleDamage(damage, stat)
+			damage = int(float64(damage) * damageMultiplier) // Apply Haki buff
+			handleMobDamage(h, player, mobID, damage, c)
+
+		}
+
+		// Charms, burns, slows... (see abilityEffects)
+		if fx, ok := abilityEffects[ability]; ok {
+			mm.mutex.Lock()
+			if mob, ok := mm.Mobs[mobID]; ok && mob.State != StateDead {
+				mob.Effects.Apply(fx.Kind, fx.Duration, fx.Magnitude, player.ID, now)
+			}
+			mm.mutex.Unlock()
+		}
+
+	case "pickup_item":
+		// Input: Item = DropID
+		drop, err := h.mobsUnsafe(player).PickupDrop(player, input.Item, h.now())
+		if err != nil {
+			return
+		}
+		if err := grantLoot(player, drop); err != nil {
+			return
+		}
+
+		updateMsg, _ := json.Marshal(map[string]interface{}{
+			"type":      "update_stats",
+			"money":     player.Money,
+			"inventory": player.Inventory,
+			"new_item":  drop.Item,
+		})
+		c.WriteMessage(websocket.TextMessage, updateMsg)
+		checkCollectQuest(player, h.now(), c)
+
+	case "trade_request", "trade_accept", "trade_offer", "trade_lock", "trade_confirm", "trade_cancel":
+		h.handleTradeInput(c, player, input)
+
+	case "crew_create", "crew_invite", "crew_accept", "crew_kick", "crew_promote", "crew_demote",
+		"crew_leave", "crew_disband", "crew_chat", "crew_deposit", "crew_withdraw", "crew_info":
+		h.handleCrewInput(c, player, input)
+
+	case "party_invite", "party_accept", "party_kick", "party_leave":
+		h.handlePartyInput(c, player, input)
+
+	case "admin_action":
+		if player.Role != "admin" && player.Role != "owner" {
+			return // Unauthorized
+		}
+
+		action := input.Item   // "kick", "ban", "grant", "teleport"
+		target := input.Weapon // Target Player ID or Item Name
+		// For grant, we might need more fields.
+		// Let's assume input.Item is Action ("kick", "grant_item")
+		// Weapon = TargetID
+		// Team = Extra Value (e.g. Item Name for grant)
+
+		switch action {
+		case "kick":
+			targetID := target
+			// Find connection
+			var targetConn *websocket.Conn
+			for c, pid := range h.clients {
+				if pid == targetID {
+					targetConn = c
+					break
+				}
+			}
+			if targetConn != nil {
+				targetConn.WriteMessage(websocket.TextMessage, []byte(`{"type":"kicked","reason":"Admin Kicked"}`))
+				targetConn.Close()
+				// Hub unregister will handle cleanup
+			}
+		case "grant_item":
+			targetID := target
+			itemName := input.Team // Reusing Team field for Item Name
+			if targetPlayer, ok := h.players[targetID]; ok {
+				if err := targetPlayer.Inventory.Add(itemName); err != nil {
+					return
+				}
+				// Notify Target
+				// We need to find their conn to send update, or just wait for next sync?
+				// Send stats update immediately
+				for c, pid := range h.clients {
+					if pid == targetID {
+						updateMsg, _ := json.Marshal(map[string]interface{}{
+							"type":      "update_stats",
+							"money":     targetPlayer.Money,
+							"inventory": targetPlayer.Inventory,
+							"new_item":  itemName,
+						})
+						c.WriteMessage(websocket.TextMessage, updateMsg)
+						break
+					}
+				}
+			}
+		case "teleport":
+			// Teleport self to target
+			targetID := target
+			if targetPlayer, ok := h.players[targetID]; ok {
+				player.X = targetPlayer.X
+				player.Y = targetPlayer.Y
+				player.Z = targetPlayer.Z
+				// Position will update on next tick broadcast
+			}
+		case "use_haki_conqueror":
+			// Range Check
+			hakiRangeSq := 400.0 // 20.0^2
+			stunDuration := 5.0  // Seconds
+
+			// Broadcast Visuals
+			broadcastMsg := map[string]interface{}{
+				"type": "event",
+				"name": "ConquerorHaki",
+				"id":   player.ID,
+			}
+			jsonMsg, _ := json.Marshal(broadcastMsg)
+			h.sendToRoomUnsafe(player.RoomID, jsonMsg)
+
+			// Stun Mobs
+			mm := h.mobsUnsafe(player)
+			mm.mutex.Lock()
+			now := h.now()
+			pX, pZ := player.X, player.Z
+			for _, mob := range mm.Mobs {
+				// ⚡ Bolt Optimization: Replacing math.Pow(x, 2) with x*x for faster range calculations
+				// and removing math.Sqrt by comparing squared distances.
+				dx := mob.X - pX
+				dz := mob.Z - pZ
+				// Use direct multiplication instead of math.Pow for performance
+				// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
+				distSq := dx*dx + dz*dz
+				if distSq <= hakiRangeSq {
+					mob.Effects.Apply(EffectStun, int64(stunDuration*1000), 0, player.ID, now)
+				}
+			}
+			mm.mutex.Unlock()
+
+		case "chat":
+			msgContent := input.Item
+			chatMsg := map[string]interface{}{
+				"type": "chat",
+				"id":   player.ID,
+				"item": msgContent,
+				"role": player.Role,
+			}
+			jsonMsg, _ := json.Marshal(chatMsg)
+			h.broadcast <- jsonMsg
+
+		case "reload_mobs":
+			// Hot-reload mob definitions and spawn zones; mobs already alive keep their stats
+			result := `{"type":"notification","msg":"Mob definitions reloaded"}`
+			if err := reloadMobData(mobDefinitionsPath, spawnZonesPath); err != nil {
+				log.Printf("Mob reload by %s failed: %v", player.ID, err)
+				errMsg, _ := json.Marshal(map[string]interface{}{
+					"type": "notification",
+					"msg":  "Mob reload failed: " + err.Error(),
+				})
+				result = string(errMsg)
+			} else {
+				for _, room := range h.rooms {
+					room.MobManager.SetSpawnZones(spawnZones.All())
+					room.MobManager.SetBossSchedule(spawnZones.Bosses())
+				}
+				log.Printf("Mob definitions reloaded by %s", player.ID)
+			}
+			c.WriteMessage(websocket.TextMessage, []byte(result))
+
+		case "reload_quests":
+			// Active quests keep the terms they were accepted with
+			result := `{"type":"notification","msg":"Quests reloaded"}`
+			if err := questCatalog.LoadFile(questsPath); err != nil {
+				log.Printf("Quest reload by %s failed: %v", player.ID, err)
+				errMsg, _ := json.Marshal(map[string]interface{}{
+					"type": "notification",
+					"msg":  "Quest reload failed: " + err.Error(),
+				})
+				result = string(errMsg)
+			} else {
+				log.Printf("Quests reloaded by %s", player.ID)
+			}
+			c.WriteMessage(websocket.TextMessage, []byte(result))
+
+		case "summon_boss":
+			// Spawn a boss at the admin's position in their room
+			mobType := target
+			if _, err := h.mobsUnsafe(player).SummonBoss(mobType, player.X, player.Z); err != nil {
+				errMsg, _ := json.Marshal(map[string]interface{}{
+					"type": "notification",
+					"msg":  "Summon failed: " + err.Error(),
+				})
+				c.WriteMessage(websocket.TextMessage, errMsg)
+			}
+
+		case "make_admin":
+			if player.Role != "owner" {
+				return // Only Owner can make admins
+			}
+			targetID := target // Use TargetID as Username (Assuming ID=Username in this system)
+			// Add to persistent storage
+			AddPersistentAdmin(targetID)
+
+			// Update runtime if online
+			if targetPlayer, ok := h.players[targetID]; ok {
+				targetPlayer.Role = "admin"
+				// Notify target?
+				// Send new init msg or just text
+				// We need to resend init to update client role if we want them to see admin panel immediately
+				// or just tell them "You are now admin"
+				if c, ok := h.clientsUnsafe(targetID); ok {
+					c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"You are now an Admin!"}`))
+					// Re-send init to update client role awareness
+					initMsg := map[string]interface{}{
+						"type":      "init",
+						"id":        targetID,
+						"money":     targetPlayer.Money,
+						"inventory": targetPlayer.Inventory,
+						"role":      targetPlayer.Role,
+					}
+					jsonMsg, _ := json.Marshal(initMsg)
+					c.WriteMessage(websocket.TextMessage, jsonMsg)
+
+				}
+			}
+
+		case "trade_history":
+			// Audit trail for scam reports; Target = player ID
+			history, err := LoadTradeHistory(target, 50)
+			if err != nil {
+				log.Printf("Trade history for %s: %v", target, err)
+				return
+			}
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type":   "trade_history",
+				"player": target,
+				"trades": history,
+			})
+			c.WriteMessage(websocket.TextMessage, msg)
+		}
 	}
 }
 
@@ -387,6 +1046,12 @@ type InputMessage struct {
 	Team   string  `json:"team,omitempty"`
 	Weapon string  `json:"weapon,omitempty"`
 	Item   string  `json:"item,omitempty"` // For buying/equipping
+
+	// Trading
+	Items map[string]int `json:"items,omitempty"`
+	Money int            `json:"money,omitempty"`
+
+	Amount int `json:"amount,omitempty"` // Generic count, e.g. stat points
 }
 
 func main() {
@@ -401,11 +1066,41 @@ func main() {
 	// Render sets PORT.
 	// Let's do:
 	pFlag := flag.String("port", "", "Port to listen on")
+	mobsFlag := flag.String("mobs", mobDefinitionsPath, "Mob definition file")
+	zonesFlag := flag.String("zones", spawnZonesPath, "Spawn zone file")
+	questsFlag := flag.String("quests", questsPath, "Quest catalog file")
+	flag.DurationVar(&deathConfig.RespawnDelay, "respawn-delay", deathConfig.RespawnDelay, "Time dead players wait to respawn")
+	flag.Float64Var(&deathConfig.MoneyPenalty, "death-money-penalty", deathConfig.MoneyPenalty, "Share of carried money lost on death (0-1)")
+	flag.Float64Var(&deathConfig.ExpPenalty, "death-exp-penalty", deathConfig.ExpPenalty, "Share of level progress lost on death (0-1)")
+	seedFlag := flag.Int64("seed", 0, "Seed for mob AI, loot and fruit rolls (0 = random)")
 	flag.Parse()
 	if *pFlag != "" {
 		port = *pFlag
 	}
 
+	// Validate mob definitions before accepting players
+	mobDefinitionsPath = *mobsFlag
+	if err := mobRegistry.LoadFile(mobDefinitionsPath); err != nil {
+		if !os.IsNotExist(err) {
+			log.Fatalf("Invalid mob definitions in %s: %v", mobDefinitionsPath, err)
+		}
+		log.Printf("%s not found, using built-in mob definitions", mobDefinitionsPath)
+	}
+	spawnZonesPath = *zonesFlag
+	if err := spawnZones.LoadFile(spawnZonesPath); err != nil {
+		if !os.IsNotExist(err) {
+			log.Fatalf("Invalid spawn zones in %s: %v", spawnZonesPath, err)
+		}
+		log.Printf("%s not found, using built-in spawn zones", spawnZonesPath)
+	}
+	questsPath = *questsFlag
+	if err := questCatalog.LoadFile(questsPath); err != nil {
+		if !os.IsNotExist(err) {
+			log.Fatalf("Invalid quest catalog in %s: %v", questsPath, err)
+		}
+		log.Printf("%s not found, using built-in quests", questsPath)
+	}
+
 	initDB()
 
 	if *reset {
@@ -432,6 +1127,15 @@ func main() {
 	})
 
 	hub := newHub()
+	if *seedFlag != 0 {
+		hub.reseed(*seedFlag)
+	}
+	log.Printf("RNG seed %d (replay with -seed)", hub.seed)
+	crews, err := LoadCrews()
+	if err != nil {
+		log.Fatalf("Failed to load crews: %v", err)
+	}
+	hub.crews = crews
 	go hub.run()
 
 	// Serve Static Files (Frontend)
@@ -459,7 +1163,7 @@ func main() {
 			}
 
 			if room == "" {
-				room = "public_1"
+				room = defaultRoomID
 			}
 			c.Locals("username", username)
 			c.Locals("room", room)
@@ -490,374 +1194,7 @@ func main() {
 			hub.mutex.Lock()
 			playerID := hub.clients[c]
 			if player, ok := hub.players[playerID]; ok {
-				switch input.Type {
-				// ... existing cases ...
-				case "move":
-					player.X = input.X
-					player.Z = input.Z
-				case "join_team":
-					player.Team = input.Team
-				case "set_weapon":
-					// Verify ownership
-					if player.Inventory.Has(input.Weapon) || input.Weapon == "melee" {
-						player.Weapon = input.Weapon
-					}
-				case "roll_fruit":
-					if player.Money >= 1000 {
-						player.Money -= 1000
-
-						// Calculate Luck
-						playerLuck := player.Luck
-						if hub.CurrentEvent == "Double Luck" {
-							playerLuck *= 2.0
-						}
-
-						fruit := rollRandomFruit(playerLuck)
-						player.Inventory.Add(fruit)
-
-						// Send Update
-						updateMsg, _ := json.Marshal(map[string]interface{}{
-							"type":      "update_stats",
-							"money":     player.Money,
-							"inventory": player.Inventory,
-							"new_item":  fruit,
-						})
-						c.WriteMessage(websocket.TextMessage, updateMsg)
-					}
-				case "buy_weapon":
-					price := getWeaponPrice(input.Item)
-					if price > 0 && player.Money >= price {
-						if !player.Inventory.Has(input.Item) {
-							player.Money -= price
-							player.Inventory.Add(input.Item)
-
-							// Send Update
-							updateMsg, _ := json.Marshal(map[string]interface{}{
-								"type":      "update_stats",
-								"money":     player.Money,
-								"inventory": player.Inventory,
-								"new_item":  input.Item,
-							})
-							c.WriteMessage(websocket.TextMessage, updateMsg)
-						}
-					}
-				case "accept_quest":
-					// Simple Hardcoded Quest for now
-					if input.Item == "gorilla_quest" {
-						player.ActiveQuest = &Quest{
-							Name:        "Defeat Gorillas",
-							Target:      "Gorilla",
-							TargetCount: 5,
-							Current:     0,
-							RewardExp:   500,
-							RewardMoney: 200,
-						}
-						// Send Update
-						c.WriteMessage(websocket.TextMessage, createQuestUpdateMsg(player))
-					}
-				case "mob_hit":
-					// Click Attack (Weapon)
-					// Check Cooldown
-					now := time.Now().UnixMilli()
-					cooldown := getWeaponCooldown(player.Weapon)
-					if now-player.LastAttack < cooldown {
-						continue // Too fast
-					}
-					player.LastAttack = now
-
-					mobID := input.Item
-					damage := getWeaponDamage(player.Weapon)
-
-					// Range Validation
-					hub.MobManager.mutex.Lock()
-					if mob, ok := hub.MobManager.Mobs[mobID]; ok {
-						// ⚡ Bolt Optimization: Replacing math.Pow(x, 2) with x*x for faster range calculations
-						// and removing math.Sqrt by comparing squared distances.
-						dx := mob.X - player.X
-						dz := mob.Z - player.Z
-						// Use direct multiplication instead of math.Pow for performance
-						// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
-						distSq := dx*dx + dz*dz
-						// Weapon Range
-						maxRangeSq := 225.0 // 15.0^2 Melee/Sword
-						if player.Weapon == "bazooka" || player.Weapon == "slingshot" {
-							maxRangeSq = 6400.0 // 80.0^2
-						}
-
-						if distSq > maxRangeSq {
-							hub.MobManager.mutex.Unlock()
-							continue // Out of range
-						}
-					} else {
-						hub.MobManager.mutex.Unlock()
-						continue
-					}
-					hub.MobManager.mutex.Unlock() // Unlock before processing damage which might lock again?
-
-					handleMobDamage(hub, player, mobID, damage, c)
-
-				case "player_hit":
-					// PvP Logic
-					now := time.Now().UnixMilli()
-					cooldown := getWeaponCooldown(player.Weapon)
-					if now-player.LastAttack < cooldown {
-						continue
-					}
-					player.LastAttack = now
-
-					victimID := input.Item
-					damage := getWeaponDamage(player.Weapon)
-
-					// Range Check
-					hub.mutex.Lock()
-					victim, ok := hub.players[victimID]
-					if ok {
-						// ⚡ Bolt Optimization: Replacing math.Pow(x, 2) with x*x for faster range calculations
-						// and removing math.Sqrt by comparing squared distances.
-						dx := victim.X - player.X
-						dz := victim.Z - player.Z
-						// Use direct multiplication instead of math.Pow for performance
-						// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
-						distSq := dx*dx + dz*dz
-						maxRangeSq := 225.0
-						if player.Weapon == "bazooka" || player.Weapon == "slingshot" {
-							maxRangeSq = 6400.0 // 80.0^2
-						}
-						if distSq > maxRangeSq {
-							hub.mutex.Unlock()
-							continue
-						}
-					}
-					hub.mutex.Unlock()
-
-					if ok {
-						handlePlayerDamage(hub, player, victimID, damage, c)
-					}
-
-				case "ability_hit":
-					// Fruit Ability Hit
-					// Input: Item = MobID
-					// We need ability name... reusing Weapon field? Or separate?
-					// Let's assume input.Item is MobID, input.Weapon is AbilityName (Reuse field for ease)
-
-					mobID := input.Item
-					ability := input.Weapon
-
-					// Range Check Loop for Ability
-					// Sanity Check: Max 100 distance for any ability for now
-					hub.MobManager.mutex.Lock()
-					if mob, ok := hub.MobManager.Mobs[mobID]; ok {
-						// ⚡ Bolt Optimization: Replacing math.Pow(x, 2) with x*x for faster range calculations
-						// and removing math.Sqrt by comparing squared distances.
-						dx := mob.X - player.X
-						dz := mob.Z - player.Z
-						// Use direct multiplication instead of math.Pow for performance
-						// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
-						distSq := dx*dx + dz*dz
-						hub.MobManager.mutex.Unlock()
-						// Max Range needed.
-						if distSq > 150.0*150.0 { // Generous range for now
-							continue
-						}
-					} else {
-						hub.MobManager.mutex.Unlock()
-						continue // Mob not found
-					}
-
-					// Haki Logic
-
-					// Haki Logic
-					damageMultiplier := 1.0
-					if player.HakiActive {
-						damageMultiplier = 1.2
-					}
-
-					// Check Cooldown
-					now := time.Now().UnixMilli()
-					cooldown := getWeaponCooldown(ability)
-					if now-player.LastAttack < cooldown {
-						continue // Too fast
-					}
-					player.LastAttack = now
-
-					damage := 0
-					switch ability {
-					case "melee":
-						damage = getWeaponDamage(player.Weapon)
-					case "Fireball":
-						damage = 40
-					case "FlamePillar":
-						damage = 60
-					case "IceShards":
-						damage = 25
-					case "IceSurge":
-						damage = 50
-					case "LoveBeam":
-						damage = 30
-						// Apply Charm State Logic?
-						// We need to access MobManager and set state.
-						// handleMobDamage can handle it if we pass ability name?
-						// Currently handleMobDamage only takes damage.
-						// We can modify handleMobDamage OR do it here if we lock MobManager.
-						// Let's do it here for specific effect:
-						hub.MobManager.mutex.Lock()
-						if mob, ok := hub.MobManager.Mobs[mobID]; ok {
-							mob.State = StateCharmed
-							mob.StunEnd = now + 5000 // 5s Charm
-						}
-						hub.MobManager.mutex.Unlock()
-					case "MagmaRain":
-						damage = 70
-					case "LightSpeed":
-						damage = 80
-					case "Transform":
-						damage = 100
-					case "DragonBreath":
-						damage = 60
-					case "Tornado":
-						damage = 30
-					}
-
-					if damage > 0 {
-						damage = int(float64(damage) * damageMultiplier) // Apply Haki buff
-						handleMobDamage(hub, player, mobID, damage, c)
-
-					}
-
-				case "admin_action":
-					if player.Role != "admin" && player.Role != "owner" {
-						continue // Unauthorized
-					}
-
-					action := input.Item   // "kick", "ban", "grant", "teleport"
-					target := input.Weapon // Target Player ID or Item Name
-					// For grant, we might need more fields.
-					// Let's assume input.Item is Action ("kick", "grant_item")
-					// Weapon = TargetID
-					// Team = Extra Value (e.g. Item Name for grant)
-
-					switch action {
-					case "kick":
-						targetID := target
-						// Find connection
-						var targetConn *websocket.Conn
-						for c, pid := range hub.clients {
-							if pid == targetID {
-								targetConn = c
-								break
-							}
-						}
-						if targetConn != nil {
-							targetConn.WriteMessage(websocket.TextMessage, []byte(`{"type":"kicked","reason":"Admin Kicked"}`))
-							targetConn.Close()
-							// Hub unregister will handle cleanup
-						}
-					case "grant_item":
-						targetID := target
-						itemName := input.Team // Reusing Team field for Item Name
-						if targetPlayer, ok := hub.players[targetID]; ok {
-							targetPlayer.Inventory.Add(itemName)
-							// Notify Target
-							// We need to find their conn to send update, or just wait for next sync?
-							// Send stats update immediately
-							for c, pid := range hub.clients {
-								if pid == targetID {
-									updateMsg, _ := json.Marshal(map[string]interface{}{
-										"type":      "update_stats",
-										"money":     targetPlayer.Money,
-										"inventory": targetPlayer.Inventory,
-										"new_item":  itemName,
-									})
-									c.WriteMessage(websocket.TextMessage, updateMsg)
-									break
-								}
-							}
-						}
-					case "teleport":
-						// Teleport self to target
-						targetID := target
-						if targetPlayer, ok := hub.players[targetID]; ok {
-							player.X = targetPlayer.X
-							player.Y = targetPlayer.Y
-							player.Z = targetPlayer.Z
-							// Position will update on next tick broadcast
-						}
-					case "use_haki_conqueror":
-						// Range Check
-						hakiRangeSq := 400.0 // 20.0^2
-						stunDuration := 5.0  // Seconds
-
-						// Broadcast Visuals
-						broadcastMsg := map[string]interface{}{
-							"type": "event",
-							"name": "ConquerorHaki",
-							"id":   player.ID,
-						}
-						jsonMsg, _ := json.Marshal(broadcastMsg)
-						hub.broadcast <- jsonMsg
-
-						// Stun Mobs
-						hub.MobManager.mutex.Lock()
-						now := time.Now().UnixMilli()
-						pX, pZ := player.X, player.Z
-						for _, mob := range hub.MobManager.Mobs {
-							// ⚡ Bolt Optimization: Replacing math.Pow(x, 2) with x*x for faster range calculations
-							// and removing math.Sqrt by comparing squared distances.
-							dx := mob.X - pX
-							dz := mob.Z - pZ
-							// Use direct multiplication instead of math.Pow for performance
-							// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
-							distSq := dx*dx + dz*dz
-							if distSq <= hakiRangeSq {
-								mob.State = StateStunned
-								mob.StunEnd = now + int64(stunDuration*1000)
-							}
-						}
-						hub.MobManager.mutex.Unlock()
-
-					case "chat":
-						msgContent := input.Item
-						chatMsg := map[string]interface{}{
-							"type": "chat",
-							"id":   player.ID,
-							"item": msgContent,
-							"role": player.Role,
-						}
-						jsonMsg, _ := json.Marshal(chatMsg)
-						hub.broadcast <- jsonMsg
-
-					case "make_admin":
-						if player.Role != "owner" {
-							continue // Only Owner can make admins
-						}
-						targetID := target // Use TargetID as Username (Assuming ID=Username in this system)
-						// Add to persistent storage
-						AddPersistentAdmin(targetID)
-
-						// Update runtime if online
-						if targetPlayer, ok := hub.players[targetID]; ok {
-							targetPlayer.Role = "admin"
-							// Notify target?
-							// Send new init msg or just text
-							// We need to resend init to update client role if we want them to see admin panel immediately
-							// or just tell them "You are now admin"
-							if c, ok := hub.clientsUnsafe(targetID); ok {
-								c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"You are now an Admin!"}`))
-								// Re-send init to update client role awareness
-								initMsg := map[string]interface{}{
-									"type":      "init",
-									"id":        targetID,
-									"money":     targetPlayer.Money,
-									"inventory": targetPlayer.Inventory,
-									"role":      targetPlayer.Role,
-								}
-								jsonMsg, _ := json.Marshal(initMsg)
-								c.WriteMessage(websocket.TextMessage, jsonMsg)
-
-							}
-						}
-					}
-				}
+				hub.handleInput(c, player, input)
 			}
 			hub.mutex.Unlock()
 		}
@@ -870,6 +1207,8 @@ func main() {
 	})
 
 	// Auth Endpoints
+	app.Get("/api/leaderboard", hub.handleLeaderboard)
+
 	app.Post("/api/register", authLimiter, func(c *fiber.Ctx) error {
 		type RegisterRequest struct {
 			Username string `json:"username"`
@@ -918,7 +1257,9 @@ func main() {
 				ID:     guestID,
 				RoomID: "public_1",
 				X:      0, Y: 3.5, Z: 0,
+				Level:  1,
 				Health: 100, MaxHealth: 100,
+				Energy: baseMaxEnergy, MaxEnergy: baseMaxEnergy,
 				Team:      "neutral",
 				Money:     1000, // Starter money
 				Inventory: NewInventory("melee"),
@@ -989,7 +1330,15 @@ func main() {
 	log.Fatal(app.Listen(":" + port))
 }
 
-func rollRandomFruit(_ float64) string {
+// rollableFruits is the gacha roster for roll_fruit.
+var rollableFruits = []string{
+	"Rocket Fruit", "Spin Fruit", "Chop Fruit", "Spring Fruit", "Bomb Fruit", "Smoke Fruit", "Spike Fruit", "Flame Fruit",
+	"Falcon Fruit", "Ice Fruit", "Sand Fruit", "Dark Fruit", "Diamond Fruit", "Light Fruit", "Love Fruit", "Rubber Fruit",
+	"Barrier Fruit", "Magma Fruit", "Quake Fruit", "Buddha Fruit", "String Fruit", "Phoenix Fruit", "Rumble Fruit", "Paw Fruit",
+	"Gravity Fruit", "Dough Fruit", "Shadow Fruit", "Venom Fruit", "Control Fruit", "Dragon Fruit", "Leopard Fruit",
+}
+
+func rollRandomFruit(rng *mrand.Rand, _ float64) string {
 
 	// Base Chances:
 	// Dragon (Legendary): 10% (0-9)
@@ -1002,15 +1351,8 @@ func rollRandomFruit(_ float64) string {
 	// Simplified Roll Logic: Just uniform random for now to test roster
 	// In production, use weighted table.
 	// 28 Fruits
-	fruits := []string{
-		"Rocket Fruit", "Spin Fruit", "Chop Fruit", "Spring Fruit", "Bomb Fruit", "Smoke Fruit", "Spike Fruit", "Flame Fruit",
-		"Falcon Fruit", "Ice Fruit", "Sand Fruit", "Dark Fruit", "Diamond Fruit", "Light Fruit", "Love Fruit", "Rubber Fruit",
-		"Barrier Fruit", "Magma Fruit", "Quake Fruit", "Buddha Fruit", "String Fruit", "Phoenix Fruit", "Rumble Fruit", "Paw Fruit",
-		"Gravity Fruit", "Dough Fruit", "Shadow Fruit", "Venom Fruit", "Control Fruit", "Dragon Fruit", "Leopard Fruit",
-	}
-
-	idx := int(time.Now().UnixNano()) % len(fruits)
-	return fruits[idx]
+	idx := rng.Intn(len(rollableFruits))
+	return rollableFruits[idx]
 }
 
 /*
@@ -1042,10 +1384,22 @@ func getWeaponPrice(item string) int {
 
 func createQuestUpdateMsg(p *Player) []byte {
 	msg := map[string]interface{}{
-		"type":        "quest_update",
-		"activeQuest": p.ActiveQuest,
-		"money":       p.Money,
-		"exp":         p.Exp,
+		"type":      "quest_update",
+		"quests":    p.Quests,
+		"completed": p.CompletedQuests,
+		"money":     p.Money,
+		"exp":       p.Exp,
+	}
+	b, _ := json.Marshal(msg)
+	return b
+}
+
+// createQuestListMsg lists the quests the player can accept from npcID.
+func createQuestListMsg(p *Player, npcID string, now int64) []byte {
+	msg := map[string]interface{}{
+		"type":   "quest_list",
+		"npc":    npcID,
+		"quests": availableQuests(p, npcID, now),
 	}
 	b, _ := json.Marshal(msg)
 	return b
@@ -1089,42 +1443,12 @@ func getWeaponDamage(w string) int {
 	return 10
 }
 
-func getWeaponCooldown(w string) int64 {
-	switch w {
-	case "Fireball":
-		return 1000
-	case "FlamePillar":
-		return 3000
-	case "IceShards":
-		return 800
-	case "IceSurge":
-		return 4000
-	case "LightSpeed":
-		return 1500
-	case "Transform":
-		return 5000 // Buddha
-	case "LoveBeam":
-		return 3000
-	case "MagmaRain":
-		return 3500
-	case "Barrier":
-		return 5000 // Wall CD
-	case "DragonBreath":
-		return 2000
-	case "Tornado":
-		return 1500
-	case "melee":
-		return 500 // ms
-	case "katana":
-		return 600
-	case "cutlass":
-		return 700
-	case "pipe":
-		return 1000
-	case "bazooka":
-		return 2000
+// weaponRangeSq is the squared reach of a weapon attack.
+func weaponRangeSq(w string) float64 {
+	if w == "bazooka" || w == "slingshot" {
+		return 6400.0 // 80.0^2
 	}
-	return 500
+	return 225.0 // 15.0^2 Melee/Sword
 }
 
 // Safe Zone Logic
@@ -1140,66 +1464,110 @@ func isSafeZone(x, z float64) bool {
 }
 
 // Helper to apply damage and handle rewards
+// Caller MUST hold hub.mutex.
 func handleMobDamage(hub *Hub, player *Player, mobID string, damage int, c *websocket.Conn) {
-	hub.MobManager.mutex.Lock()
-	if mob, ok := hub.MobManager.Mobs[mobID]; ok {
+	damage = int(float64(damage) * player.Effects.DamageFactor(hub.now()))
+	mm := hub.mobsUnsafe(player)
+	mm.mutex.Lock()
+	if mob, ok := mm.Mobs[mobID]; ok && mob.State != StateDead {
+		// Credit only the damage that actually landed
+		dealt := damage
+		if dealt > mob.Health {
+			dealt = mob.Health
+		}
+		mob.RecordDamage(player.ID, dealt)
+		if mob.boss != nil {
+			mob.boss.engage(hub.now())
+		}
+
 		mob.Health -= damage
 		if mob.Health <= 0 {
 			mob.State = StateDead
 			mob.Health = 0
 
-			// Rewards
-			player.Exp += mob.ExpReward
-
-			bountyReward := 100
 			if mob.IsBoss {
-				bountyReward = 5000
+				mm.queueBossEvent(map[string]interface{}{
+					"type":          "boss_defeated",
+					"mobId":         mob.ID,
+					"mobType":       mob.Type,
+					"killerId":      player.ID,
+					"contributions": mob.Contributions,
+				})
 			}
-			player.Bounty += bountyReward
 
-			// Notify Bounty Gain
-			if c != nil {
-				c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"Bounty Increased!"}`))
+			// Rewards (bosses split them among everyone who dealt damage)
+			now := hub.now()
+			questCredit := make(map[string]*Player)
+			// In ID order so loot rolls replay under the same seed
+			shares := mob.RewardShares(player.ID)
+			for _, id := range slices.Sorted(maps.Keys(shares)) {
+				share := shares[id]
+				p, ok := hub.players[id]
+				if !ok || p.RoomID != player.RoomID {
+					continue // Left the room before the kill
+				}
+				conn := c
+				if id != player.ID {
+					conn, _ = hub.clientsUnsafe(id)
+				}
+				hub.rewardMobKillUnsafe(p, mob, share, conn, questCredit)
+
+				// Personal loot roll, visible to its owner first
+				if drops := mm.dropLootLocked(mob, p, hub.luckUnsafe(p), now); len(drops) > 0 && conn != nil {
+					lootMsg, _ := json.Marshal(map[string]interface{}{
+						"type":  "loot_drop",
+						"drops": drops,
+					})
+					conn.WriteMessage(websocket.TextMessage, lootMsg)
+				}
 			}
-
-			// Quest Progress
-			if player.ActiveQuest != nil && player.ActiveQuest.Target == mob.Type {
-				player.ActiveQuest.Current++
-				if player.ActiveQuest.Current >= player.ActiveQuest.TargetCount {
-					// Complete
-					player.Money += player.ActiveQuest.RewardMoney
-					player.Exp += player.ActiveQuest.RewardExp
-					player.ActiveQuest = nil
-
-					// Simple "Quest Complete" bonus msg?
-					if c != nil {
-						c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"Quest Completed!"}`))
-					}
+			// Each credited player progresses their quests once per kill
+			for id, p := range questCredit {
+				conn := c
+				if id != player.ID {
+					conn, _ = hub.clientsUnsafe(id)
 				}
-				c.WriteMessage(websocket.TextMessage, createQuestUpdateMsg(player))
+				advanceKillQuest(p, mob, now, conn)
 			}
+			// Removed and respawned by the room's spawner on the next tick
+		}
+	}
+	mm.mutex.Unlock()
+}
 
-			// Respawn
-			go func(mid, mtype string, sx, sz float64) {
-				time.Sleep(5 * time.Second)
-				hub.MobManager.SpawnMob(mid, mtype, sx, sz)
-			}(mob.ID, mob.Type, mob.spawnX, mob.spawnZ)
+// rewardMobKillUnsafe grants a player their share of a mob's rewards. Party
+// members near the mob split the exp, with a bonus for grouping up; the
+// bounty stays with the player. Everyone who shared the kill is added to
+// questCredit.
+// Caller MUST hold hub.mutex.
+func (h *Hub) rewardMobKillUnsafe(player *Player, mob *Mob, share float64, c *websocket.Conn, questCredit map[string]*Player) {
+	near := h.partyNearUnsafe(player, mob.X, mob.Z)
+	expShare := share * partyExpMultiplier(len(near)) / float64(len(near))
+	for _, m := range near {
+		conn := c
+		if m != player {
+			conn, _ = h.clientsUnsafe(m.ID)
 		}
+		grantExp(m, int(float64(mob.ExpReward)*expShare), conn)
+		questCredit[m.ID] = m
+	}
+	player.Bounty += int(float64(mob.BountyReward) * share)
+
+	// Notify Bounty Gain
+	if c != nil {
+		c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"Bounty Increased!"}`))
 	}
-	hub.MobManager.mutex.Unlock()
 }
 
+// Caller MUST hold hub.mutex.
 func handlePlayerDamage(hub *Hub, attacker *Player, victimID string, damage int, c *websocket.Conn) {
-	hub.mutex.Lock()
 	victim, ok := hub.players[victimID]
-	if !ok {
-		hub.mutex.Unlock()
+	if !ok || victim.RoomID != attacker.RoomID || victim.Dead {
 		return
 	}
 
 	// Safe Zone Check
 	if isSafeZone(victim.X, victim.Z) || isSafeZone(attacker.X, attacker.Z) {
-		hub.mutex.Unlock()
 		// No PvP in Safe Zone
 		if c != nil {
 			c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"PvP Disabled in Safe Zone!"}`))
@@ -1209,62 +1577,42 @@ func handlePlayerDamage(hub *Hub, attacker *Player, victimID string, damage int,
 
 	// Team Check (No Friendly Fire, except Neutral?)
 	if victim.Team == attacker.Team && victim.Team != "neutral" {
-		hub.mutex.Unlock()
 		return
 	}
 
-	// Level/Bounty Difference Protection? (Optional, skipping for now to keep simple)
-
-	victim.Health -= damage
-	if victim.Health <= 0 {
-		victim.Health = 0
-
-		// Kill Rewards Logic
-		reward := 2500
-
-		// If Attacker is Marine
-		if attacker.Team == "marine" {
-			// Killing Pirate gives Honor
-			if victim.Team == "pirate" {
-				attacker.Bounty += reward
-			} else {
-				// Killing other marines/civilians might reduce honor? For now just add.
-				attacker.Bounty += reward
-			}
-		} else {
-			// Pirate
-			attacker.Bounty += reward
-		}
-		attacker.Money += 1000
+	// Crewmates never hurt each other, whatever their team
+	if victim.Crew != "" && victim.Crew == attacker.Crew {
+		return
+	}
 
-		// Victim Bounty Loss
-		loss := 1000
-		if victim.Bounty > 0 {
-			if victim.Bounty < loss {
-				loss = victim.Bounty
-			}
-			victim.Bounty -= loss
+	// Both sides must have opted in
+	if !attacker.PvP || !victim.PvP {
+		if c != nil {
+			c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"PvP is disabled for one of you!"}`))
 		}
-
-		// Notify Victim (Need to find conn)
-		// We are holding hub.mutex, so we can check clients
-		// But iterating clients inside handlePlayerDamage might be slow if many players.
-		// For now, it's fine.
-		// We will broadcast kill msg anyway.
-
+		return
 	}
-	hub.mutex.Unlock()
+	now := hub.now()
+	attacker.tagCombat(now)
+	victim.tagCombat(now)
 
-	if victim.Health == 0 {
-		// Respawn Logic (Teleport to spawn)
-		hub.mutex.Lock()
-		victim.Health = victim.MaxHealth
-		victim.X = 0
-		victim.Y = 3.5
-		victim.Z = 0
-		hub.mutex.Unlock()
+	// Level/Bounty Difference Protection? (Optional, skipping for now to keep simple)
+
+	_, killed := hub.dealDamageUnsafe(&DamageEvent{
+		Attacker: attacker,
+		Target:   victim,
+		Base:     damage,
+		Tags:     []string{TagPvP},
+		Now:      now,
+	})
+	if killed {
+		bounty, honor := applyPvPKill(attacker, victim)
+		if c != nil {
+			c.WriteMessage(websocket.TextMessage, pvpKillNotification(bounty, honor))
+		}
 
-		// Broadcast Kill Msg
+		// Announce the kill to the room; the hub mutex is held, so no
+		// blocking send on hub.broadcast
 		killMsg := map[string]interface{}{
 			"type": "chat",
 			"id":   "SERVER",
@@ -1272,6 +1620,6 @@ func handlePlayerDamage(hub *Hub, attacker *Player, victimID string, damage int,
 			"role": "system",
 		}
 		b, _ := json.Marshal(killMsg)
-		hub.broadcast <- b
+		hub.sendToRoomUnsafe(victim.RoomID, b)
 	}
 }
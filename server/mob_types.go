@@ -0,0 +1,177 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// mobDefinitionsPath is the mob definition file read at startup and on the
+// "reload_mobs" admin action.
+var mobDefinitionsPath = "mobs.json"
+
+// defaultMobDefinitions is the mob definition file compiled into the binary,
+// used when no file is present on disk (e.g. in the Docker image).
+//
+//go:embed mobs.json
+var defaultMobDefinitions []byte
+
+// MobAbility is a special attack a mob casts on a cooldown.
+type MobAbility struct {
+	Name     string      `json:"name"`
+	Damage   int         `json:"damage"`
+	Range    float64     `json:"range"`
+	Cooldown int64       `json:"cooldown"`         // ms
+	Radius   float64     `json:"radius"`           // Area of effect around the target point (bosses)
+	WindUp   int64       `json:"windUp"`           // ms between the telegraph and the hit (bosses)
+	Effect   *EffectSpec `json:"effect,omitempty"` // Applied to players the ability hurts
+}
+
+// MobType describes the stats and rewards of one kind of mob.
+type MobType struct {
+	Name            string       `json:"name"`
+	MaxHealth       int          `json:"maxHealth"`
+	Damage          int          `json:"damage"`
+	Speed           float64      `json:"speed"` // units per second
+	ExpReward       int          `json:"expReward"`
+	BountyReward    int          `json:"bountyReward"`
+	DetectionRadius float64      `json:"detectionRadius"`
+	LeashRange      float64      `json:"leashRange"` // Max distance from spawn before giving up the chase
+	IsBoss          bool         `json:"isBoss"`
+	Abilities       []MobAbility `json:"abilities"`
+	Boss            *BossConfig  `json:"boss,omitempty"`
+	Loot            *LootTable   `json:"loot,omitempty"`
+}
+
+// ability looks up one of the type's abilities by name.
+func (t *MobType) ability(name string) (MobAbility, bool) {
+	for _, a := range t.Abilities {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return MobAbility{}, false
+}
+
+type mobDefinitionFile struct {
+	Mobs []*MobType `json:"mobs"`
+}
+
+// MobRegistry holds the known mob types. It is safe for concurrent use and
+// can be reloaded while the server is running.
+type MobRegistry struct {
+	types map[string]*MobType
+	mu    sync.RWMutex
+}
+
+var mobRegistry = newDefaultMobRegistry()
+
+func newDefaultMobRegistry() *MobRegistry {
+	r := &MobRegistry{types: make(map[string]*MobType)}
+	if err := r.Load(defaultMobDefinitions); err != nil {
+		log.Fatalf("Invalid built-in mob definitions: %v", err)
+	}
+	return r
+}
+
+// Get returns the mob type with the given name.
+func (r *MobRegistry) Get(name string) (*MobType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// Load parses and validates a mob definition file. The registry is only
+// replaced if every definition is valid.
+func (r *MobRegistry) Load(data []byte) error {
+	var file mobDefinitionFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if len(file.Mobs) == 0 {
+		return errors.New("no mobs defined")
+	}
+
+	types := make(map[string]*MobType, len(file.Mobs))
+	for i, t := range file.Mobs {
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("mob %d (%q): %w", i, t.Name, err)
+		}
+		if _, dup := types[t.Name]; dup {
+			return fmt.Errorf("mob %d: duplicate name %q", i, t.Name)
+		}
+		types[t.Name] = t
+	}
+
+	r.swap(types)
+	return nil
+}
+
+// swap installs types and returns the definitions they replaced.
+func (r *MobRegistry) swap(types map[string]*MobType) map[string]*MobType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := r.types
+	r.types = types
+	return previous
+}
+
+// LoadFile loads mob definitions from path.
+func (r *MobRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.Load(data)
+}
+
+func (t *MobType) validate() error {
+	switch {
+	case t.Name == "":
+		return errors.New("name is required")
+	case t.MaxHealth <= 0:
+		return errors.New("maxHealth must be positive")
+	case t.Damage < 0:
+		return errors.New("damage must not be negative")
+	case t.Speed <= 0:
+		return errors.New("speed must be positive")
+	case t.ExpReward < 0 || t.BountyReward < 0:
+		return errors.New("rewards must not be negative")
+	case t.DetectionRadius <= 0:
+		return errors.New("detectionRadius must be positive")
+	case t.LeashRange < t.DetectionRadius:
+		return errors.New("leashRange must be at least detectionRadius")
+	}
+	for _, a := range t.Abilities {
+		if a.Name == "" {
+			return errors.New("ability name is required")
+		}
+		if a.Range <= 0 || a.Cooldown <= 0 || a.Damage < 0 {
+			return fmt.Errorf("ability %q: range and cooldown must be positive", a.Name)
+		}
+		if a.Radius < 0 || a.WindUp < 0 {
+			return fmt.Errorf("ability %q: radius and windUp must not be negative", a.Name)
+		}
+		if a.Effect != nil {
+			if _, ok := effectDefs[a.Effect.Kind]; !ok || a.Effect.Duration <= 0 {
+				return fmt.Errorf("ability %q: invalid effect %q", a.Name, a.Effect.Kind)
+			}
+		}
+	}
+	if t.Boss != nil {
+		if err := t.Boss.validate(t); err != nil {
+			return err
+		}
+	}
+	if t.Loot != nil {
+		if err := t.Loot.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
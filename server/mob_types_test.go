@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMobRegistry_Load(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		expectErr bool
+	}{
+		{"valid", `{"mobs":[{"name":"Bandit","maxHealth":50,"damage":5,"speed":2,"detectionRadius":10,"leashRange":30}]}`, false},
+		{"empty", `{"mobs":[]}`, true},
+		{"malformed", `{"mobs":`, true},
+		{"missing_name", `{"mobs":[{"maxHealth":50,"speed":2,"detectionRadius":10,"leashRange":30}]}`, true},
+		{"zero_health", `{"mobs":[{"name":"Bandit","speed":2,"detectionRadius":10,"leashRange":30}]}`, true},
+		{"leash_too_short", `{"mobs":[{"name":"Bandit","maxHealth":50,"speed":2,"detectionRadius":10,"leashRange":5}]}`, true},
+		{"duplicate", `{"mobs":[{"name":"Bandit","maxHealth":50,"speed":2,"detectionRadius":10,"leashRange":30},{"name":"Bandit","maxHealth":50,"speed":2,"detectionRadius":10,"leashRange":30}]}`, true},
+		{"bad_ability", `{"mobs":[{"name":"Bandit","maxHealth":50,"speed":2,"detectionRadius":10,"leashRange":30,"abilities":[{"name":"Slash","range":5}]}]}`, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newDefaultMobRegistry()
+			err := r.Load([]byte(tc.data))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error: %v, got: %v", tc.expectErr, err)
+			}
+			if tc.expectErr {
+				// A failed load must keep the previous definitions
+				if _, ok := r.Get("Gorilla"); !ok {
+					t.Errorf("failed load should not replace the registry")
+				}
+			}
+		})
+	}
+}
+
+func TestSpawnMob_UnknownType(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+
+	if err := mm.SpawnMob("mob1", "Sea King", 0, 0); err == nil {
+		t.Fatal("expected error for unknown mob type")
+	}
+	if len(mm.Mobs) != 0 {
+		t.Errorf("unknown mob type should not be spawned")
+	}
+}
+
+func TestSpawnMob_UsesRegistryStats(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+
+	if err := mm.SpawnMob("boss", "Ice Admiral", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	def, _ := mobRegistry.Get("Ice Admiral")
+	mob := mm.Mobs["boss"]
+	if mob.MaxHealth != def.MaxHealth || !mob.IsBoss || len(mob.Abilities) != len(def.Abilities) {
+		t.Errorf("mob stats do not match definition: %+v", mob)
+	}
+}
+
+func TestMobManager_Update_Leash(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+
+	mm.SpawnMob("mob1", "Gorilla", 100.0, 100.0)
+	mob := mm.Mobs["mob1"]
+	// Dragged past the leash range while a player is still in reach
+	mob.X = 100.0 + mob.LeashRange + 5
+	hub.players["player1"] = &Player{
+		ID:     "player1",
+		RoomID: defaultRoomID,
+		X:      mob.X + 3,
+		Z:      100.0,
+	}
+
+	mm.Update(0.05)
+
+	if mob.State != StateIdle || mob.TargetID != "" {
+		t.Errorf("Leashed mob should stop chasing, got state=%s target=%s", mob.State, mob.TargetID)
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"maps"
 	"math"
 	"math/rand"
+	"slices"
 	"sync"
-	"time"
 )
 
 type MobState string
@@ -19,88 +21,220 @@ const (
 	StateStunned MobState = "stunned"
 )
 
+const mobAttackInterval = 500 // ms between melee hits
+
 type Mob struct {
-	ID        string   `json:"id"`
-	Type      string   `json:"type"` // "Gorilla", "Marine", etc.
-	X         float64  `json:"x"`
-	Y         float64  `json:"y"`
-	Z         float64  `json:"z"`
-	Health    int      `json:"health"`
-	MaxHealth int      `json:"maxHealth"`
-	State     MobState `json:"state"`
-	TargetID  string   `json:"-"` // ID of player being chased
-	Speed     float64  `json:"-"`
-	Damage    int      `json:"-"`
-	ExpReward int      `json:"-"`
-	IsBoss    bool     `json:"isBoss"`
-	Abilities []string `json:"-"`
-	AbilityCD int64    `json:"-"` // Time when next ability can be used
-	StunEnd   int64    `json:"-"` // Time when stun ends
-	PoisonEnd int64    `json:"-"` // Time when poison ends
-
-	spawnX float64
-	spawnZ float64
+	ID        string        `json:"id"`
+	Type      string        `json:"type"` // "Gorilla", "Marine", etc.
+	X         float64       `json:"x"`
+	Y         float64       `json:"y"`
+	Z         float64       `json:"z"`
+	Health    int           `json:"health"`
+	MaxHealth int           `json:"maxHealth"`
+	State     MobState      `json:"state"`
+	TargetID  string        `json:"-"` // ID of player being chased
+	Speed     float64       `json:"-"`
+	Damage    int           `json:"-"`
+	ExpReward int           `json:"-"`
+	IsBoss    bool          `json:"isBoss"`
+	AbilityCD int64         `json:"-"` // Time when next ability can be used
+	Effects   StatusEffects `json:"effects"`
+
+	BountyReward    int          `json:"-"`
+	DetectionRadius float64      `json:"-"`
+	LeashRange      float64      `json:"-"`
+	Abilities       []MobAbility `json:"-"`
+
+	Phase         int            `json:"phase,omitempty"`   // Boss phase index
+	Enraged       bool           `json:"enraged,omitempty"` // Boss enrage timer expired
+	Contributions map[string]int `json:"-"`                 // PlayerID -> damage dealt
+	boss          *bossEncounter
+
+	spawnX    float64
+	spawnZ    float64
+	returning bool  // Leashed: walking back to spawn and ignoring players
+	attack    Timer // Melee hit interval
 }
 
+// MobManager simulates the mob population of a single room and the loot
+// they leave behind.
+// Lock order is hub.mutex before mutex.
 type MobManager struct {
-	Mobs  map[string]*Mob
-	mutex sync.Mutex
-	hub   *Hub
+	Mobs   map[string]*Mob
+	Drops  map[string]*GroundDrop
+	RoomID string
+	mutex  sync.Mutex
+	hub    *Hub
+	nextID int
+	outbox [][]byte   // Room-scoped messages produced during Update
+	rng    *rand.Rand // Spawn, loot, fruit and damage rolls of this room. Caller MUST hold hub.mutex.
+
+	spawners     []*zoneSpawner
+	bossSpawners []*bossSpawner
 }
 
-func NewMobManager(hub *Hub) *MobManager {
+func NewMobManager(hub *Hub, roomID string) *MobManager {
 	return &MobManager{
-		Mobs: make(map[string]*Mob),
-		hub:  hub,
+		Mobs:   make(map[string]*Mob),
+		Drops:  make(map[string]*GroundDrop),
+		RoomID: roomID,
+		hub:    hub,
+		rng:    hub.roomRNG(roomID),
 	}
 }
 
-func (mm *MobManager) SpawnMob(id, mobType string, x, z float64) {
+// NewMobID returns a mob ID that is unique within this manager.
+func (mm *MobManager) NewMobID() string {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	return mm.newMobIDLocked()
+}
+
+// Caller MUST hold mm.mutex.
+func (mm *MobManager) newMobIDLocked() string {
+	mm.nextID++
+	return fmt.Sprintf("%s_mob_%d", mm.RoomID, mm.nextID)
+}
+
+// queue buffers a message for the players of this room.
+// Caller MUST hold mm.mutex.
+func (mm *MobManager) queue(msg []byte) {
+	mm.outbox = append(mm.outbox, msg)
+}
+
+// DrainMessages returns and clears the messages queued since the last call.
+func (mm *MobManager) DrainMessages() [][]byte {
 	mm.mutex.Lock()
 	defer mm.mutex.Unlock()
+	msgs := mm.outbox
+	mm.outbox = nil
+	return msgs
+}
+
+// Snapshot returns the living mobs of this room for the mob_update message.
+func (mm *MobManager) Snapshot() map[string]*Mob {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	mobData := make(map[string]*Mob, len(mm.Mobs))
+	for k, v := range mm.Mobs {
+		if v.State != StateDead {
+			mobData[k] = v
+		}
+	}
+	return mobData
+}
+
+// SpawnMob creates a mob of a type known to the mob registry.
+func (mm *MobManager) SpawnMob(id, mobType string, x, z float64) error {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	_, err := mm.spawnMobLocked(id, mobType, x, z)
+	return err
+}
+
+// Caller MUST hold mm.mutex.
+func (mm *MobManager) spawnMobLocked(id, mobType string, x, z float64) (*Mob, error) {
+	def, ok := mobRegistry.Get(mobType)
+	if !ok {
+		return nil, fmt.Errorf("unknown mob type %q", mobType)
+	}
+
+	mob := &Mob{
+		ID:              id,
+		Type:            def.Name,
+		X:               x,
+		Y:               2.2, // Ground level approximation
+		Z:               z,
+		Health:          def.MaxHealth,
+		MaxHealth:       def.MaxHealth,
+		State:           StateIdle,
+		Speed:           def.Speed,
+		Damage:          def.Damage,
+		ExpReward:       def.ExpReward,
+		BountyReward:    def.BountyReward,
+		DetectionRadius: def.DetectionRadius,
+		LeashRange:      def.LeashRange,
+		Abilities:       def.Abilities,
+		IsBoss:          def.IsBoss,
+		Contributions:   make(map[string]int),
+		spawnX:          x,
+		spawnZ:          z,
+	}
+	if def.Boss != nil {
+		mob.boss = &bossEncounter{config: def.Boss, def: def}
+	}
+	mm.Mobs[id] = mob
+
+	if mob.IsBoss {
+		mm.queueBossEvent(map[string]interface{}{
+			"type":    "boss_spawn",
+			"mobId":   mob.ID,
+			"mobType": mob.Type,
+			"x":       mob.X,
+			"z":       mob.Z,
+		})
+	}
+	return mob, nil
+}
+
+// RecordDamage credits a player with damage dealt to the mob.
+func (mob *Mob) RecordDamage(playerID string, amount int) {
+	if amount > 0 {
+		mob.Contributions[playerID] += amount
+	}
+}
 
-	hp := 100
-	dmg := 10
-	speed := 2.0 // units per second
-
-	switch mobType {
-	case "Gorilla":
-		hp = 200
-		dmg = 15
-		speed = 3.0
-	case "Gorilla King":
-		hp = 1000
-		dmg = 50
-		speed = 4.0
-	case "Ice Admiral":
-		hp = 5000
-		dmg = 100
-		speed = 5.0
+// RewardShares returns each participant's share of the kill rewards. Bosses
+// split rewards by damage contribution; other mobs reward the killer only.
+func (mob *Mob) RewardShares(killerID string) map[string]float64 {
+	if !mob.IsBoss {
+		return map[string]float64{killerID: 1.0}
+	}
+	total := 0
+	for _, dmg := range mob.Contributions {
+		total += dmg
+	}
+	if total == 0 {
+		return map[string]float64{killerID: 1.0}
 	}
+	shares := make(map[string]float64, len(mob.Contributions))
+	for id, dmg := range mob.Contributions {
+		shares[id] = float64(dmg) / float64(total)
+	}
+	return shares
+}
 
-	mm.Mobs[id] = &Mob{
-		ID:        id,
-		Type:      mobType,
-		X:         x,
-		Y:         2.2, // Ground level approximation
-		Z:         z,
-		Health:    hp,
-		MaxHealth: hp,
-		State:     StateIdle,
-		Speed:     speed,
-		Damage:    dmg,
-		ExpReward: hp / 2,
-		spawnX:    x,
-		spawnZ:    z,
-		IsBoss:    mobType == "Gorilla King" || mobType == "Ice Admiral",
+// damageMultiplier is applied to all damage the mob deals.
+func (mob *Mob) damageMultiplier() float64 {
+	if mob.Enraged && mob.boss != nil {
+		return mob.boss.config.EnrageMultiplier
 	}
+	return 1.0
+}
+
+// currentSpeed is the mob's chase speed including boss phase and enrage.
+func (mob *Mob) currentSpeed() float64 {
+	speed := mob.Speed
+	if mob.boss != nil {
+		if m := mob.boss.config.Phases[mob.Phase].SpeedMultiplier; m > 0 {
+			speed *= m
+		}
+		speed *= mob.damageMultiplier()
+	}
+	return speed
 }
 
 func (mm *MobManager) Update(deltaTime float64) {
+	mm.hub.mutex.Lock()
+	defer mm.hub.mutex.Unlock()
 	mm.mutex.Lock()
 	defer mm.mutex.Unlock()
 
-	now := time.Now().UnixMilli()
+	now := mm.hub.now()
+
+	mm.updateSpawnersLocked(now)
+	mm.updateBossSpawnersLocked(now)
+	mm.updateDropsLocked(now)
 
 	// Spatial partitioning grid to optimize player distance checks
 	type cellKey struct {
@@ -110,89 +244,88 @@ func (mm *MobManager) Update(deltaTime float64) {
 	cellSize := 20.0
 	grid := make(map[cellKey][]*Player)
 
-	mm.hub.mutex.Lock()
 	for _, p := range mm.hub.players {
-		if isSafeZone(p.X, p.Z) {
+		if p.RoomID != mm.RoomID || p.Dead || isSafeZone(p.X, p.Z) {
 			continue
 		}
 		cx := int(math.Floor(p.X / cellSize))
 		cz := int(math.Floor(p.Z / cellSize))
 		grid[cellKey{cx, cz}] = append(grid[cellKey{cx, cz}], p)
 	}
-	mm.hub.mutex.Unlock()
 
-	for _, mob := range mm.Mobs {
+	// Fruit auras tick on their own timers
+	for _, cell := range grid {
+		for _, p := range cell {
+			mm.applyAuraLocked(p, now)
+		}
+	}
+
+	// In ID order so the room's rolls replay under the same seed
+	for _, id := range slices.Sorted(maps.Keys(mm.Mobs)) {
+		mob := mm.Mobs[id]
 		if mob.State == StateDead {
 			continue
 		}
 
-		// Handle Stun/Charm
-		if now < mob.StunEnd {
-			mob.State = StateCharmed
-			// Still take damage if poisoned
-		} else if mob.State == StateCharmed {
+		// Status effects: damage over time leaves the kill to the players
+		if dot, _ := mob.Effects.Update(now); dot > 0 {
+			hurtMobNonLethal(mob, dot)
+		}
+		if mob.Effects.Disabled(now) {
+			mob.State = StateStunned
+			if mob.Effects.Has(EffectCharm, now) {
+				mob.State = StateCharmed
+			}
+			mob.TargetID = ""
+			continue
+		} else if mob.State == StateCharmed || mob.State == StateStunned {
 			mob.State = StateIdle // Wake up
 		}
 
-		// Handle Poison (Venom Fruit)
-		if now < mob.PoisonEnd {
-			// Take DoT (every ~1s? or every tick?)
-			// Every tick is too fast. Let's use random chance again for "tickrate"
-			// ⚡ Bolt Optimization: Using math/rand instead of math.Sin(time) to reduce CPU overhead
-			if rand.Float64() < 0.2 {
-				mob.Health -= 2
+		// Leash: give up the chase once dragged too far from spawn
+		if !mob.returning && distanceSq(mob.X, mob.Z, mob.spawnX, mob.spawnZ) > mob.LeashRange*mob.LeashRange {
+			mob.returning = true
+			if mob.boss != nil {
+				mm.resetBossLocked(mob)
 			}
 		}
+		if mob.returning && distanceSq(mob.X, mob.Z, mob.spawnX, mob.spawnZ) <= 1.0 {
+			mob.returning = false
+		}
 
 		// AI Logic
 		// 1. Check for nearby players to chase
 		var closestPlayer *Player
 		// ⚡ Bolt Optimization: Use squared distance to avoid expensive math.Sqrt calls during detection loop
-		minDistSq := 15.0 * 15.0 // Detection radius squared
+		minDistSq := mob.DetectionRadius * mob.DetectionRadius
+		if mob.returning {
+			minDistSq = 0 // Ignore everyone until back home
+		}
 
 		mobCx := int(math.Floor(mob.X / cellSize))
 		mobCz := int(math.Floor(mob.Z / cellSize))
+		cellRange := int(math.Ceil(mob.DetectionRadius / cellSize))
 
-		mm.hub.mutex.Lock()
-		for dx := -1; dx <= 1; dx++ {
-			for dz := -1; dz <= 1; dz++ {
+		for dx := -cellRange; dx <= cellRange; dx++ {
+			for dz := -cellRange; dz <= cellRange; dz++ {
 				key := cellKey{mobCx + dx, mobCz + dz}
 				for _, p := range grid[key] {
 					// ⚡ Bolt Optimization: Calculate squared distance
 					distSq := distanceSq(mob.X, mob.Z, p.X, p.Z)
 
-					// Magma Aura Passive (Feature 6)
-					// Apply tick damage every frame? Too fast.
-					// Let's rely on randomness to throttle or add a BurnTimer.
-					// Random 5% chance per tick (20 ticks/sec -> 1 hit/sec avg)
-					if p.Weapon == "Magma Fruit" && distSq < 64.0 { // 8.0 squared
-						if math.Sin(float64(now)) > 0.95 { // Simple random throttle
-							mob.Health -= 5
-							// Don't kill implicitly here without rewards?
-							// Let's just reduce health. If it dies, the next handleMobDamage check will fail?
-							// Or just let it be 1 HP for player to finish.
-							if mob.Health < 1 {
-								mob.Health = 1
-							}
-						}
-					}
-
-					// Shadow Stealth (Feature 10)
-					if p.Weapon == "Shadow Fruit" { // Renamed Ghost->Shadow in Roster
-						// Detection radius reduced
-						if distSq > 25.0 { // 5.0 squared
-							continue // Ignore player unless very close
-						}
+					// Stealthy players are only noticed up close
+					if st := p.passiveSet().Stealth; st != nil && distSq > st.Radius*st.Radius {
+						continue
 					}
 
-					if distSq < minDistSq {
+					// Ties go to the lower ID, whatever the map order
+					if distSq < minDistSq || (distSq == minDistSq && closestPlayer != nil && p.ID < closestPlayer.ID) {
 						minDistSq = distSq
 						closestPlayer = p
 					}
 				}
 			}
 		}
-		mm.hub.mutex.Unlock()
 
 		if closestPlayer != nil {
 			// Re-check Safe Zone (in case they just entered)
@@ -205,6 +338,10 @@ func (mm *MobManager) Update(deltaTime float64) {
 			mob.State = StateChase
 			mob.TargetID = closestPlayer.ID
 
+			if mob.boss != nil && mm.updateBossLocked(mob, closestPlayer, now) {
+				continue // Rooted while winding up a telegraphed ability
+			}
+
 			// Move towards player
 			dx := closestPlayer.X - mob.X
 			dz := closestPlayer.Z - mob.Z
@@ -216,203 +353,73 @@ func (mm *MobManager) Update(deltaTime float64) {
 				dirX := dx / dist
 				dirZ := dz / dist
 
-				// Dough Slow Aura (Feature 19)
-				currentSpeed := mob.Speed
-				if closestPlayer.Weapon == "Dough Fruit" {
-					currentSpeed *= 0.5 // 50% Slow
+				currentSpeed := mob.currentSpeed() * mob.Effects.SpeedFactor(now)
+				if slow := closestPlayer.passiveSet().Slow; slow != nil {
+					currentSpeed *= slow.Factor
 				}
 
 				mob.X += dirX * currentSpeed * deltaTime
 				mob.Z += dirZ * currentSpeed * deltaTime
 
-				// Boss Ability: Ice Spikes
-				if mob.IsBoss && mob.Type == "Ice Admiral" {
-					if now > mob.AbilityCD {
-						// Range Check (e.g. 15 units)
-						if distSq < 225.0 { // 15^2
-							// Cast Ability
-							mob.AbilityCD = now + 5000 // 5s Cooldown
-
-							// Deal Damage to Player (Area of Effect)
-							// Simple: Just Damage the target logic for now
-							// In a real server, we'd spawn a "Projectile" entity.
-							// Here we just instant hit for simplicity of prototype.
-							mm.hub.mutex.Lock()
-							closestPlayer.Health -= 30
-							if closestPlayer.Health < 0 {
-								closestPlayer.Health = 0
-							}
-							mm.hub.mutex.Unlock()
-
-							// We should Broadcast this "Cast" to clients for Visuals!
-							castMsg, _ := json.Marshal(map[string]interface{}{
-								"type":      "mob_cast_ability",
-								"mobId":     mob.ID,
-								"ability":   "IceSpikes",
-								"targetId":  closestPlayer.ID,
-								"timestamp": now,
-							})
-
-							// Broadcast to all clients (simplified, ideally only room)
-							// Send via non-blocking channel send to prevent deadlocking the Hub.run loop,
-							// which handles h.broadcast and also calls MobManager.Update.
-							select {
-							case mm.hub.broadcast <- castMsg:
-							default:
-								// Drop message if channel is full to prevent deadlocking
-							}
+				// Mob Abilities (bosses cast theirs through the encounter above)
+				if mob.boss == nil && now > mob.AbilityCD {
+					for _, ability := range mob.Abilities {
+						if distSq >= ability.Range*ability.Range {
+							continue
 						}
+						// Cast Ability
+						mob.AbilityCD = now + ability.Cooldown
+
+						// Deal Damage to Player (Area of Effect)
+						// Simple: Just Damage the target logic for now
+						// In a real server, we'd spawn a "Projectile" entity.
+						// Here we just instant hit for simplicity of prototype.
+						mm.hub.dealDamageUnsafe(&DamageEvent{
+							Mob:     mob,
+							Target:  closestPlayer,
+							Ability: &ability,
+							Base:    ability.Damage,
+							Tags:    []string{TagAbility},
+							Now:     now,
+						})
+
+						// We should Broadcast this "Cast" to clients for Visuals!
+						castMsg, _ := json.Marshal(map[string]interface{}{
+							"type":      "mob_cast_ability",
+							"mobId":     mob.ID,
+							"ability":   ability.Name,
+							"targetId":  closestPlayer.ID,
+							"timestamp": now,
+						})
+
+						// Delivered to this room only once Update returns
+						mm.queue(castMsg)
+						break
 					}
 				}
 
 			} else {
 				// Attack Logic (Melee)
 				mob.State = StateAttack
-				// Simple Melee Damage
-				// Only once per second? We need attack timer.
-				// Reusing AbilityCD for melee timer for non-bosses? Or separate?
-				// Let's keep it simple: If close, player takes minimal damage over time?
-				// Or just frame-perfect damage (dangerous).
-				// Let's add a random chance to hit per tick (poor man's cooldown)
-				if rand.Float64() < 0.1 {
-					mm.hub.mutex.Lock()
-
-					// Rubber Immunity (Feature 5)
-					damage := mob.Damage
-					if closestPlayer.Weapon == "Rubber Fruit" && !mob.IsBoss {
-						damage = 0 // Immune to non-bosses
-					}
-
-					// Diamond Defense (Feature 12)
-					if closestPlayer.Weapon == "Diamond Fruit" {
-						damage = damage / 2 // 50% Reduction
-					}
-
-					// Rumble Stun Proc (Feature 15)
-					if closestPlayer.Weapon == "Rumble Fruit" {
-						if rand.Float64() < 0.2 { // Random chance
-							mob.State = StateCharmed // Reuse charmed state for stun
-							mob.StunEnd = now + 2000 // 2s Stun
-						}
-					}
-
-					if damage > 0 {
-						closestPlayer.Health -= damage
-						if closestPlayer.Health < 0 {
-							closestPlayer.Health = 0
-						}
-
-						// Spike Thorns Reflection (Feature 11)
-						if closestPlayer.Weapon == "Spike Fruit" {
-							// Mob takes damage back
-							mob.Health -= 10
-							if mob.Health < 1 {
-								mob.Health = 1
-							}
-						}
-
-						// Venom Poison Application (Feature 18)
-						if closestPlayer.Weapon == "Venom Fruit" {
-							mob.PoisonEnd = now + 5000
-						}
-
-						// String Trap (Feature 20)
-						if closestPlayer.Weapon == "String Fruit" {
-							if rand.Float64() < 0.4 {
-								mob.StunEnd = now + 1500
-								mob.State = StateCharmed
-							}
-						}
-
-						// Leopard Boost (Feature 22)
-						if closestPlayer.Weapon == "Leopard Fruit" {
-							damage = damage / 2 // 50% Defense
-						}
-
-						// Dragon Scales (Feature 23)
-						if closestPlayer.Weapon == "Dragon Fruit" {
-							damage = damage - 5 // Flat reduction
-							if damage < 0 {
-								damage = 0
-							}
-
-							// Fire Thorns
-							mob.Health -= 5 // Burn attacker
-							if mob.Health < 1 {
-								mob.Health = 1
-							}
-						}
-
-						// Smoke Dodge (Feature 26)
-						if closestPlayer.Weapon == "Smoke Fruit" {
-							if rand.Float64() < 0.5 { // 50% chance to dodge
-								damage = 0
-							}
-						}
-
-						// Chop Sword Immunity (Feature 28)
-						// Assume Marines use Swords
-						if closestPlayer.Weapon == "Chop Fruit" {
-							if damage < 20 {
-								damage = 0
-							}
-						}
-
-						// Bomb Self-Destruct (Feature 25)
-						if closestPlayer.Weapon == "Bomb Fruit" {
-							// Let's do AOE return damage
-							mob.Health -= 20
-							if mob.Health < 1 {
-								mob.Health = 1
-							}
-						}
-
-						// Sand Trap (Feature 30)
-						if closestPlayer.Weapon == "Sand Fruit" {
-							if rand.Float64() < 0.25 {
-								mob.StunEnd = now + 1000 // 1s Stun
-							}
-						}
-					}
-					mm.hub.mutex.Unlock()
-				}
-
-				// Paw Knockback (Feature 14) - Passive Repel
-				mm.hub.mutex.Lock()
-				if closestPlayer.Weapon == "Paw Fruit" {
-					dx := mob.X - closestPlayer.X
-					dz := mob.Z - closestPlayer.Z
-					// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
-					magSq := dx*dx + dz*dz
-					if magSq > 0 && magSq < 4.0*4.0 { // Push if too close
-						mag := math.Sqrt(magSq)
-						dx /= mag
-						dz /= mag
-						mob.X += dx * 5.0 // Knockback
-						mob.Z += dz * 5.0
-					}
+				if mob.attack.Fire(now, mobAttackInterval) {
+					mm.hub.dealDamageUnsafe(&DamageEvent{
+						Mob:    mob,
+						Target: closestPlayer,
+						Base:   int(float64(mob.Damage) * mob.damageMultiplier()),
+						Tags:   []string{TagMelee},
+						Now:    now,
+					})
 				}
-
-				// Dark Black Hole (Feature 29) - Passive Pull
-				if closestPlayer.Weapon == "Dark Fruit" {
-					dx := mob.X - closestPlayer.X
-					dz := mob.Z - closestPlayer.Z
-					// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
-					magSq := dx*dx + dz*dz
-					if magSq > 2.0*2.0 && magSq < 10.0*10.0 { // Pull if within 10 units but not too close
-						mag := math.Sqrt(magSq)
-						dx /= mag
-						dz /= mag
-						mob.X -= dx * 2.0 * deltaTime // Pull towards player
-						mob.Z -= dz * 2.0 * deltaTime
-					}
-				}
-				mm.hub.mutex.Unlock()
-
+				applyKnockback(mob, closestPlayer)
 			}
+			applyPull(mob, closestPlayer, deltaTime)
 		} else {
 			// Wander or Return to Spawn
 			mob.State = StateIdle
+			mob.TargetID = ""
+			if mob.boss != nil && mm.updateBossLocked(mob, nil, now) {
+				continue
+			}
 			dxSpawn := mob.X - mob.spawnX
 			dzSpawn := mob.Z - mob.spawnZ
 			distToSpawnSq := dxSpawn*dxSpawn + dzSpawn*dzSpawn
@@ -7,13 +7,14 @@ import (
 
 func BenchmarkMobUpdate(b *testing.B) {
 	hub := newHub()
-	hub.MobManager = NewMobManager(hub)
+	mm := NewMobManager(hub, defaultRoomID)
 
 	// Spawn 1000 players
 	for i := 0; i < 1000; i++ {
 		id := fmt.Sprintf("player_%d", i)
 		hub.players[id] = &Player{
 			ID:     id,
+			RoomID: defaultRoomID,
 			X:      float64(i % 100),
 			Z:      float64(i / 100),
 			Weapon: "None",
@@ -23,11 +24,11 @@ func BenchmarkMobUpdate(b *testing.B) {
 	// Spawn 1000 mobs
 	for i := 0; i < 1000; i++ {
 		id := fmt.Sprintf("mob_%d", i)
-		hub.MobManager.SpawnMob(id, "Gorilla", float64(i%100), float64(i/100))
+		mm.SpawnMob(id, "Gorilla", float64(i%100), float64(i/100))
 	}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		hub.MobManager.Update(0.05)
+		mm.Update(0.05)
 	}
 }
@@ -6,17 +6,18 @@ import (
 
 func BenchmarkMobManagerUpdate(b *testing.B) {
 	hub := newHub()
-	hub.MobManager = NewMobManager(hub)
+	mm := NewMobManager(hub, defaultRoomID)
 
 	hub.players["player1"] = &Player{
 		ID:     "player1",
+		RoomID: defaultRoomID,
 		X:      0,
 		Y:      0,
 		Z:      0,
 		Health: 100,
 	}
 
-	hub.MobManager.SpawnMob("mob1", "Ice Admiral", 2, 2)
+	mm.SpawnMob("mob1", "Ice Admiral", 2, 2)
 
 	// Start hub background goroutine to drain broadcast
 	go func() {
@@ -26,7 +27,7 @@ func BenchmarkMobManagerUpdate(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		hub.MobManager.Mobs["mob1"].AbilityCD = 0 // Force cast
-		hub.MobManager.Update(0.05)
+		mm.Mobs["mob1"].AbilityCD = 0 // Force cast
+		mm.Update(0.05)
 	}
 }
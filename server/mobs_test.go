@@ -1,13 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"maps"
+	"slices"
 	"testing"
 	"time"
 )
 
 func TestMobManager_Update_DeadMob(t *testing.T) {
 	hub := newHub()
-	mm := NewMobManager(hub)
+	mm := NewMobManager(hub, defaultRoomID)
 
 	mm.SpawnMob("mob1", "Gorilla", 10.0, 10.0)
 	mob := mm.Mobs["mob1"]
@@ -24,7 +27,7 @@ func TestMobManager_Update_DeadMob(t *testing.T) {
 
 func TestMobManager_Update_ReturnToSpawn(t *testing.T) {
 	hub := newHub()
-	mm := NewMobManager(hub)
+	mm := NewMobManager(hub, defaultRoomID)
 
 	mm.SpawnMob("mob1", "Gorilla", 0.0, 0.0)
 	mob := mm.Mobs["mob1"]
@@ -50,7 +53,7 @@ func TestMobManager_Update_ReturnToSpawn(t *testing.T) {
 
 func TestMobManager_Update_ChasePlayer(t *testing.T) {
 	hub := newHub()
-	mm := NewMobManager(hub)
+	mm := NewMobManager(hub, defaultRoomID)
 
 	// Safe zone logic: players in safe zone are ignored.
 	// isSafeZone is distance < 45.0 from (0,0). So place player and mob far away, e.g. (100, 100).
@@ -58,9 +61,10 @@ func TestMobManager_Update_ChasePlayer(t *testing.T) {
 	mob := mm.Mobs["mob1"]
 
 	hub.players["player1"] = &Player{
-		ID: "player1",
-		X:  105.0, // distance 5.0 (within 15.0 detection radius)
-		Z:  100.0,
+		ID:     "player1",
+		RoomID: defaultRoomID,
+		X:      105.0, // distance 5.0 (within 15.0 detection radius)
+		Z:      100.0,
 	}
 
 	mm.Update(1.0)
@@ -78,13 +82,14 @@ func TestMobManager_Update_ChasePlayer(t *testing.T) {
 
 func TestMobManager_Update_AttackState(t *testing.T) {
 	hub := newHub()
-	mm := NewMobManager(hub)
+	mm := NewMobManager(hub, defaultRoomID)
 
 	mm.SpawnMob("mob1", "Gorilla", 100.0, 100.0)
 	mob := mm.Mobs["mob1"]
 
 	hub.players["player1"] = &Player{
 		ID:     "player1",
+		RoomID: defaultRoomID,
 		X:      101.0, // distance 1.0 (<= 1.5 attack radius)
 		Z:      100.0,
 		Health: 100,
@@ -99,15 +104,16 @@ func TestMobManager_Update_AttackState(t *testing.T) {
 
 func TestMobManager_Update_SafeZone(t *testing.T) {
 	hub := newHub()
-	mm := NewMobManager(hub)
+	mm := NewMobManager(hub, defaultRoomID)
 
 	mm.SpawnMob("mob1", "Gorilla", 10.0, 10.0) // inside safe zone
 	mob := mm.Mobs["mob1"]
 
 	hub.players["player1"] = &Player{
-		ID: "player1",
-		X:  12.0, // distance 2.0 (inside detection radius)
-		Z:  10.0,
+		ID:     "player1",
+		RoomID: defaultRoomID,
+		X:      12.0, // distance 2.0 (inside detection radius)
+		Z:      10.0,
 	}
 
 	mm.Update(1.0)
@@ -121,19 +127,133 @@ func TestMobManager_Update_SafeZone(t *testing.T) {
 }
 
 func TestMobManager_Update_StunWakeup(t *testing.T) {
-	hub := newHub()
-	mm := NewMobManager(hub)
+	hub, clock := setupClockTest()
+	mm := NewMobManager(hub, defaultRoomID)
 
 	mm.SpawnMob("mob1", "Gorilla", 100.0, 100.0)
 	mob := mm.Mobs["mob1"]
 
-	mob.State = StateCharmed
-	// Stun ended 1 ms ago
-	mob.StunEnd = time.Now().UnixMilli() - 1
+	mob.Effects.Apply(EffectCharm, 1000, 0, "", hub.now())
+	mm.Update(1.0)
+	if mob.State != StateCharmed {
+		t.Fatalf("Charmed mob state = %s", mob.State)
+	}
 
+	clock.Advance(1000 * time.Millisecond)
 	mm.Update(1.0)
 
 	if mob.State != StateIdle {
 		t.Errorf("Mob should wake up from charm/stun when timer expires, got: %s", mob.State)
 	}
 }
+
+func TestMobManager_Update_IgnoresOtherRooms(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, "private_a")
+
+	mm.SpawnMob("mob1", "Gorilla", 100.0, 100.0)
+	mob := mm.Mobs["mob1"]
+
+	hub.players["player1"] = &Player{
+		ID:     "player1",
+		RoomID: "private_b",
+		X:      105.0,
+		Z:      100.0,
+	}
+
+	mm.Update(1.0)
+
+	if mob.State != StateIdle || mob.TargetID != "" {
+		t.Errorf("Mob should ignore players from another room, got state=%s target=%s", mob.State, mob.TargetID)
+	}
+}
+
+func TestMobManager_Update_CastQueuedForRoom(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+
+	mm.SpawnMob("boss", "Ice Admiral", 100.0, 100.0)
+	mm.DrainMessages() // boss_spawn
+	hub.players["player1"] = &Player{
+		ID:     "player1",
+		RoomID: defaultRoomID,
+		X:      110.0,
+		Z:      100.0,
+		Health: 100,
+	}
+
+	mm.Update(0.05)
+
+	msgs := mm.DrainMessages()
+	if len(msgs) != 1 {
+		t.Fatalf("Expected one queued telegraph message, got %d", len(msgs))
+	}
+	if len(hub.broadcast) != 0 {
+		t.Errorf("Cast should not be sent on the global broadcast channel")
+	}
+	if len(mm.DrainMessages()) != 0 {
+		t.Errorf("DrainMessages should clear the queue")
+	}
+}
+
+// TestMobManager_SeedReplaysSession runs two rooms through the same ticks,
+// fights and loot rolls twice and expects identical worlds.
+func TestMobManager_SeedReplaysSession(t *testing.T) {
+	run := func(seed int64) string {
+		hub, clock := setupClockTest()
+		hub.reseed(seed)
+		players := []*Player{
+			{ID: "dodger", RoomID: defaultRoomID, CurrentFruit: "Smoke Fruit", X: -62, Z: -62},
+			{ID: "sidestep", RoomID: defaultRoomID, CurrentFruit: "Smoke Fruit", X: -61, Z: -62},
+			{ID: "trapper", RoomID: defaultRoomID, CurrentFruit: "Sand Fruit", X: -50, Z: -50},
+			{ID: "elsewhere", RoomID: "private_replay", X: -50, Z: -50},
+		}
+		for _, p := range players {
+			p.Level, p.Health, p.MaxHealth, p.Luck, p.Inventory = 1, 100000, 100000, 1, NewInventory()
+			hub.players[p.ID] = p
+			hub.roomUnsafe(p.RoomID)
+		}
+
+		// Mobs close in and fight, the boss pounds both dodgers at once;
+		// every 2s a mob dies, and later the boss falls to the dodgers so
+		// their loot rolls follow each other
+		for i := 1; i <= 200; i++ {
+			clock.Advance(50 * time.Millisecond)
+			hub.tick(0.05)
+			if i%40 != 0 && i != 150 {
+				continue
+			}
+			hub.mutex.Lock()
+			mm := hub.mobsUnsafe(players[0])
+			for _, id := range slices.Sorted(maps.Keys(mm.Mobs)) {
+				mob := mm.Mobs[id]
+				if mob.State == StateDead || mob.IsBoss != (i == 150) {
+					continue
+				}
+				mob.Contributions[players[0].ID] = 1
+				mob.Contributions[players[1].ID] = 1
+				handleMobDamage(hub, players[0], id, mob.Health, nil)
+				break
+			}
+			hub.mutex.Unlock()
+		}
+
+		hub.mutex.Lock()
+		defer hub.mutex.Unlock()
+		state, _ := json.Marshal(map[string]interface{}{
+			"public":  hub.rooms[defaultRoomID].MobManager.Mobs,
+			"drops":   hub.rooms[defaultRoomID].MobManager.Drops,
+			"private": hub.rooms["private_replay"].MobManager.Mobs,
+			"players": hub.players,
+		})
+		return string(state)
+	}
+
+	first := run(99)
+	if replay := run(99); replay != first {
+		t.Errorf("Seed 99 did not replay:\n%s\n%s", first, replay)
+	}
+	if run(100) == first {
+		t.Error("A different seed should play out differently")
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	maxPartySize       = 4
+	partyInviteTimeout = 30000 // ms an invite stays open
+	partyShareRadius   = 60.0  // Members this close to a kill share it
+	partyExpBonus      = 0.1   // Extra exp per additional member sharing a kill
+)
+
+// Party is a short-lived group of players that share kills. Parties live
+// in memory only and break up when members disconnect.
+type Party struct {
+	ID      string   `json:"id"`
+	Leader  string   `json:"leader"`
+	Members []string `json:"members"` // In join order
+}
+
+// PartyInvite is an open invitation to join a player's party.
+type PartyInvite struct {
+	From      string
+	CreatedAt int64
+}
+
+func partyErrorMsg(reason string) []byte {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":   "party_error",
+		"reason": reason,
+	})
+	return msg
+}
+
+// partyExpMultiplier is the total exp paid out for a kill shared by n
+// members, relative to a solo kill.
+func partyExpMultiplier(n int) float64 {
+	return 1 + partyExpBonus*float64(n-1)
+}
+
+// broadcastPartyUnsafe sends the roster to every member.
+// Caller MUST hold h.mutex.
+func (h *Hub) broadcastPartyUnsafe(party *Party) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":  "party_update",
+		"party": party,
+	})
+	for _, id := range party.Members {
+		h.sendToPlayerUnsafe(id, msg)
+	}
+}
+
+// sendPartyFramesUnsafe sends every party its members' health for the
+// party frame.
+// Caller MUST hold h.mutex.
+func (h *Hub) sendPartyFramesUnsafe() {
+	type frame struct {
+		ID        string  `json:"id"`
+		Health    int     `json:"health"`
+		MaxHealth int     `json:"maxHealth"`
+		Level     int     `json:"level"`
+		X         float64 `json:"x"`
+		Z         float64 `json:"z"`
+	}
+	sent := make(map[*Party]bool)
+	for _, party := range h.parties {
+		if sent[party] {
+			continue
+		}
+		sent[party] = true
+
+		frames := make([]frame, 0, len(party.Members))
+		for _, id := range party.Members {
+			if p, ok := h.players[id]; ok {
+				frames = append(frames, frame{ID: id, Health: p.Health, MaxHealth: p.MaxHealth, Level: p.Level, X: p.X, Z: p.Z})
+			}
+		}
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type":    "party_frame",
+			"members": frames,
+		})
+		for _, id := range party.Members {
+			h.sendToPlayerUnsafe(id, msg)
+		}
+	}
+}
+
+// leavePartyUnsafe removes the player from their party. The next member
+// in join order takes over from a leader, and a party of one breaks up.
+// Caller MUST hold h.mutex.
+func (h *Hub) leavePartyUnsafe(playerID string) {
+	party, ok := h.parties[playerID]
+	if !ok {
+		return
+	}
+	delete(h.parties, playerID)
+	h.sendToPlayerUnsafe(playerID, []byte(`{"type":"party_update","party":null}`))
+
+	for i, id := range party.Members {
+		if id == playerID {
+			party.Members = append(party.Members[:i], party.Members[i+1:]...)
+			break
+		}
+	}
+	if len(party.Members) == 1 {
+		last := party.Members[0]
+		delete(h.parties, last)
+		h.sendToPlayerUnsafe(last, []byte(`{"type":"party_update","party":null}`))
+		return
+	}
+	if party.Leader == playerID {
+		party.Leader = party.Members[0]
+	}
+	h.broadcastPartyUnsafe(party)
+}
+
+// partyNearUnsafe returns the members of p's party, p included, who are in
+// p's room and within partyShareRadius of (x, z). A player without a party
+// gets just themselves.
+// Caller MUST hold h.mutex.
+func (h *Hub) partyNearUnsafe(p *Player, x, z float64) []*Player {
+	party, ok := h.parties[p.ID]
+	if !ok {
+		return []*Player{p}
+	}
+	near := []*Player{p}
+	for _, id := range party.Members {
+		m, ok := h.players[id]
+		if !ok || m == p || m.RoomID != p.RoomID {
+			continue
+		}
+		if distanceSq(m.X, m.Z, x, z) <= partyShareRadius*partyShareRadius {
+			near = append(near, m)
+		}
+	}
+	return near
+}
+
+// handlePartyInput runs the party actions: party_invite (Item = player),
+// party_accept, party_kick (Item = player) and party_leave.
+// Caller MUST hold h.mutex.
+func (h *Hub) handlePartyInput(c *websocket.Conn, player *Player, input InputMessage) {
+	now := h.now()
+	party := h.parties[player.ID]
+	fail := func(reason string) {
+		h.sendToPlayerUnsafe(player.ID, partyErrorMsg(reason))
+	}
+
+	switch input.Type {
+	case "party_invite":
+		target, ok := h.players[input.Item]
+		if !ok || target.ID == player.ID {
+			fail("player not available")
+			return
+		}
+		if party != nil && party.Leader != player.ID {
+			fail("only the party leader can invite")
+			return
+		}
+		if party != nil && len(party.Members) >= maxPartySize {
+			fail("party is full")
+			return
+		}
+		if _, busy := h.parties[target.ID]; busy {
+			fail(target.ID + " is already in a party")
+			return
+		}
+		h.partyInvites[target.ID] = &PartyInvite{From: player.ID, CreatedAt: now}
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type": "party_invite",
+			"from": player.ID,
+		})
+		h.sendToPlayerUnsafe(target.ID, msg)
+
+	case "party_accept":
+		inv, ok := h.partyInvites[player.ID]
+		delete(h.partyInvites, player.ID)
+		if !ok || now-inv.CreatedAt > partyInviteTimeout {
+			fail("no open party invite")
+			return
+		}
+		if party != nil {
+			fail("leave your party first")
+			return
+		}
+		if _, online := h.players[inv.From]; !online {
+			fail("inviter is no longer online")
+			return
+		}
+		host, ok := h.parties[inv.From]
+		if !ok {
+			host = &Party{
+				ID:      fmt.Sprintf("party_%s_%d", inv.From, now),
+				Leader:  inv.From,
+				Members: []string{inv.From},
+			}
+			h.parties[inv.From] = host
+		}
+		if len(host.Members) >= maxPartySize {
+			fail("party is full")
+			return
+		}
+		host.Members = append(host.Members, player.ID)
+		h.parties[player.ID] = host
+		h.broadcastPartyUnsafe(host)
+
+	case "party_kick":
+		if party == nil || party.Leader != player.ID || h.parties[input.Item] != party || input.Item == player.ID {
+			fail("only the party leader can kick members")
+			return
+		}
+		h.leavePartyUnsafe(input.Item)
+
+	case "party_leave":
+		h.leavePartyUnsafe(player.ID)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import "testing"
+
+func setupPartyTest(ids ...string) (*Hub, []*Player) {
+	hub := newHub()
+	var players []*Player
+	for _, id := range ids {
+		p := &Player{ID: id, RoomID: defaultRoomID, Level: 1, Health: 100, MaxHealth: 100, Inventory: NewInventory()}
+		hub.players[id] = p
+		players = append(players, p)
+	}
+	return hub, players
+}
+
+func partyInput(hub *Hub, p *Player, typ, item string) {
+	hub.handlePartyInput(nil, p, InputMessage{Type: typ, Item: item})
+}
+
+func TestParty_InviteAcceptLeave(t *testing.T) {
+	hub, ps := setupPartyTest("a", "b", "c")
+	a, b, c := ps[0], ps[1], ps[2]
+
+	partyInput(hub, b, "party_accept", "")
+	if hub.parties[b.ID] != nil {
+		t.Fatal("Joined without an invite")
+	}
+
+	partyInput(hub, a, "party_invite", "b")
+	partyInput(hub, b, "party_accept", "")
+	party := hub.parties[a.ID]
+	if party == nil || hub.parties[b.ID] != party || party.Leader != "a" {
+		t.Fatalf("Party = %+v", party)
+	}
+
+	// Only the leader invites
+	partyInput(hub, b, "party_invite", "c")
+	partyInput(hub, c, "party_accept", "")
+	if hub.parties[c.ID] != nil {
+		t.Error("Non-leader invited a member")
+	}
+
+	// The leader leaving hands over the party; the last member disbands it
+	partyInput(hub, a, "party_invite", "c")
+	partyInput(hub, c, "party_accept", "")
+	partyInput(hub, a, "party_leave", "")
+	if party.Leader != "b" || hub.parties[a.ID] != nil || len(party.Members) != 2 {
+		t.Fatalf("After leader left: %+v", party)
+	}
+	partyInput(hub, b, "party_leave", "")
+	if len(hub.parties) != 0 {
+		t.Errorf("A party of one should break up, got %v", hub.parties)
+	}
+}
+
+func TestParty_MaxSize(t *testing.T) {
+	hub, ps := setupPartyTest("a", "b", "c", "d", "e")
+	for _, p := range ps[1:] {
+		partyInput(hub, ps[0], "party_invite", p.ID)
+		partyInput(hub, p, "party_accept", "")
+	}
+	if n := len(hub.parties[ps[0].ID].Members); n != maxPartySize {
+		t.Errorf("Party has %d members, want %d", n, maxPartySize)
+	}
+	if hub.parties["e"] != nil {
+		t.Error("Joined a full party")
+	}
+}
+
+func TestParty_SharesKillsNearby(t *testing.T) {
+	hub, ps := setupPartyTest("killer", "helper", "faraway")
+	killer, helper, faraway := ps[0], ps[1], ps[2]
+	faraway.X = partyShareRadius + 50
+	for _, p := range ps[1:] {
+		partyInput(hub, killer, "party_invite", p.ID)
+		partyInput(hub, p, "party_accept", "")
+	}
+
+	for _, p := range ps {
+		p.Quests = []*Quest{{ID: "gorilla_quest", Type: QuestKill, Target: "Gorilla", TargetCount: 5}}
+	}
+
+	mm := hub.mobsUnsafe(killer)
+	mm.Mobs["m1"] = &Mob{ID: "m1", Type: "Gorilla", Health: 10, MaxHealth: 10, Contributions: map[string]int{}, ExpReward: 100, BountyReward: 50}
+	handleMobDamage(hub, killer, "m1", 10, nil)
+
+	// Two members in range split 100 exp with a 10% bonus
+	want := int(100 * partyExpMultiplier(2) / 2)
+	if killer.Exp != want || helper.Exp != want {
+		t.Errorf("Exp = %d/%d, want %d each", killer.Exp, helper.Exp, want)
+	}
+	if faraway.Exp != 0 || faraway.quest("gorilla_quest").Current != 0 {
+		t.Error("Members out of range share nothing")
+	}
+	if killer.quest("gorilla_quest").Current != 1 || helper.quest("gorilla_quest").Current != 1 {
+		t.Error("Nearby members should get quest credit")
+	}
+	if killer.Bounty != 50 || helper.Bounty != 0 {
+		t.Errorf("Bounty = %d/%d; it stays with the killer", killer.Bounty, helper.Bounty)
+	}
+}
+
+func TestParty_SoloKillUnchanged(t *testing.T) {
+	hub, ps := setupPartyTest("solo")
+	solo := ps[0]
+	mm := hub.mobsUnsafe(solo)
+	mm.Mobs["m1"] = &Mob{ID: "m1", Type: "Gorilla", Health: 10, MaxHealth: 10, Contributions: map[string]int{}, ExpReward: 50}
+	handleMobDamage(hub, solo, "m1", 10, nil)
+	if solo.Exp != 50 {
+		t.Errorf("Exp = %d, want the full 50", solo.Exp)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import "math"
+
+// Fruit passive kinds.
+const (
+	PassiveAura      = "aura"      // Damages mobs within Radius every Tick
+	PassiveSlow      = "slow"      // Mobs chasing the player move at Factor speed
+	PassiveStealth   = "stealth"   // Mobs only notice the player within Radius
+	PassiveThorns    = "thorns"    // Contact hits that land cost the mob Amount health
+	PassiveRegen     = "regen"     // Heals Amount every Tick
+	PassiveKnockback = "knockback" // Mobs attacking in melee within Radius are pushed Force units away
+	PassivePull      = "pull"      // Mobs between MinRadius and Radius are pulled in at Force/s
+
+	// Damage pipeline passives, see damage.go
+	PassiveBounce     = "bounce"     // Melee hits from ordinary mobs deal nothing
+	PassiveIntangible = "intangible" // Hits below Amount deal nothing
+	PassiveDodge      = "dodge"      // Hits miss with probability Chance
+	PassiveReduce     = "reduce"     // Hits are scaled by Factor, then Amount is taken off
+	PassiveProc       = "proc"       // Contact hits that land apply Effect to the mob with probability Chance
+)
+
+// FruitPassive is one always-on effect of an eaten fruit.
+type FruitPassive struct {
+	Kind      string
+	Radius    float64
+	MinRadius float64
+	Amount    int         // Damage for aura and thorns, health for regen, threshold or flat cut for intangible and reduce
+	Factor    float64     // Speed multiplier for slow, damage multiplier for reduce
+	Force     float64     // Distance for knockback, speed for pull
+	Tick      int64       // ms between aura and regen applications
+	Chance    float64     // Probability for dodge and proc
+	Effect    *EffectSpec // Applied by proc
+}
+
+// fruitPassives declares the passives each fruit grants to whoever ate it.
+var fruitPassives = map[string][]FruitPassive{
+	"Magma Fruit":   {{Kind: PassiveAura, Radius: 8, Amount: 5, Tick: 500}},
+	"Phoenix Fruit": {{Kind: PassiveRegen, Amount: 5, Tick: 1000}},
+	"Dough Fruit":   {{Kind: PassiveSlow, Factor: 0.5}},
+	"Shadow Fruit":  {{Kind: PassiveStealth, Radius: 5}},
+	"Spike Fruit":   {{Kind: PassiveThorns, Amount: 10}},
+	"Dragon Fruit":  {{Kind: PassiveThorns, Amount: 5}, {Kind: PassiveReduce, Factor: 1, Amount: 5}},
+	"Bomb Fruit":    {{Kind: PassiveThorns, Amount: 20}},
+	"Paw Fruit":     {{Kind: PassiveKnockback, Radius: 4, Force: 5}},
+	"Dark Fruit":    {{Kind: PassivePull, MinRadius: 2, Radius: 10, Force: 2}},
+	"Rubber Fruit":  {{Kind: PassiveBounce}},
+	"Chop Fruit":    {{Kind: PassiveIntangible, Amount: 20}},
+	"Smoke Fruit":   {{Kind: PassiveDodge, Chance: 0.5}},
+	"Diamond Fruit": {{Kind: PassiveReduce, Factor: 0.5}},
+	"Leopard Fruit": {{Kind: PassiveReduce, Factor: 0.5}},
+	"Rumble Fruit":  {{Kind: PassiveProc, Chance: 0.2, Effect: &EffectSpec{Kind: EffectStun, Duration: 2000}}},
+	"Venom Fruit":   {{Kind: PassiveProc, Chance: 1, Effect: &EffectSpec{Kind: EffectPoison, Duration: 5000, Magnitude: 2}}},
+	"String Fruit":  {{Kind: PassiveProc, Chance: 0.4, Effect: &EffectSpec{Kind: EffectStun, Duration: 1500}}},
+	"Sand Fruit":    {{Kind: PassiveProc, Chance: 0.25, Effect: &EffectSpec{Kind: EffectStun, Duration: 1000}}},
+}
+
+// PassiveSet is a fruit's passives by kind, resolved once from
+// fruitPassives so the per-tick lookups are a map read.
+type PassiveSet struct {
+	Aura, Slow, Stealth, Thorns, Regen, Knockback, Pull *FruitPassive
+	Bounce, Intangible, Dodge, Reduce, Proc             *FruitPassive
+}
+
+var (
+	passiveSets   = buildPassiveSets(fruitPassives)
+	noPassives    = &PassiveSet{}
+	passiveFields = map[string]func(s *PassiveSet) **FruitPassive{
+		PassiveAura:       func(s *PassiveSet) **FruitPassive { return &s.Aura },
+		PassiveSlow:       func(s *PassiveSet) **FruitPassive { return &s.Slow },
+		PassiveStealth:    func(s *PassiveSet) **FruitPassive { return &s.Stealth },
+		PassiveThorns:     func(s *PassiveSet) **FruitPassive { return &s.Thorns },
+		PassiveRegen:      func(s *PassiveSet) **FruitPassive { return &s.Regen },
+		PassiveKnockback:  func(s *PassiveSet) **FruitPassive { return &s.Knockback },
+		PassivePull:       func(s *PassiveSet) **FruitPassive { return &s.Pull },
+		PassiveBounce:     func(s *PassiveSet) **FruitPassive { return &s.Bounce },
+		PassiveIntangible: func(s *PassiveSet) **FruitPassive { return &s.Intangible },
+		PassiveDodge:      func(s *PassiveSet) **FruitPassive { return &s.Dodge },
+		PassiveReduce:     func(s *PassiveSet) **FruitPassive { return &s.Reduce },
+		PassiveProc:       func(s *PassiveSet) **FruitPassive { return &s.Proc },
+	}
+)
+
+func buildPassiveSets(defs map[string][]FruitPassive) map[string]*PassiveSet {
+	sets := make(map[string]*PassiveSet, len(defs))
+	for fruit, passives := range defs {
+		set := &PassiveSet{}
+		for i := range passives {
+			if field, ok := passiveFields[passives[i].Kind]; ok {
+				*field(set) = &passives[i]
+			}
+		}
+		sets[fruit] = set
+	}
+	return sets
+}
+
+// passiveSet returns the passives of the player's eaten fruit. The
+// result is shared and must not be modified.
+func (p *Player) passiveSet() *PassiveSet {
+	if set, ok := passiveSets[p.CurrentFruit]; ok {
+		return set
+	}
+	return noPassives
+}
+
+// passiveReady reports whether a ticking passive is due and, if so,
+// schedules its next application.
+func (p *Player) passiveReady(fp *FruitPassive, now int64) bool {
+	if p.passiveTimers == nil {
+		p.passiveTimers = make(map[string]*Timer)
+	}
+	t, ok := p.passiveTimers[fp.Kind]
+	if !ok {
+		t = &Timer{}
+		p.passiveTimers[fp.Kind] = t
+	}
+	return t.Fire(now, fp.Tick)
+}
+
+// applyRegenUnsafe heals every living player with a regen passive.
+// Caller MUST hold h.mutex.
+func (h *Hub) applyRegenUnsafe(now int64) {
+	for _, p := range h.players {
+		regen := p.passiveSet().Regen
+		if regen == nil || p.Dead || p.Health >= p.MaxHealth || !p.passiveReady(regen, now) {
+			continue
+		}
+		p.Health += regen.Amount
+		if p.Health > p.MaxHealth {
+			p.Health = p.MaxHealth
+		}
+	}
+}
+
+// applyAuraLocked damages the mobs around a player with an aura passive.
+// Auras never finish a mob off.
+// Caller MUST hold mm.mutex.
+func (mm *MobManager) applyAuraLocked(p *Player, now int64) {
+	aura := p.passiveSet().Aura
+	if aura == nil || !p.passiveReady(aura, now) {
+		return
+	}
+	radiusSq := aura.Radius * aura.Radius
+	for _, mob := range mm.Mobs {
+		if mob.State != StateDead && distanceSq(mob.X, mob.Z, p.X, p.Z) < radiusSq {
+			hurtMobNonLethal(mob, aura.Amount)
+		}
+	}
+}
+
+// applyKnockback shoves a mob that attacked the player in melee away.
+func applyKnockback(mob *Mob, p *Player) {
+	kb := p.passiveSet().Knockback
+	if kb == nil {
+		return
+	}
+	dx := mob.X - p.X
+	dz := mob.Z - p.Z
+	// ⚡ Bolt Optimization: Replace math.Sqrt with squared distance check
+	magSq := dx*dx + dz*dz
+	if magSq > 0 && magSq < kb.Radius*kb.Radius {
+		mag := math.Sqrt(magSq)
+		mob.X += dx / mag * kb.Force
+		mob.Z += dz / mag * kb.Force
+	}
+}
+
+// applyPull drags a mob chasing or attacking the player closer.
+func applyPull(mob *Mob, p *Player, deltaTime float64) {
+	pull := p.passiveSet().Pull
+	if pull == nil {
+		return
+	}
+	dx := mob.X - p.X
+	dz := mob.Z - p.Z
+	magSq := dx*dx + dz*dz
+	if magSq > pull.MinRadius*pull.MinRadius && magSq < pull.Radius*pull.Radius {
+		mag := math.Sqrt(magSq)
+		mob.X -= dx / mag * pull.Force * deltaTime
+		mob.Z -= dz / mag * pull.Force * deltaTime
+	}
+}
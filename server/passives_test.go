@@ -0,0 +1,129 @@
+package main
+
+import "testing"
+
+func TestFruitPassives_Registry(t *testing.T) {
+	for fruit, passives := range fruitPassives {
+		if !isFruit(fruit) {
+			t.Errorf("%s is not an edible fruit", fruit)
+		}
+		for _, fp := range passives {
+			if _, ok := passiveFields[fp.Kind]; !ok {
+				t.Errorf("%s: unknown passive kind %q", fruit, fp.Kind)
+			}
+			if (fp.Kind == PassiveAura || fp.Kind == PassiveRegen) && fp.Tick <= 0 {
+				t.Errorf("%s: %s needs a tick interval", fruit, fp.Kind)
+			}
+			if fp.Kind == PassiveReduce && fp.Factor <= 0 {
+				t.Errorf("%s: reduce needs a damage factor", fruit)
+			}
+			if fp.Kind == PassiveProc && (fp.Effect == nil || fp.Chance <= 0) {
+				t.Errorf("%s: proc needs an effect and a chance", fruit)
+			} else if fp.Kind == PassiveProc {
+				if _, ok := effectDefs[fp.Effect.Kind]; !ok {
+					t.Errorf("%s: unknown proc effect %q", fruit, fp.Effect.Kind)
+				}
+			}
+		}
+	}
+}
+
+func TestPassives_FromEatenFruitOnly(t *testing.T) {
+	p := &Player{Weapon: "Phoenix Fruit"}
+	if p.passiveSet().Regen != nil {
+		t.Error("Wielding a fruit without eating it should grant nothing")
+	}
+	p.CurrentFruit = "Phoenix Fruit"
+	p.Weapon = "katana"
+	if p.passiveSet().Regen == nil {
+		t.Error("An eaten fruit's passives apply whatever the weapon")
+	}
+}
+
+func TestPassives_RegenTicks(t *testing.T) {
+	hub := newHub()
+	p := &Player{ID: "p", CurrentFruit: "Phoenix Fruit", Health: 50, MaxHealth: 100}
+	hub.players[p.ID] = p
+
+	hub.applyRegenUnsafe(1000)
+	hub.applyRegenUnsafe(1500) // Within the tick interval
+	if p.Health != 55 {
+		t.Fatalf("Health = %d, want 55 after one tick", p.Health)
+	}
+	hub.applyRegenUnsafe(2000)
+	if p.Health != 60 {
+		t.Errorf("Health = %d, want 60 after two ticks", p.Health)
+	}
+
+	p.Dead, p.Health = true, 0
+	hub.applyRegenUnsafe(5000)
+	if p.Health != 0 {
+		t.Error("The dead don't regenerate")
+	}
+}
+
+func TestPassives_AuraIsNonLethal(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	p := &Player{ID: "p", CurrentFruit: "Magma Fruit", X: 100, Z: 100}
+	near := &Mob{ID: "near", Health: 7, X: 104, Z: 100}
+	far := &Mob{ID: "far", Health: 50, X: 120, Z: 100}
+	mm.Mobs[near.ID], mm.Mobs[far.ID] = near, far
+
+	mm.applyAuraLocked(p, 0)
+	if near.Health != 2 || far.Health != 50 {
+		t.Fatalf("Health near=%d far=%d, want 2/50", near.Health, far.Health)
+	}
+	mm.applyAuraLocked(p, 100) // Not ticked yet
+	if near.Health != 2 {
+		t.Error("Aura ticked early")
+	}
+	mm.applyAuraLocked(p, 500)
+	if near.Health != 1 {
+		t.Errorf("Aura should leave the mob on 1, got %d", near.Health)
+	}
+}
+
+func TestPassives_KnockbackAndPull(t *testing.T) {
+	paw := &Player{CurrentFruit: "Paw Fruit", X: 0, Z: 0}
+	mob := &Mob{X: 1, Z: 0}
+	applyKnockback(mob, paw)
+	if mob.X != 6 {
+		t.Errorf("Knocked back to %v, want 6", mob.X)
+	}
+
+	dark := &Player{CurrentFruit: "Dark Fruit", X: 0, Z: 0}
+	mob = &Mob{X: 5, Z: 0}
+	applyPull(mob, dark, 1)
+	if mob.X != 3 {
+		t.Errorf("Pulled to %v, want 3", mob.X)
+	}
+	mob.X = 1.5
+	applyPull(mob, dark, 1)
+	if mob.X != 1.5 {
+		t.Error("Mobs inside MinRadius are left alone")
+	}
+}
+
+func TestPassives_StealthAndSlow(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	mm.SpawnMob("mob1", "Gorilla", 100, 100)
+	mob := mm.Mobs["mob1"]
+	hub.players["p"] = &Player{ID: "p", RoomID: defaultRoomID, CurrentFruit: "Shadow Fruit", Health: 100, MaxHealth: 100, X: 108, Z: 100}
+
+	mm.Update(0.05)
+	if mob.State == StateChase {
+		t.Fatal("Mob noticed a stealthy player outside the stealth radius")
+	}
+
+	hub.players["p"].CurrentFruit = "Dough Fruit"
+	startX := mob.X
+	mm.Update(0.05)
+	if mob.State != StateChase {
+		t.Fatalf("Mob should chase, state %s", mob.State)
+	}
+	if moved, full := mob.X-startX, mob.currentSpeed()*0.05; moved > full*0.5+1e-9 {
+		t.Errorf("Slowed mob moved %v, want at most %v", moved, full*0.5)
+	}
+}
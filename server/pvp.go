@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	pvpToggleCooldown = 30000 // ms between PvP switches
+	combatTagDuration = 15000 // ms a PvP hit keeps both players in combat
+)
+
+// inCombat reports whether the player dealt or took PvP damage recently.
+// Tagged players cannot enter safe zones, and their character stays in
+// the world if they disconnect.
+func (p *Player) inCombat(now int64) bool {
+	return now < p.CombatTagEnd
+}
+
+func (p *Player) tagCombat(now int64) {
+	p.CombatTagEnd = now + combatTagDuration
+}
+
+// setPvP switches the player's PvP flag.
+func (p *Player) setPvP(enabled bool, now int64) error {
+	if p.PvP == enabled {
+		return nil
+	}
+	if !enabled && p.inCombat(now) {
+		return errors.New("can't disable PvP while in combat")
+	}
+	if wait := p.PvPToggledAt + pvpToggleCooldown - now; p.PvPToggledAt > 0 && wait > 0 {
+		return fmt.Errorf("PvP can be switched again in %ds", (wait+999)/1000)
+	}
+	p.PvP = enabled
+	p.PvPToggledAt = now
+	return nil
+}
+
+func pvpStatusMsg(p *Player, reason string) []byte {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":         "pvp_status",
+		"pvp":          p.PvP,
+		"toggledAt":    p.PvPToggledAt,
+		"cooldown":     pvpToggleCooldown,
+		"combatTagEnd": p.CombatTagEnd,
+		"reason":       reason,
+	})
+	return msg
+}
+
+// enteringSafeZone reports whether a move takes the player from outside a
+// safe zone into one.
+func enteringSafeZone(p *Player, x, z float64) bool {
+	return isSafeZone(x, z) && !isSafeZone(p.X, p.Z)
+}
+
+// disconnectUnsafe drops the connection and everything tied to the
+// session. The player is removed and returned for saving, unless they are
+// combat tagged; they then stay in the world until releaseCombatLoggersUnsafe
+// removes them.
+// Caller MUST hold h.mutex.
+func (h *Hub) disconnectUnsafe(conn *websocket.Conn, now int64) (departed map[string]string, samples []LeaderboardSample) {
+	id, ok := h.clients[conn]
+	if !ok {
+		return nil, nil
+	}
+	delete(h.clients, conn)
+
+	// Rebuild copy-on-write slice for zero-allocation broadcasts
+	h.clientConns = make([]*websocket.Conn, 0, len(h.clients))
+	for c := range h.clients {
+		h.clientConns = append(h.clientConns, c)
+	}
+
+	h.cancelTradeUnsafe(id, id+" disconnected")
+	delete(h.crewInvites, id)
+	delete(h.partyInvites, id)
+	h.leavePartyUnsafe(id)
+
+	if p, ok := h.players[id]; ok && p.inCombat(now) {
+		log.Printf("Player disconnected in combat: %s", id)
+		p.combatLogged = true
+		return nil, nil
+	}
+	log.Printf("Player disconnected: %s", id)
+	return h.removePlayersUnsafe(id)
+}
+
+// releaseCombatLoggersUnsafe removes players who disconnected in combat
+// once their tag runs out and returns them for saving. Players who never
+// had a connection yet, like fresh guests, are left alone.
+// Caller MUST hold h.mutex.
+func (h *Hub) releaseCombatLoggersUnsafe(now int64) (departed map[string]string, samples []LeaderboardSample) {
+	var ids []string
+	for id, p := range h.players {
+		if !p.combatLogged {
+			continue
+		}
+		if _, online := h.clientsUnsafe(id); online {
+			p.combatLogged = false // Came back in time
+			continue
+		}
+		if !p.inCombat(now) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return h.removePlayersUnsafe(ids...)
+}
+
+// removePlayersUnsafe drops the players from the world and returns their
+// saved data. Persist it before any re-register is handled so progress
+// since the last periodic save survives a reconnect.
+// Caller MUST hold h.mutex.
+func (h *Hub) removePlayersUnsafe(ids ...string) (departed map[string]string, samples []LeaderboardSample) {
+	departed = make(map[string]string, len(ids))
+	for _, id := range ids {
+		p, ok := h.players[id]
+		if !ok {
+			continue
+		}
+		if data, err := json.Marshal(p); err == nil {
+			departed[id] = string(data)
+			samples = append(samples, leaderboardSample(p))
+		}
+		delete(h.players, id)
+		h.pruneRoomUnsafe(p.RoomID)
+	}
+	return departed, samples
+}
+
+// saveDeparted persists players removed by removePlayersUnsafe. Runs on
+// the hub goroutine, outside h.mutex.
+func (h *Hub) saveDeparted(departed map[string]string, samples []LeaderboardSample) {
+	if len(departed) == 0 {
+		return
+	}
+	if err := SaveUsersBatch(departed); err != nil {
+		log.Printf("Error saving on disconnect: %v", err)
+	} else if err := RecordLeaderboardSamples(samples, h.clock.Now()); err != nil {
+		log.Printf("Error updating leaderboards on disconnect: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+func setupPvPTest() (*Hub, *Player, *Player) {
+	hub := newHub()
+	a := &Player{ID: "a", RoomID: defaultRoomID, Team: "pirate", Level: 1, Health: 100, MaxHealth: 100, X: 100}
+	b := &Player{ID: "b", RoomID: defaultRoomID, Team: "marine", Level: 1, Health: 100, MaxHealth: 100, X: 105}
+	hub.players[a.ID] = a
+	hub.players[b.ID] = b
+	return hub, a, b
+}
+
+func TestPvP_OptIn(t *testing.T) {
+	hub, a, b := setupPvPTest()
+	a.PvP = true
+
+	handlePlayerDamage(hub, a, b.ID, 10, nil)
+	if b.Health != 100 || a.inCombat(time.Now().UnixMilli()) {
+		t.Fatal("A player without PvP took damage")
+	}
+
+	b.PvP = true
+	handlePlayerDamage(hub, a, b.ID, 10, nil)
+	if b.Health == 100 {
+		t.Fatal("Both opted in, expected damage")
+	}
+	if !a.inCombat(time.Now().UnixMilli()) || !b.inCombat(time.Now().UnixMilli()) {
+		t.Error("PvP damage should tag both players")
+	}
+}
+
+func TestPvP_ToggleCooldown(t *testing.T) {
+	p := &Player{}
+	now := int64(1_000_000)
+	if err := p.setPvP(true, now); err != nil || !p.PvP {
+		t.Fatalf("First switch: %v", err)
+	}
+	if err := p.setPvP(false, now+1000); err == nil {
+		t.Error("Switched during the cooldown")
+	}
+	if err := p.setPvP(false, now+pvpToggleCooldown); err != nil || p.PvP {
+		t.Errorf("Switch after the cooldown: %v", err)
+	}
+
+	// Combat blocks opting out even once the cooldown is over
+	p.PvP = true
+	p.tagCombat(now + 2*pvpToggleCooldown)
+	if err := p.setPvP(false, now+2*pvpToggleCooldown+1); err == nil {
+		t.Error("Opted out of PvP while in combat")
+	}
+}
+
+func TestPvP_CombatTagBlocksSafeZone(t *testing.T) {
+	hub, a, _ := setupPvPTest()
+	a.tagCombat(time.Now().UnixMilli())
+	hub.handleInput(nil, a, InputMessage{Type: "move", X: 10, Z: 0})
+	if a.X != 100 {
+		t.Fatalf("Tagged player entered the safe zone (x=%v)", a.X)
+	}
+
+	a.CombatTagEnd = 0
+	hub.handleInput(nil, a, InputMessage{Type: "move", X: 10, Z: 0})
+	if a.X != 10 {
+		t.Errorf("Untagged player should enter the safe zone (x=%v)", a.X)
+	}
+}
+
+func TestPvP_CombatTagKeepsDisconnectedPlayer(t *testing.T) {
+	hub, a, _ := setupPvPTest()
+	now := time.Now().UnixMilli()
+	a.tagCombat(now)
+	connA, connB := &websocket.Conn{}, &websocket.Conn{}
+	hub.clients[connA], hub.clients[connB] = "a", "b"
+	hub.players["guest"] = &Player{ID: "guest", RoomID: defaultRoomID} // Websocket not open yet
+
+	if departed, _ := hub.disconnectUnsafe(connA, now); departed != nil || hub.players["a"] == nil {
+		t.Fatal("Tagged player left the world")
+	}
+	if departed, _ := hub.disconnectUnsafe(connB, now); departed["b"] == "" || hub.players["b"] != nil {
+		t.Error("Untagged player should leave on disconnect")
+	}
+
+	if departed, _ := hub.releaseCombatLoggersUnsafe(now); len(departed) != 0 {
+		t.Errorf("Released %v while still tagged", departed)
+	}
+	departed, _ := hub.releaseCombatLoggersUnsafe(now + combatTagDuration)
+	if _, ok := departed["a"]; !ok || hub.players["a"] != nil {
+		t.Error("Player should leave once the tag runs out")
+	}
+	if hub.players["guest"] == nil {
+		t.Error("A guest released before connecting")
+	}
+}
+
+func TestPvP_KillDoesNotWaitOnBroadcast(t *testing.T) {
+	hub, a, b := setupPvPTest()
+	a.PvP, b.PvP = true, true
+	for len(hub.broadcast) < cap(hub.broadcast) {
+		hub.broadcast <- nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		handlePlayerDamage(hub, a, b.ID, 1000, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("The kill blocked on a full broadcast channel")
+	}
+	if !b.Dead {
+		t.Error("Victim should be dead")
+	}
+}
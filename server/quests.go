@@ -0,0 +1,491 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// questsPath is the quest catalog read at startup and on the
+// "reload_quests" admin action.
+var questsPath = "quests.json"
+
+// defaultQuestDefinitions is the quest catalog compiled into the binary.
+//
+//go:embed quests.json
+var defaultQuestDefinitions []byte
+
+// Quest types
+const (
+	QuestKill    = "kill"    // Kill Count mobs of type Target
+	QuestCollect = "collect" // Hand in Count of item Target
+	QuestReach   = "reach"   // Reach X, Z
+	QuestBoss    = "boss"    // Defeat the boss Target
+	QuestEscort  = "escort"  // Lead Target to X, Z before TimeLimit runs out
+)
+
+const (
+	questNPCRange     = 15.0 // Max distance to the giver when accepting
+	escortSpeed       = 6.0  // units per second
+	escortFollowRange = 3.0  // The escortee stops this close to the player
+	escortLeashRange  = 25.0 // and waits if the player runs further ahead
+
+	maxActiveQuests = 5
+)
+
+// QuestNPC is a quest giver standing in the world.
+type QuestNPC struct {
+	ID   string  `json:"id"`
+	Name string  `json:"name"`
+	X    float64 `json:"x"`
+	Z    float64 `json:"z"`
+}
+
+// QuestDef is one entry of the quest catalog.
+type QuestDef struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Type          string   `json:"type"`
+	Target        string   `json:"target"` // Mob type, item or escortee, depending on Type
+	Count         int      `json:"count"`
+	X             float64  `json:"x"`
+	Z             float64  `json:"z"`
+	Radius        float64  `json:"radius"`
+	TimeLimit     int64    `json:"timeLimit"` // ms, escort only
+	MinLevel      int      `json:"minLevel"`
+	MaxLevel      int      `json:"maxLevel"`
+	Prerequisites []string `json:"prerequisites"`
+	NPC           string   `json:"npc"`
+	Repeatable    bool     `json:"repeatable"`
+	Cooldown      int64    `json:"cooldown"` // ms between completions of a repeatable quest
+	RewardExp     int      `json:"rewardExp"`
+	RewardMoney   int      `json:"rewardMoney"`
+}
+
+func (d *QuestDef) validate() error {
+	switch {
+	case d.ID == "" || d.Name == "":
+		return errors.New("id and name are required")
+	case d.MinLevel < 1 || d.MaxLevel < d.MinLevel:
+		return errors.New("invalid level range")
+	case d.Cooldown < 0 || d.RewardExp < 0 || d.RewardMoney < 0:
+		return errors.New("cooldown and rewards must not be negative")
+	}
+
+	switch d.Type {
+	case QuestKill, QuestBoss:
+		def, ok := mobRegistry.Get(d.Target)
+		if !ok {
+			return fmt.Errorf("unknown mob type %q", d.Target)
+		}
+		if d.Type == QuestBoss && !def.IsBoss {
+			return fmt.Errorf("%q is not a boss", d.Target)
+		}
+		if d.Count <= 0 {
+			return errors.New("count must be positive")
+		}
+	case QuestCollect:
+		if d.Target == "" || d.Count <= 0 {
+			return errors.New("collect quests need a target item and a positive count")
+		}
+	case QuestReach, QuestEscort:
+		if d.Radius <= 0 {
+			return errors.New("radius must be positive")
+		}
+		if d.Type == QuestEscort && (d.Target == "" || d.TimeLimit <= 0 || d.NPC == "") {
+			return errors.New("escort quests need a target, a giver and a positive timeLimit")
+		}
+	default:
+		return fmt.Errorf("unknown quest type %q", d.Type)
+	}
+	return nil
+}
+
+// QuestRegistry holds the quest catalog and its givers.
+type QuestRegistry struct {
+	quests map[string]*QuestDef
+	order  []*QuestDef
+	npcs   map[string]*QuestNPC
+	mu     sync.RWMutex
+}
+
+var questCatalog = newDefaultQuestRegistry()
+
+func newDefaultQuestRegistry() *QuestRegistry {
+	r := &QuestRegistry{}
+	if err := r.Load(defaultQuestDefinitions); err != nil {
+		log.Fatalf("Invalid built-in quests: %v", err)
+	}
+	return r
+}
+
+// Get returns the quest with the given id.
+func (r *QuestRegistry) Get(id string) (*QuestDef, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.quests[id]
+	return d, ok
+}
+
+// NPC returns the quest giver with the given id.
+func (r *QuestRegistry) NPC(id string) (*QuestNPC, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n, ok := r.npcs[id]
+	return n, ok
+}
+
+// All returns the quests in catalog order.
+func (r *QuestRegistry) All() []*QuestDef {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.order
+}
+
+// Load parses and validates a quest catalog. The registry is only replaced
+// if every quest is valid.
+func (r *QuestRegistry) Load(data []byte) error {
+	var file struct {
+		NPCs   []*QuestNPC `json:"npcs"`
+		Quests []*QuestDef `json:"quests"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	npcs := make(map[string]*QuestNPC, len(file.NPCs))
+	for i, n := range file.NPCs {
+		if n.ID == "" {
+			return fmt.Errorf("npc %d: id is required", i)
+		}
+		npcs[n.ID] = n
+	}
+
+	quests := make(map[string]*QuestDef, len(file.Quests))
+	for i, d := range file.Quests {
+		if err := d.validate(); err != nil {
+			return fmt.Errorf("quest %d (%q): %w", i, d.ID, err)
+		}
+		if _, dup := quests[d.ID]; dup {
+			return fmt.Errorf("quest %d: duplicate id %q", i, d.ID)
+		}
+		if _, ok := npcs[d.NPC]; d.NPC != "" && !ok {
+			return fmt.Errorf("quest %q: unknown npc %q", d.ID, d.NPC)
+		}
+		quests[d.ID] = d
+	}
+	for _, d := range file.Quests {
+		for _, pre := range d.Prerequisites {
+			if _, ok := quests[pre]; !ok {
+				return fmt.Errorf("quest %q: unknown prerequisite %q", d.ID, pre)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.quests = quests
+	r.order = file.Quests
+	r.npcs = npcs
+	r.mu.Unlock()
+	return nil
+}
+
+// LoadFile loads the quest catalog from path.
+func (r *QuestRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.Load(data)
+}
+
+// availableTo returns why the player can't take the quest, or nil.
+func (d *QuestDef) availableTo(p *Player, now int64) error {
+	if p.Level < d.MinLevel || p.Level > d.MaxLevel {
+		return fmt.Errorf("requires level %d-%d", d.MinLevel, d.MaxLevel)
+	}
+	if p.quest(d.ID) != nil {
+		return errors.New("quest already active")
+	}
+	for _, pre := range d.Prerequisites {
+		if _, done := p.CompletedQuests[pre]; !done {
+			return errors.New("prerequisites not completed")
+		}
+	}
+	if last, done := p.CompletedQuests[d.ID]; done {
+		if !d.Repeatable {
+			return errors.New("quest already completed")
+		}
+		if now < last+d.Cooldown {
+			return fmt.Errorf("available again in %ds", (last+d.Cooldown-now+999)/1000)
+		}
+	}
+	return nil
+}
+
+// availableQuests lists the quests the player can accept, optionally only
+// those offered by one NPC.
+func availableQuests(p *Player, npcID string, now int64) []*QuestDef {
+	available := make([]*QuestDef, 0)
+	for _, d := range questCatalog.All() {
+		if npcID != "" && d.NPC != npcID {
+			continue
+		}
+		if d.availableTo(p, now) == nil {
+			available = append(available, d)
+		}
+	}
+	return available
+}
+
+// acceptQuest starts a quest from the catalog for the player.
+func acceptQuest(p *Player, id string, now int64) error {
+	d, ok := questCatalog.Get(id)
+	if !ok {
+		return errors.New("unknown quest")
+	}
+	if err := d.availableTo(p, now); err != nil {
+		return err
+	}
+	if len(p.Quests) >= maxActiveQuests {
+		return errors.New("quest log is full")
+	}
+
+	q := &Quest{
+		ID:          d.ID,
+		Name:        d.Name,
+		Type:        d.Type,
+		Target:      d.Target,
+		TargetCount: d.Count,
+		RewardExp:   d.RewardExp,
+		RewardMoney: d.RewardMoney,
+		MinLevel:    d.MinLevel,
+		NPC:         d.NPC,
+		X:           d.X,
+		Z:           d.Z,
+		Radius:      d.Radius,
+	}
+	if d.NPC != "" {
+		npc, _ := questCatalog.NPC(d.NPC)
+		if distanceSq(p.X, p.Z, npc.X, npc.Z) > questNPCRange*questNPCRange {
+			return fmt.Errorf("talk to %s to accept this quest", npc.Name)
+		}
+		if d.Type == QuestEscort {
+			// The escortee starts next to the giver
+			q.EscortX, q.EscortZ = npc.X, npc.Z
+			q.ExpiresAt = now + d.TimeLimit
+		}
+	}
+	if q.TargetCount == 0 {
+		q.TargetCount = 1 // Reach and escort complete in one step
+	}
+	p.Quests = append(p.Quests, q)
+	return nil
+}
+
+// quest returns the player's active quest with the given id.
+func (p *Player) quest(id string) *Quest {
+	for _, q := range p.Quests {
+		if q.ID == id {
+			return q
+		}
+	}
+	return nil
+}
+
+// removeQuest drops a quest from the player's log.
+func (p *Player) removeQuest(q *Quest) {
+	for i, active := range p.Quests {
+		if active == q {
+			p.Quests = append(p.Quests[:i], p.Quests[i+1:]...)
+			return
+		}
+	}
+}
+
+// abandonQuest drops an active quest and its progress. Abandoning doesn't
+// count as a completion, so the quest can be taken again right away.
+func abandonQuest(p *Player, id string) error {
+	q := p.quest(id)
+	if q == nil {
+		return errors.New("quest not active")
+	}
+	p.removeQuest(q)
+	return nil
+}
+
+// migrateLegacyQuest moves the single ActiveQuest of older saves into the
+// quest log.
+func (p *Player) migrateLegacyQuest() {
+	q := p.ActiveQuest
+	if q == nil {
+		return
+	}
+	p.ActiveQuest = nil
+	if q.ID == "" && q.Target == "Gorilla" {
+		q.ID = "gorilla_quest" // The only quest before the catalog
+	}
+	if q.Type == "" {
+		q.Type = QuestKill
+	}
+	if q.ID == "" || p.quest(q.ID) == nil {
+		p.Quests = append(p.Quests, q)
+	}
+}
+
+// completeQuest pays out a quest, removes it from the log and records it.
+func completeQuest(p *Player, q *Quest, now int64, c *websocket.Conn) {
+	p.removeQuest(q)
+	if p.CompletedQuests == nil {
+		p.CompletedQuests = make(map[string]int64)
+	}
+	if q.ID != "" {
+		p.CompletedQuests[q.ID] = now
+	}
+	p.Money += q.RewardMoney
+	if c != nil {
+		c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"Quest Completed!"}`))
+	}
+	grantExp(p, q.RewardExp, c)
+}
+
+func sendQuestUpdate(p *Player, c *websocket.Conn) {
+	if c != nil {
+		c.WriteMessage(websocket.TextMessage, createQuestUpdateMsg(p))
+	}
+}
+
+// questsOfType snapshots the player's quests of one type, so callers can
+// complete them while iterating.
+func (p *Player) questsOfType(types ...string) []*Quest {
+	var out []*Quest
+	for _, q := range p.Quests {
+		for _, t := range types {
+			if q.Type == t {
+				out = append(out, q)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// advanceKillQuest counts a mob kill toward kill and boss quests.
+func advanceKillQuest(p *Player, mob *Mob, now int64, c *websocket.Conn) {
+	changed := false
+	for _, q := range p.questsOfType(QuestKill, QuestBoss) {
+		if q.Target != mob.Type || (q.Type == QuestBoss && !mob.IsBoss) {
+			continue
+		}
+		q.Current++
+		if q.Current >= q.TargetCount {
+			completeQuest(p, q, now, c)
+		}
+		changed = true
+	}
+	if changed {
+		sendQuestUpdate(p, c)
+	}
+}
+
+// checkCollectQuest tracks how many of the items the player holds. Quests
+// with a giver are handed in there with turnInQuest; the rest complete as
+// soon as the player holds enough.
+func checkCollectQuest(p *Player, now int64, c *websocket.Conn) {
+	changed := false
+	for _, q := range p.questsOfType(QuestCollect) {
+		have := p.Inventory.Count(q.Target)
+		if q.NPC == "" && have >= q.TargetCount && p.Inventory.Remove(q.Target, q.TargetCount) == nil {
+			completeQuest(p, q, now, c)
+			changed = true
+		} else if have != q.Current {
+			q.Current = have
+			changed = true
+		}
+	}
+	if changed {
+		sendQuestUpdate(p, c)
+	}
+}
+
+// turnInQuest hands the items of a collect quest to its giver.
+func turnInQuest(p *Player, id string, now int64, c *websocket.Conn) error {
+	q := p.quest(id)
+	if q == nil || q.Type != QuestCollect {
+		return errors.New("no collect quest to turn in")
+	}
+	if npc, ok := questCatalog.NPC(q.NPC); ok && distanceSq(p.X, p.Z, npc.X, npc.Z) > questNPCRange*questNPCRange {
+		return fmt.Errorf("talk to %s to turn this quest in", npc.Name)
+	}
+	if err := p.Inventory.Remove(q.Target, q.TargetCount); err != nil {
+		return fmt.Errorf("bring %d %s", q.TargetCount, q.Target)
+	}
+	completeQuest(p, q, now, c)
+	sendQuestUpdate(p, c)
+	return nil
+}
+
+// checkReachQuest completes reach quests once the player arrives.
+func checkReachQuest(p *Player, now int64, c *websocket.Conn) {
+	changed := false
+	for _, q := range p.questsOfType(QuestReach) {
+		if distanceSq(p.X, p.Z, q.X, q.Z) <= q.Radius*q.Radius {
+			q.Current = q.TargetCount
+			completeQuest(p, q, now, c)
+			changed = true
+		}
+	}
+	if changed {
+		sendQuestUpdate(p, c)
+	}
+}
+
+// updateEscortsUnsafe moves every escortee after its player and resolves
+// escorts that arrived or ran out of time.
+// Caller MUST hold h.mutex.
+func (h *Hub) updateEscortsUnsafe(dt float64, now int64) {
+	for _, p := range h.players {
+		for _, q := range p.questsOfType(QuestEscort) {
+			h.updateEscortUnsafe(p, q, dt, now)
+		}
+	}
+}
+
+// Caller MUST hold h.mutex.
+func (h *Hub) updateEscortUnsafe(p *Player, q *Quest, dt float64, now int64) {
+	c, _ := h.clientsUnsafe(p.ID)
+
+	if now > q.ExpiresAt {
+		p.removeQuest(q)
+		if c != nil {
+			c.WriteMessage(websocket.TextMessage, []byte(`{"type":"notification","msg":"Escort failed: out of time"}`))
+		}
+		sendQuestUpdate(p, c)
+		return
+	}
+
+	distSq := distanceSq(q.EscortX, q.EscortZ, p.X, p.Z)
+	if distSq > escortFollowRange*escortFollowRange && distSq <= escortLeashRange*escortLeashRange {
+		dist := math.Sqrt(distSq)
+		step := escortSpeed * dt
+		if step > dist-escortFollowRange {
+			step = dist - escortFollowRange
+		}
+		q.EscortX += (p.X - q.EscortX) / dist * step
+		q.EscortZ += (p.Z - q.EscortZ) / dist * step
+	}
+
+	if distanceSq(q.EscortX, q.EscortZ, q.X, q.Z) <= q.Radius*q.Radius {
+		q.Current = q.TargetCount
+		completeQuest(p, q, now, c)
+		sendQuestUpdate(p, c)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQuestCatalog_Builtin(t *testing.T) {
+	if len(questCatalog.All()) == 0 {
+		t.Fatal("Built-in catalog is empty")
+	}
+	if d, ok := questCatalog.Get("gorilla_quest"); !ok || d.Type != QuestKill || d.Target != "Gorilla" {
+		t.Errorf("gorilla_quest = %+v", d)
+	}
+}
+
+func TestQuestRegistry_LoadRejectsInvalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown type":   `{"quests":[{"id":"a","name":"A","type":"dance","minLevel":1,"maxLevel":5}]}`,
+		"unknown mob":    `{"quests":[{"id":"a","name":"A","type":"kill","target":"Dragon","count":1,"minLevel":1,"maxLevel":5}]}`,
+		"not a boss":     `{"quests":[{"id":"a","name":"A","type":"boss","target":"Gorilla","count":1,"minLevel":1,"maxLevel":5}]}`,
+		"bad levels":     `{"quests":[{"id":"a","name":"A","type":"reach","radius":5,"minLevel":5,"maxLevel":1}]}`,
+		"unknown npc":    `{"quests":[{"id":"a","name":"A","type":"reach","radius":5,"minLevel":1,"maxLevel":5,"npc":"ghost"}]}`,
+		"unknown prereq": `{"quests":[{"id":"a","name":"A","type":"reach","radius":5,"minLevel":1,"maxLevel":5,"prerequisites":["b"]}]}`,
+	}
+	for name, data := range cases {
+		r := &QuestRegistry{}
+		if err := r.Load([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestQuestAvailability(t *testing.T) {
+	p := &Player{Level: 5, Inventory: NewInventory()}
+
+	ids := func(npc string) string {
+		var out []string
+		for _, d := range availableQuests(p, npc, 0) {
+			out = append(out, d.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	// collect_fur needs gorilla_quest done first
+	if got := ids("jungle_scout"); got != "" {
+		t.Errorf("jungle_scout offers %q before prerequisites", got)
+	}
+	p.CompletedQuests = map[string]int64{"gorilla_quest": 1000}
+	if got := ids("jungle_scout"); got != "collect_fur" {
+		t.Errorf("jungle_scout offers %q, want collect_fur", got)
+	}
+
+	// Repeatable quests wait out their cooldown
+	d, _ := questCatalog.Get("gorilla_quest")
+	if err := d.availableTo(p, 1000+d.Cooldown-1); err == nil {
+		t.Error("gorilla_quest should be on cooldown")
+	}
+	if err := d.availableTo(p, 1000+d.Cooldown); err != nil {
+		t.Errorf("gorilla_quest after cooldown: %v", err)
+	}
+
+	// Once-only quests never come back
+	p.CompletedQuests["scout_jungle"] = 1000
+	scout, _ := questCatalog.Get("scout_jungle")
+	if err := scout.availableTo(p, 1<<40); err == nil {
+		t.Error("scout_jungle is not repeatable")
+	}
+
+	// Level range
+	p.Level = 50
+	if err := d.availableTo(p, 1<<40); err == nil {
+		t.Error("gorilla_quest is capped at its max level")
+	}
+}
+
+func TestAcceptQuest_RequiresGiverInRange(t *testing.T) {
+	p := &Player{Level: 1, X: 100, Z: 100, Inventory: NewInventory()}
+	if err := acceptQuest(p, "gorilla_quest", 0); err == nil {
+		t.Fatal("Accepted a quest far from the giver")
+	}
+	p.X, p.Z = 0, 5
+	if err := acceptQuest(p, "gorilla_quest", 0); err != nil {
+		t.Fatalf("acceptQuest: %v", err)
+	}
+	if onlyQuest(p) == nil || onlyQuest(p).ID != "gorilla_quest" || onlyQuest(p).TargetCount != 5 {
+		t.Errorf("ActiveQuest = %+v", onlyQuest(p))
+	}
+}
+
+func TestKillAndBossQuestProgress(t *testing.T) {
+	p := &Player{Level: 10, Inventory: NewInventory(), CompletedQuests: map[string]int64{"gorilla_quest": 0}}
+	p.X, p.Z = -40, -40
+	if err := acceptQuest(p, "gorilla_king", 1<<40); err != nil {
+		t.Fatalf("acceptQuest: %v", err)
+	}
+
+	// A regular gorilla doesn't count toward the boss
+	advanceKillQuest(p, &Mob{Type: "Gorilla"}, 0, nil)
+	if onlyQuest(p).Current != 0 {
+		t.Fatal("Non-boss kill counted toward a boss quest")
+	}
+
+	money := p.Money
+	advanceKillQuest(p, &Mob{Type: "Gorilla King", IsBoss: true}, 5000, nil)
+	if onlyQuest(p) != nil {
+		t.Fatal("Boss quest should be complete")
+	}
+	if p.Money != money+2000 || p.CompletedQuests["gorilla_king"] != 5000 {
+		t.Errorf("Money %d, completed at %d", p.Money, p.CompletedQuests["gorilla_king"])
+	}
+}
+
+func TestCollectQuest_ConsumesItems(t *testing.T) {
+	p := &Player{Level: 5, X: -40, Z: -40, Inventory: NewInventory(), CompletedQuests: map[string]int64{"gorilla_quest": 0}}
+	p.Inventory.AddQuantity("Gorilla Fur", 2)
+	if err := acceptQuest(p, "collect_fur", 1<<40); err != nil {
+		t.Fatalf("acceptQuest: %v", err)
+	}
+
+	checkCollectQuest(p, 0, nil)
+	if onlyQuest(p) == nil || onlyQuest(p).Current != 2 {
+		t.Fatalf("Quest = %+v, want 2/3", onlyQuest(p))
+	}
+
+	p.Inventory.AddQuantity("Gorilla Fur", 2)
+	checkCollectQuest(p, 0, nil)
+	if q := onlyQuest(p); q == nil || q.Current != 4 {
+		t.Fatalf("Quest = %+v; it completes at the giver, not on pickup", q)
+	}
+
+	p.X, p.Z = 0, 0
+	if err := turnInQuest(p, "collect_fur", 0, nil); err == nil {
+		t.Fatal("Turned in away from the giver")
+	}
+	p.X, p.Z = -40, -40
+	if err := turnInQuest(p, "collect_fur", 0, nil); err != nil {
+		t.Fatalf("turnInQuest: %v", err)
+	}
+	if onlyQuest(p) != nil {
+		t.Fatal("Collect quest should be complete")
+	}
+	if got := p.Inventory.Count("Gorilla Fur"); got != 1 {
+		t.Errorf("Fur left = %d, want 1", got)
+	}
+}
+
+func TestReachQuest(t *testing.T) {
+	p := &Player{Level: 1, Z: 8, Inventory: NewInventory()}
+	if err := acceptQuest(p, "scout_jungle", 0); err != nil {
+		t.Fatalf("acceptQuest: %v", err)
+	}
+	p.X, p.Z = -30, -30
+	checkReachQuest(p, 0, nil)
+	if onlyQuest(p) == nil {
+		t.Fatal("Completed before arriving")
+	}
+	p.X, p.Z = -48, -52
+	checkReachQuest(p, 0, nil)
+	if onlyQuest(p) != nil {
+		t.Error("Reach quest should be complete")
+	}
+}
+
+func TestEscortQuest(t *testing.T) {
+	p := &Player{ID: "p1", Level: 20, X: 45, Z: 40, Inventory: NewInventory(), CompletedQuests: map[string]int64{"bandit_quest": 0}}
+	h := &Hub{players: map[string]*Player{"p1": p}}
+	if err := acceptQuest(p, "escort_merchant", 1<<40); err != nil {
+		t.Fatalf("acceptQuest: %v", err)
+	}
+	if q := onlyQuest(p); q.EscortX != 45 || q.EscortZ != 45 {
+		t.Fatalf("Merchant should start at the giver, got %.1f,%.1f", q.EscortX, q.EscortZ)
+	}
+
+	// Walk toward the destination, waiting for the merchant to catch up
+	now := int64(1 << 40)
+	for i := 0; i < 2000 && onlyQuest(p) != nil; i++ {
+		q := onlyQuest(p)
+		if distanceSq(p.X, p.Z, q.EscortX, q.EscortZ) < 10*10 {
+			p.X += (q.X - p.X) * 0.02
+			p.Z += (q.Z - p.Z) * 0.02
+		}
+		now += 50
+		h.updateEscortsUnsafe(0.05, now)
+	}
+	if onlyQuest(p) != nil {
+		t.Fatalf("Escort not complete: %+v", onlyQuest(p))
+	}
+	if _, done := p.CompletedQuests["escort_merchant"]; !done {
+		t.Error("Escort not recorded as completed")
+	}
+}
+
+func TestEscortQuest_Expires(t *testing.T) {
+	p := &Player{ID: "p1", Level: 20, X: 45, Z: 40, Inventory: NewInventory(), CompletedQuests: map[string]int64{"bandit_quest": 0}}
+	h := &Hub{players: map[string]*Player{"p1": p}}
+	if err := acceptQuest(p, "escort_merchant", 0); err != nil {
+		t.Fatalf("acceptQuest: %v", err)
+	}
+	h.updateEscortsUnsafe(0.05, onlyQuest(p).ExpiresAt+1)
+	if onlyQuest(p) != nil {
+		t.Fatal("Escort should fail after its time limit")
+	}
+	if _, done := p.CompletedQuests["escort_merchant"]; done {
+		t.Error("Failed escort recorded as completed")
+	}
+}
+
+// onlyQuest returns the single quest in the player's log, or nil.
+func onlyQuest(p *Player) *Quest {
+	if len(p.Quests) != 1 {
+		return nil
+	}
+	return p.Quests[0]
+}
+
+func TestQuestLog_Concurrent(t *testing.T) {
+	p := &Player{Level: 5, Z: 8, Inventory: NewInventory()}
+	for _, id := range []string{"gorilla_quest", "scout_jungle"} {
+		if err := acceptQuest(p, id, 0); err != nil {
+			t.Fatalf("accept %s: %v", id, err)
+		}
+	}
+	if err := acceptQuest(p, "gorilla_quest", 0); err == nil {
+		t.Error("Accepted the same quest twice")
+	}
+
+	// A kill only advances the quest that targets it
+	advanceKillQuest(p, &Mob{Type: "Gorilla"}, 0, nil)
+	if p.quest("gorilla_quest").Current != 1 || p.quest("scout_jungle").Current != 0 {
+		t.Errorf("Progress = %d/%d", p.quest("gorilla_quest").Current, p.quest("scout_jungle").Current)
+	}
+
+	// Reaching the jungle completes one without touching the other
+	p.X, p.Z = -50, -50
+	checkReachQuest(p, 0, nil)
+	if len(p.Quests) != 1 || p.quest("gorilla_quest").Current != 1 {
+		t.Errorf("Quests after reach = %+v", p.Quests)
+	}
+}
+
+func TestQuestLog_Full(t *testing.T) {
+	p := &Player{Level: 5, Z: 8, Inventory: NewInventory()}
+	for i := 0; i < maxActiveQuests; i++ {
+		p.Quests = append(p.Quests, &Quest{ID: fmt.Sprintf("filler_%d", i)})
+	}
+	if err := acceptQuest(p, "gorilla_quest", 0); err == nil {
+		t.Error("Accepted a quest into a full log")
+	}
+}
+
+func TestAbandonQuest(t *testing.T) {
+	p := &Player{Level: 5, Z: 8, Inventory: NewInventory()}
+	acceptQuest(p, "scout_jungle", 0)
+
+	if err := abandonQuest(p, "gorilla_quest"); err == nil {
+		t.Error("Abandoned a quest that isn't active")
+	}
+	if err := abandonQuest(p, "scout_jungle"); err != nil {
+		t.Fatalf("abandonQuest: %v", err)
+	}
+	if len(p.Quests) != 0 {
+		t.Fatal("Quest still in the log")
+	}
+	// Abandoning isn't completing, so a once-only quest can be retaken
+	if err := acceptQuest(p, "scout_jungle", 0); err != nil {
+		t.Errorf("Retake after abandon: %v", err)
+	}
+}
+
+func TestQuestLog_SurvivesSaveLoad(t *testing.T) {
+	useTestDB(t)
+	if err := RegisterUser("questlogger", "password123"); err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+	p, err := LoadUser("questlogger")
+	if err != nil {
+		t.Fatalf("LoadUser: %v", err)
+	}
+	p.Z = 8
+	acceptQuest(p, "gorilla_quest", 0)
+	advanceKillQuest(p, &Mob{Type: "Gorilla"}, 0, nil)
+	p.CompletedQuests = map[string]int64{"scout_jungle": 1234}
+	if err := SaveUser(p); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	loaded, err := LoadUser("questlogger")
+	if err != nil {
+		t.Fatalf("LoadUser: %v", err)
+	}
+	if q := loaded.quest("gorilla_quest"); q == nil || q.Current != 1 {
+		t.Errorf("Quest after reload = %+v", q)
+	}
+	if loaded.CompletedQuests["scout_jungle"] != 1234 {
+		t.Errorf("History after reload = %v", loaded.CompletedQuests)
+	}
+}
+
+func TestMigrateLegacyQuest(t *testing.T) {
+	var p Player
+	legacy := `{"activeQuest":{"name":"Defeat Gorillas","target":"Gorilla","targetCount":5,"current":3}}`
+	if err := json.Unmarshal([]byte(legacy), &p); err != nil {
+		t.Fatal(err)
+	}
+	p.applyDefaults()
+
+	if p.ActiveQuest != nil {
+		t.Error("Legacy quest not cleared")
+	}
+	q := p.quest("gorilla_quest")
+	if q == nil || q.Current != 3 || q.Type != QuestKill {
+		t.Fatalf("Migrated quest = %+v", q)
+	}
+}
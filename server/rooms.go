@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"math/rand"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// defaultRoomID is the room used when /ws is opened without ?room=.
+const defaultRoomID = "public_1"
+
+// Room is an isolated world instance. Players sharing a RoomID see each other
+// and fight the same mob population, which is simulated independently of
+// every other room.
+type Room struct {
+	ID         string
+	MobManager *MobManager
+}
+
+// newRoom creates a room populated from the current spawn zones and boss
+// schedule. Mobs appear on the room's first AI tick.
+func newRoom(hub *Hub, id string) *Room {
+	room := &Room{
+		ID:         id,
+		MobManager: NewMobManager(hub, id),
+	}
+	room.MobManager.SetSpawnZones(spawnZones.All())
+	room.MobManager.SetBossSchedule(spawnZones.Bosses())
+	return room
+}
+
+// reseed restarts the hub's and every room's RNG from seed so a run can be
+// replayed with -seed.
+func (h *Hub) reseed(seed int64) {
+	h.seed = seed
+	h.rng = rand.New(rand.NewSource(seed))
+	for id, room := range h.rooms {
+		room.MobManager.rng = h.roomRNG(id)
+	}
+}
+
+// roomRNG returns a room's own RNG, derived from the hub seed and the room
+// ID so rooms don't consume each other's rolls.
+func (h *Hub) roomRNG(roomID string) *rand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(roomID))
+	return rand.New(rand.NewSource(h.seed ^ int64(hash.Sum64())))
+}
+
+// roomUnsafe returns the room with the given ID, creating it on first use.
+// Caller MUST hold h.mutex.
+func (h *Hub) roomUnsafe(id string) *Room {
+	if room, ok := h.rooms[id]; ok {
+		return room
+	}
+	room := newRoom(h, id)
+	h.rooms[id] = room
+	log.Printf("Room created: %s", id)
+	return room
+}
+
+// mobsUnsafe returns the mob manager of the player's room.
+// Caller MUST hold h.mutex.
+func (h *Hub) mobsUnsafe(p *Player) *MobManager {
+	return h.roomUnsafe(p.RoomID).MobManager
+}
+
+// activeRoomsUnsafe returns the rooms that currently have at least one player.
+// Caller MUST hold h.mutex.
+func (h *Hub) activeRoomsUnsafe() []*Room {
+	occupied := make(map[string]bool, len(h.rooms))
+	for _, p := range h.players {
+		occupied[p.RoomID] = true
+	}
+	rooms := make([]*Room, 0, len(occupied))
+	for id, room := range h.rooms {
+		if occupied[id] {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
+
+// pruneRoomUnsafe drops a room once its last player has left so abandoned
+// private rooms do not keep their mobs in memory. The default room is kept.
+// Caller MUST hold h.mutex.
+func (h *Hub) pruneRoomUnsafe(id string) {
+	if id == defaultRoomID {
+		return
+	}
+	for _, p := range h.players {
+		if p.RoomID == id {
+			return
+		}
+	}
+	if _, ok := h.rooms[id]; ok {
+		delete(h.rooms, id)
+		log.Printf("Room closed: %s", id)
+	}
+}
+
+// sendToRoomUnsafe writes msg to every connection whose player is in roomID.
+// Caller MUST hold h.mutex.
+func (h *Hub) sendToRoomUnsafe(roomID string, msg []byte) {
+	for conn, pid := range h.clients {
+		if p, ok := h.players[pid]; ok && p.RoomID == roomID {
+			conn.WriteMessage(websocket.TextMessage, msg)
+		}
+	}
+}
+
+// broadcastRoomMobsUnsafe flushes the room's queued mob events and sends its
+// current mob_update to the players in that room.
+// Caller MUST hold h.mutex.
+func (h *Hub) broadcastRoomMobsUnsafe(room *Room) {
+	for _, msg := range room.MobManager.DrainMessages() {
+		h.sendToRoomUnsafe(room.ID, msg)
+	}
+
+	mobMsg, _ := json.Marshal(map[string]interface{}{
+		"type": "mob_update",
+		"mobs": room.MobManager.Snapshot(),
+	})
+	h.sendToRoomUnsafe(room.ID, mobMsg)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// spawnZonesPath is the spawn zone file read at startup and on the
+// "reload_mobs" admin action.
+var spawnZonesPath = "spawn_zones.json"
+
+// defaultSpawnZoneDefinitions is the spawn zone file compiled into the binary.
+//
+//go:embed spawn_zones.json
+var defaultSpawnZoneDefinitions []byte
+
+// dayDuration is the length of a full in-game day, matching the client's
+// DayNightCycle.
+const dayDuration = 120 * time.Second
+
+// gameHour returns the in-game hour of day in [0, 24).
+func gameHour(t time.Time) float64 {
+	elapsed := t.UnixMilli() % dayDuration.Milliseconds()
+	return float64(elapsed) / float64(dayDuration.Milliseconds()) * 24.0
+}
+
+// SpawnZone is an area that the room's spawner keeps populated with mobs of
+// a single type.
+type SpawnZone struct {
+	Name         string    `json:"name"`
+	MobType      string    `json:"mobType"`
+	CenterX      float64   `json:"centerX"`
+	CenterZ      float64   `json:"centerZ"`
+	Radius       float64   `json:"radius"`
+	MaxAlive     int       `json:"maxAlive"`
+	RespawnDelay int64     `json:"respawnDelay"`          // ms between a death and its replacement
+	ActiveHours  []float64 `json:"activeHours,omitempty"` // Optional [from, to) game hours; may wrap midnight
+}
+
+// ActiveAt reports whether the zone spawns mobs at the given game hour.
+func (z *SpawnZone) ActiveAt(hour float64) bool {
+	if len(z.ActiveHours) == 0 {
+		return true
+	}
+	from, to := z.ActiveHours[0], z.ActiveHours[1]
+	if from <= to {
+		return hour >= from && hour < to
+	}
+	return hour >= from || hour < to // Window wraps midnight
+}
+
+func (z *SpawnZone) validate() error {
+	switch {
+	case z.Name == "":
+		return errors.New("name is required")
+	case z.Radius <= 0:
+		return errors.New("radius must be positive")
+	case z.MaxAlive <= 0:
+		return errors.New("maxAlive must be positive")
+	case z.RespawnDelay < 0:
+		return errors.New("respawnDelay must not be negative")
+	}
+	if _, ok := mobRegistry.Get(z.MobType); !ok {
+		return fmt.Errorf("unknown mob type %q", z.MobType)
+	}
+	if len(z.ActiveHours) != 0 {
+		if len(z.ActiveHours) != 2 {
+			return errors.New("activeHours must be [from, to]")
+		}
+		for _, h := range z.ActiveHours {
+			if h < 0 || h >= 24 {
+				return errors.New("activeHours must be within [0, 24)")
+			}
+		}
+	}
+	return nil
+}
+
+// SpawnZoneRegistry holds the spawn zones and scheduled bosses every room is
+// populated from.
+type SpawnZoneRegistry struct {
+	zones  []*SpawnZone
+	bosses []*BossSpawn
+	mu     sync.RWMutex
+}
+
+var spawnZones = newDefaultSpawnZoneRegistry()
+
+func newDefaultSpawnZoneRegistry() *SpawnZoneRegistry {
+	r := &SpawnZoneRegistry{}
+	if err := r.Load(defaultSpawnZoneDefinitions); err != nil {
+		log.Fatalf("Invalid built-in spawn zones: %v", err)
+	}
+	return r
+}
+
+// All returns the current spawn zones.
+func (r *SpawnZoneRegistry) All() []*SpawnZone {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.zones
+}
+
+// Bosses returns the current boss schedule.
+func (r *SpawnZoneRegistry) Bosses() []*BossSpawn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.bosses
+}
+
+// Load parses and validates a spawn zone file against the mob registry. The
+// registry is only replaced if every zone is valid.
+func (r *SpawnZoneRegistry) Load(data []byte) error {
+	var file struct {
+		Zones  []*SpawnZone `json:"zones"`
+		Bosses []*BossSpawn `json:"bosses"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(file.Zones))
+	for i, z := range file.Zones {
+		if err := z.validate(); err != nil {
+			return fmt.Errorf("zone %d (%q): %w", i, z.Name, err)
+		}
+		if seen[z.Name] {
+			return fmt.Errorf("zone %d: duplicate name %q", i, z.Name)
+		}
+		seen[z.Name] = true
+	}
+	for i, b := range file.Bosses {
+		if err := b.validate(); err != nil {
+			return fmt.Errorf("boss %d: %w", i, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.zones = file.Zones
+	r.bosses = file.Bosses
+	r.mu.Unlock()
+	return nil
+}
+
+// LoadFile loads spawn zones from path.
+func (r *SpawnZoneRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.Load(data)
+}
+
+// reloadMobData reloads the mob definitions and spawn zones together. Zones
+// are validated against the new mobs; if either file is invalid neither is
+// replaced.
+func reloadMobData(mobsPath, zonesPath string) error {
+	data, err := os.ReadFile(mobsPath)
+	if err != nil {
+		return err
+	}
+	r := &MobRegistry{}
+	if err := r.Load(data); err != nil {
+		return err
+	}
+	previous := mobRegistry.swap(r.types)
+	if err := spawnZones.LoadFile(zonesPath); err != nil {
+		mobRegistry.swap(previous)
+		return err
+	}
+	return nil
+}
+
+// zoneSpawner tracks the population of one spawn zone within a room.
+type zoneSpawner struct {
+	zone      *SpawnZone
+	alive     map[string]struct{} // Mob IDs currently owned by the zone
+	respawnAt []int64             // Pending respawn times (ms)
+}
+
+// SetSpawnZones replaces the zones this room is populated from. Spawners of
+// zones that keep their name also keep their mobs and pending respawns.
+func (mm *MobManager) SetSpawnZones(zones []*SpawnZone) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	previous := make(map[string]*zoneSpawner, len(mm.spawners))
+	for _, s := range mm.spawners {
+		previous[s.zone.Name] = s
+	}
+
+	mm.spawners = make([]*zoneSpawner, 0, len(zones))
+	for _, z := range zones {
+		s, ok := previous[z.Name]
+		if !ok {
+			s = &zoneSpawner{alive: make(map[string]struct{})}
+		}
+		s.zone = z
+		mm.spawners = append(mm.spawners, s)
+	}
+}
+
+// updateSpawnersLocked removes dead mobs and keeps every zone at its
+// population target.
+// Caller MUST hold mm.mutex and mm.hub.mutex.
+func (mm *MobManager) updateSpawnersLocked(now int64) {
+	owner := make(map[string]*zoneSpawner)
+	for _, s := range mm.spawners {
+		for id := range s.alive {
+			owner[id] = s
+		}
+	}
+
+	// Reap the dead and schedule their replacements
+	for id, mob := range mm.Mobs {
+		if mob.State != StateDead {
+			continue
+		}
+		delete(mm.Mobs, id)
+		if s, ok := owner[id]; ok {
+			delete(s.alive, id)
+			s.respawnAt = append(s.respawnAt, now+s.zone.RespawnDelay)
+		}
+	}
+
+	hour := gameHour(time.UnixMilli(now))
+	for _, s := range mm.spawners {
+		if !s.zone.ActiveAt(hour) {
+			// Out of its time window: idle mobs leave, nothing new spawns
+			for id := range s.alive {
+				if mob, ok := mm.Mobs[id]; !ok || mob.State == StateIdle {
+					delete(mm.Mobs, id)
+					delete(s.alive, id)
+				}
+			}
+			s.respawnAt = s.respawnAt[:0]
+			continue
+		}
+
+		pending := s.respawnAt[:0]
+		for _, at := range s.respawnAt {
+			if at > now {
+				pending = append(pending, at)
+			}
+		}
+		s.respawnAt = pending
+
+		for len(s.alive)+len(s.respawnAt) < s.zone.MaxAlive {
+			// Uniform point inside the zone circle
+			angle := mm.rng.Float64() * 2 * math.Pi
+			r := s.zone.Radius * math.Sqrt(mm.rng.Float64())
+			x := s.zone.CenterX + r*math.Cos(angle)
+			z := s.zone.CenterZ + r*math.Sin(angle)
+
+			mob, err := mm.spawnMobLocked(mm.newMobIDLocked(), s.zone.MobType, x, z)
+			if err != nil {
+				log.Printf("Zone %s: %v", s.zone.Name, err)
+				break
+			}
+			s.alive[mob.ID] = struct{}{}
+		}
+	}
+}
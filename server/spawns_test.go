@@ -0,0 +1,161 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testZone() *SpawnZone {
+	return &SpawnZone{
+		Name:         "Test Zone",
+		MobType:      "Gorilla",
+		CenterX:      100,
+		CenterZ:      100,
+		Radius:       10,
+		MaxAlive:     3,
+		RespawnDelay: 5000,
+	}
+}
+
+func TestSpawnZone_ActiveAt(t *testing.T) {
+	tests := []struct {
+		name   string
+		hours  []float64
+		hour   float64
+		active bool
+	}{
+		{"no_window", nil, 3, true},
+		{"inside_day_window", []float64{6, 18}, 12, true},
+		{"outside_day_window", []float64{6, 18}, 20, false},
+		{"night_before_midnight", []float64{18, 6}, 22, true},
+		{"night_after_midnight", []float64{18, 6}, 2, true},
+		{"night_window_at_noon", []float64{18, 6}, 12, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := &SpawnZone{ActiveHours: tt.hours}
+			if got := z.ActiveAt(tt.hour); got != tt.active {
+				t.Errorf("ActiveAt(%v) with window %v = %v, expected %v", tt.hour, tt.hours, got, tt.active)
+			}
+		})
+	}
+}
+
+func TestSpawnZoneRegistry_RejectsUnknownMobType(t *testing.T) {
+	r := &SpawnZoneRegistry{}
+	err := r.Load([]byte(`{"zones":[{"name":"Sea","mobType":"Sea King","radius":5,"maxAlive":1}]}`))
+	if err == nil {
+		t.Fatal("expected error for zone with unknown mob type")
+	}
+}
+
+func TestSpawner_FillsZone(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	zone := testZone()
+	mm.SetSpawnZones([]*SpawnZone{zone})
+
+	mm.updateSpawnersLocked(time.Now().UnixMilli())
+
+	if len(mm.Mobs) != zone.MaxAlive {
+		t.Fatalf("expected %d mobs, got %d", zone.MaxAlive, len(mm.Mobs))
+	}
+	for _, mob := range mm.Mobs {
+		if distance(mob.X, mob.Z, zone.CenterX, zone.CenterZ) > zone.Radius {
+			t.Errorf("mob spawned outside zone at (%f, %f)", mob.X, mob.Z)
+		}
+	}
+}
+
+func TestSpawner_RespawnsAfterDelay(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	zone := testZone()
+	mm.SetSpawnZones([]*SpawnZone{zone})
+
+	now := time.Now().UnixMilli()
+	mm.updateSpawnersLocked(now)
+
+	var killed string
+	for id, mob := range mm.Mobs {
+		mob.State = StateDead
+		killed = id
+		break
+	}
+
+	mm.updateSpawnersLocked(now + 1000)
+	if _, ok := mm.Mobs[killed]; ok {
+		t.Errorf("dead mob should be removed")
+	}
+	if len(mm.Mobs) != zone.MaxAlive-1 {
+		t.Errorf("replacement should wait for the respawn delay, got %d mobs", len(mm.Mobs))
+	}
+
+	mm.updateSpawnersLocked(now + 1000 + zone.RespawnDelay)
+	if len(mm.Mobs) != zone.MaxAlive {
+		t.Errorf("expected zone back at %d mobs, got %d", zone.MaxAlive, len(mm.Mobs))
+	}
+}
+
+func TestSpawner_DespawnsOutsideWindow(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	zone := testZone()
+	mm.SetSpawnZones([]*SpawnZone{zone})
+
+	now := time.Now().UnixMilli()
+	mm.updateSpawnersLocked(now)
+
+	// Close the window around the current hour
+	hour := gameHour(time.UnixMilli(now))
+	from := math.Mod(math.Floor(hour)+1, 24)
+	zone.ActiveHours = []float64{from, math.Mod(from+1, 24)}
+	mm.updateSpawnersLocked(now)
+
+	if len(mm.Mobs) != 0 {
+		t.Errorf("idle mobs should leave when the zone is inactive, got %d", len(mm.Mobs))
+	}
+}
+
+func TestSpawner_SameSeedSamePositions(t *testing.T) {
+	spawn := func(seed int64) map[string][2]float64 {
+		hub := newHub()
+		hub.reseed(seed)
+		mm := NewMobManager(hub, defaultRoomID)
+		mm.SetSpawnZones([]*SpawnZone{testZone()})
+		mm.updateSpawnersLocked(0)
+		pos := make(map[string][2]float64)
+		for id, mob := range mm.Mobs {
+			pos[id] = [2]float64{mob.X, mob.Z}
+		}
+		return pos
+	}
+	a, b := spawn(7), spawn(7)
+	if len(a) == 0 || !reflect.DeepEqual(a, b) {
+		t.Errorf("Seed 7 spawned %v, then %v", a, b)
+	}
+}
+
+func TestReloadMobData_BadZonesKeepsMobs(t *testing.T) {
+	dir := t.TempDir()
+	mobsPath := filepath.Join(dir, "mobs.json")
+	zonesPath := filepath.Join(dir, "zones.json")
+	os.WriteFile(mobsPath, []byte(`{"mobs":[{"name":"Bandit","maxHealth":50,"speed":2,"detectionRadius":10,"leashRange":30}]}`), 0644)
+	// Valid zones that reference a type the new mob file dropped
+	os.WriteFile(zonesPath, []byte(`{"zones":[{"name":"Jungle","mobType":"Gorilla","radius":5,"maxAlive":1}]}`), 0644)
+
+	if err := reloadMobData(mobsPath, zonesPath); err == nil {
+		t.Fatal("Zones referencing a removed mob type should fail the reload")
+	}
+	if _, ok := mobRegistry.Get("Gorilla"); !ok {
+		t.Error("A failed reload must keep the previous mob definitions")
+	}
+	if _, ok := mobRegistry.Get("Bandit"); ok {
+		t.Error("The new mob definitions must not go live on their own")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Stat names used by allocate_stat
+const (
+	StatMelee   = "melee"
+	StatDefense = "defense"
+	StatSword   = "sword"
+	StatGun     = "gun"
+	StatFruit   = "fruit"
+)
+
+const (
+	statDamagePerPoint  = 0.02 // +2% damage per point in the attack's stat
+	statDefensePerPoint = 2    // Each point is worth 2% extra effective health
+)
+
+// PlayerStats are the points a player has put into each stat.
+type PlayerStats struct {
+	Melee   int `json:"melee"`
+	Defense int `json:"defense"`
+	Sword   int `json:"sword"`
+	Gun     int `json:"gun"`
+	Fruit   int `json:"fruit"`
+}
+
+func (s *PlayerStats) field(stat string) *int {
+	switch stat {
+	case StatMelee:
+		return &s.Melee
+	case StatDefense:
+		return &s.Defense
+	case StatSword:
+		return &s.Sword
+	case StatGun:
+		return &s.Gun
+	case StatFruit:
+		return &s.Fruit
+	}
+	return nil
+}
+
+// allocateStat spends unspent stat points on a stat.
+func (p *Player) allocateStat(stat string, points int) error {
+	f := p.Stats.field(strings.ToLower(stat))
+	if f == nil {
+		return errors.New("unknown stat")
+	}
+	if points <= 0 || points > p.StatPoints {
+		return errors.New("not enough stat points")
+	}
+	*f += points
+	p.StatPoints -= points
+	return nil
+}
+
+// weaponStat returns the stat that scales a weapon's damage.
+func weaponStat(weapon string) string {
+	switch weapon {
+	case "katana", "cutlass", "pipe":
+		return StatSword
+	case "bazooka", "slingshot":
+		return StatGun
+	}
+	if isFruit(weapon) {
+		return StatFruit
+	}
+	return StatMelee
+}
+
+// scaleDamage applies the player's points in stat to outgoing damage.
+func (p *Player) scaleDamage(damage int, stat string) int {
+	f := p.Stats.field(stat)
+	if f == nil {
+		return damage
+	}
+	return int(float64(damage) * (1 + float64(*f)*statDamagePerPoint))
+}
+
+// mitigateDamage applies the player's defense to incoming damage. A hit
+// that does damage always does at least 1.
+func (p *Player) mitigateDamage(damage int) int {
+	if damage <= 0 {
+		return damage
+	}
+	reduced := damage * 100 / (100 + p.Stats.Defense*statDefensePerPoint)
+	if reduced < 1 {
+		reduced = 1
+	}
+	return reduced
+}
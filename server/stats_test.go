@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestAllocateStat(t *testing.T) {
+	p := &Player{StatPoints: 3}
+
+	if err := p.allocateStat("Sword", 2); err != nil {
+		t.Fatalf("allocateStat: %v", err)
+	}
+	if p.Stats.Sword != 2 || p.StatPoints != 1 {
+		t.Errorf("Sword = %d, points = %d", p.Stats.Sword, p.StatPoints)
+	}
+	if err := p.allocateStat(StatMelee, 2); err == nil {
+		t.Error("Should not spend more points than available")
+	}
+	if err := p.allocateStat("luck", 1); err == nil {
+		t.Error("Unknown stat should be rejected")
+	}
+	if err := p.allocateStat(StatMelee, -1); err == nil {
+		t.Error("Negative points should be rejected")
+	}
+}
+
+func TestScaleDamage(t *testing.T) {
+	p := &Player{Stats: PlayerStats{Sword: 50, Fruit: 10}}
+
+	if got := p.scaleDamage(100, weaponStat("katana")); got != 200 {
+		t.Errorf("katana damage = %d, want 200", got)
+	}
+	if got := p.scaleDamage(100, StatFruit); got != 120 {
+		t.Errorf("fruit damage = %d, want 120", got)
+	}
+	if got := p.scaleDamage(100, weaponStat("melee")); got != 100 {
+		t.Errorf("melee damage = %d, want 100 with no melee points", got)
+	}
+}
+
+func TestMitigateDamage(t *testing.T) {
+	p := &Player{}
+	if got := p.mitigateDamage(50); got != 50 {
+		t.Errorf("No defense: got %d, want 50", got)
+	}
+	p.Stats.Defense = 50
+	if got := p.mitigateDamage(50); got != 25 {
+		t.Errorf("50 defense: got %d, want 25", got)
+	}
+	p.Stats.Defense = 10000
+	if got := p.mitigateDamage(5); got != 1 {
+		t.Errorf("Hits should deal at least 1, got %d", got)
+	}
+}
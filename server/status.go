@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// Status effect kinds, shared by mobs and players.
+const (
+	EffectStun       = "stun"
+	EffectSlow       = "slow"
+	EffectPoison     = "poison"
+	EffectBurn       = "burn"
+	EffectCharm      = "charm"
+	EffectFreeze     = "freeze"
+	EffectShield     = "shield"
+	EffectDamageBuff = "damage_buff"
+)
+
+// How reapplying an active effect behaves.
+const (
+	StackRefresh = "refresh" // Restart the duration, keep the stronger magnitude
+	StackAdd     = "stack"   // Add a stack up to MaxStacks and restart the duration
+	StackIgnore  = "ignore"  // No effect until the current one ends
+)
+
+// EffectDef holds the rules for one kind of status effect.
+type EffectDef struct {
+	Stacking  string
+	MaxStacks int
+	Tick      int64 // ms between damage ticks (poison, burn)
+	Immunity  int64 // ms the target is immune once the effect ends
+	Disables  bool  // The target can't move or act
+	LowerWins bool  // A lower magnitude is the stronger effect (slow)
+}
+
+// Magnitude means, per kind: damage per tick and stack (poison, burn),
+// speed multiplier (slow), health absorbed (shield) and extra damage
+// dealt (damage_buff, 0.25 = +25%).
+var effectDefs = map[string]EffectDef{
+	EffectStun:       {Stacking: StackIgnore, Immunity: 3000, Disables: true},
+	EffectFreeze:     {Stacking: StackIgnore, Immunity: 3000, Disables: true},
+	EffectCharm:      {Stacking: StackIgnore, Immunity: 5000, Disables: true},
+	EffectSlow:       {Stacking: StackRefresh, LowerWins: true},
+	EffectPoison:     {Stacking: StackAdd, MaxStacks: 5, Tick: 500},
+	EffectBurn:       {Stacking: StackRefresh, Tick: 500},
+	EffectShield:     {Stacking: StackRefresh},
+	EffectDamageBuff: {Stacking: StackRefresh},
+}
+
+// stronger reports whether magnitude a beats b.
+func (d EffectDef) stronger(a, b float64) bool {
+	if d.LowerWins {
+		return a < b
+	}
+	return a > b
+}
+
+// StatusEffect is one active effect.
+type StatusEffect struct {
+	Kind      string  `json:"kind"`
+	Source    string  `json:"source,omitempty"` // Who applied it, for death messages
+	Stacks    int     `json:"stacks"`
+	Magnitude float64 `json:"magnitude,omitempty"`
+	ExpiresAt int64   `json:"expiresAt"` // ms
+	nextTick  int64
+}
+
+// StatusEffects is the status effect component of a mob or player. The
+// zero value is ready to use. It serializes as the list of active effects
+// so clients can show icons.
+type StatusEffects struct {
+	active      map[string]*StatusEffect
+	immuneUntil map[string]int64
+}
+
+// Apply adds an effect lasting duration ms. It returns false if the kind
+// is unknown, the target is immune, or an active effect ignores reapplying.
+func (s *StatusEffects) Apply(kind string, duration int64, magnitude float64, source string, now int64) bool {
+	def, ok := effectDefs[kind]
+	if !ok || duration <= 0 || now < s.immuneUntil[kind] {
+		return false
+	}
+	if s.active == nil {
+		s.active = make(map[string]*StatusEffect)
+	}
+	e, active := s.active[kind]
+	if active && now < e.ExpiresAt {
+		switch def.Stacking {
+		case StackIgnore:
+			return false
+		case StackAdd:
+			if e.Stacks < def.MaxStacks {
+				e.Stacks++
+			}
+		}
+		if def.stronger(magnitude, e.Magnitude) {
+			e.Magnitude = magnitude
+		}
+		e.ExpiresAt = now + duration
+		e.Source = source
+		return true
+	}
+	s.active[kind] = &StatusEffect{
+		Kind:      kind,
+		Source:    source,
+		Stacks:    1,
+		Magnitude: magnitude,
+		ExpiresAt: now + duration,
+		nextTick:  now + def.Tick,
+	}
+	return true
+}
+
+// Get returns the active effect of a kind, or nil.
+func (s *StatusEffects) Get(kind string, now int64) *StatusEffect {
+	if e, ok := s.active[kind]; ok && now < e.ExpiresAt {
+		return e
+	}
+	return nil
+}
+
+func (s *StatusEffects) Has(kind string, now int64) bool {
+	return s.Get(kind, now) != nil
+}
+
+// Remove ends an effect early. Its immunity window still applies.
+func (s *StatusEffects) Remove(kind string, now int64) {
+	if _, ok := s.active[kind]; !ok {
+		return
+	}
+	delete(s.active, kind)
+	s.startImmunity(kind, now)
+}
+
+func (s *StatusEffects) startImmunity(kind string, now int64) {
+	if immunity := effectDefs[kind].Immunity; immunity > 0 {
+		if s.immuneUntil == nil {
+			s.immuneUntil = make(map[string]int64)
+		}
+		s.immuneUntil[kind] = now + immunity
+	}
+}
+
+// Update expires effects and returns the damage over time due this tick,
+// and who applied the effect that dealt the most of it.
+func (s *StatusEffects) Update(now int64) (damage int, source string) {
+	top := 0
+	for kind, e := range s.active {
+		def := effectDefs[kind]
+		if def.Tick > 0 {
+			// Ticks that fell due before the effect expired still land
+			for e.nextTick <= now && e.nextTick <= e.ExpiresAt {
+				d := int(e.Magnitude) * e.Stacks
+				damage += d
+				if d > top {
+					top, source = d, e.Source
+				}
+				e.nextTick += def.Tick
+			}
+		}
+		if now >= e.ExpiresAt {
+			delete(s.active, kind)
+			s.startImmunity(kind, e.ExpiresAt)
+		}
+	}
+	return damage, source
+}
+
+// Disabled reports whether a stun, freeze or charm is active.
+func (s *StatusEffects) Disabled(now int64) bool {
+	for kind, e := range s.active {
+		if effectDefs[kind].Disables && now < e.ExpiresAt {
+			return true
+		}
+	}
+	return false
+}
+
+// SpeedFactor is the movement speed multiplier from slows.
+func (s *StatusEffects) SpeedFactor(now int64) float64 {
+	if e := s.Get(EffectSlow, now); e != nil {
+		return e.Magnitude
+	}
+	return 1
+}
+
+// DamageFactor is the outgoing damage multiplier from damage buffs.
+func (s *StatusEffects) DamageFactor(now int64) float64 {
+	if e := s.Get(EffectDamageBuff, now); e != nil {
+		return 1 + e.Magnitude
+	}
+	return 1
+}
+
+// AbsorbShield spends the shield on incoming damage and returns what gets
+// through. A spent shield breaks.
+func (s *StatusEffects) AbsorbShield(damage int, now int64) int {
+	e := s.Get(EffectShield, now)
+	if e == nil || damage <= 0 {
+		return damage
+	}
+	absorbed := float64(damage)
+	if absorbed > e.Magnitude {
+		absorbed = e.Magnitude
+	}
+	e.Magnitude -= absorbed
+	if e.Magnitude <= 0 {
+		s.Remove(EffectShield, now)
+	}
+	return damage - int(absorbed)
+}
+
+// List returns the active effects sorted by kind.
+func (s *StatusEffects) List() []StatusEffect {
+	list := make([]StatusEffect, 0, len(s.active))
+	for _, e := range s.active {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Kind < list[j].Kind })
+	return list
+}
+
+func (s StatusEffects) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.List())
+}
+
+func (s *StatusEffects) UnmarshalJSON(data []byte) error {
+	var list []StatusEffect
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	s.active = make(map[string]*StatusEffect, len(list))
+	for i := range list {
+		e := list[i]
+		if _, ok := effectDefs[e.Kind]; ok {
+			e.nextTick = e.ExpiresAt + 1 // No ticks owed while saved
+			s.active[e.Kind] = &e
+		}
+	}
+	return nil
+}
+
+// blockedWhileDisabled are the inputs a stunned, frozen or charmed player
+// can't send.
+var blockedWhileDisabled = map[string]bool{
+	"move":        true,
+	"mob_hit":     true,
+	"player_hit":  true,
+	"ability_hit": true,
+}
+
+// updatePlayerEffectsUnsafe ticks every living player's status effects.
+// Damage over time goes through the damage pipeline and can kill.
+// Caller MUST hold h.mutex.
+func (h *Hub) updatePlayerEffectsUnsafe(now int64) {
+	for _, p := range h.players {
+		if p.Dead {
+			continue
+		}
+		if dot, source := p.Effects.Update(now); dot > 0 {
+			h.dealDamageUnsafe(&DamageEvent{
+				Source: source,
+				Target: p,
+				Base:   dot,
+				Tags:   []string{TagDoT},
+				Now:    now,
+			})
+		}
+	}
+}
+
+const (
+	playerRunSpeed = 16.0 // Client run speed, units/s
+	maxMoveWindow  = 500  // ms of travel a single move can claim
+)
+
+// clampSlowedMove cuts a slowed player's move short to the distance the
+// run speed, scaled by the slow, covers since their last move. It reports
+// whether the move was cut.
+func (p *Player) clampSlowedMove(x, z float64, now int64) (float64, float64, bool) {
+	factor := p.Effects.SpeedFactor(now)
+	if factor >= 1 {
+		return x, z, false
+	}
+	elapsed := min(now-p.lastMoveAt, maxMoveWindow)
+	maxStep := playerRunSpeed * factor * float64(elapsed) / 1000
+	dx, dz := x-p.X, z-p.Z
+	dist := math.Sqrt(dx*dx + dz*dz)
+	if dist <= maxStep {
+		return x, z, false
+	}
+	return p.X + dx/dist*maxStep, p.Z + dz/dist*maxStep, true
+}
+
+// EffectSpec is an effect to apply, as declared in data files.
+type EffectSpec struct {
+	Kind      string  `json:"kind"`
+	Duration  int64   `json:"duration"` // ms
+	Magnitude float64 `json:"magnitude,omitempty"`
+}
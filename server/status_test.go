@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStatusEffects_Stacking(t *testing.T) {
+	var s StatusEffects
+
+	// Stuns don't stack or refresh
+	s.Apply(EffectStun, 1000, 0, "a", 0)
+	if s.Apply(EffectStun, 5000, 0, "a", 500) || s.Get(EffectStun, 500).ExpiresAt != 1000 {
+		t.Error("Reapplying an active stun should do nothing")
+	}
+
+	// Poison stacks up to its cap and refreshes
+	for i := 0; i < 10; i++ {
+		s.Apply(EffectPoison, 2000, 2, "a", int64(i))
+	}
+	if p := s.Get(EffectPoison, 10); p.Stacks != effectDefs[EffectPoison].MaxStacks || p.ExpiresAt != 2009 {
+		t.Errorf("Poison = %+v", p)
+	}
+
+	// Slows refresh and keep the stronger (lower) speed multiplier
+	s.Apply(EffectSlow, 1000, 0.5, "a", 0)
+	s.Apply(EffectSlow, 1000, 0.3, "a", 500)
+	if sl := s.Get(EffectSlow, 600); sl.ExpiresAt != 1500 || sl.Magnitude != 0.3 {
+		t.Errorf("Slow = %+v", sl)
+	}
+	s.Apply(EffectSlow, 1000, 0.8, "a", 700)
+	if sl := s.Get(EffectSlow, 800); sl.ExpiresAt != 1700 || sl.Magnitude != 0.3 {
+		t.Errorf("A weaker slow weakened the active one: %+v", sl)
+	}
+}
+
+func TestStatusEffects_TicksAndExpiry(t *testing.T) {
+	var s StatusEffects
+	s.Apply(EffectBurn, 1500, 3, "dragon", 0)
+
+	if dmg, _ := s.Update(400); dmg != 0 {
+		t.Errorf("Ticked early: %d", dmg)
+	}
+	if dmg, src := s.Update(500); dmg != 3 || src != "dragon" {
+		t.Errorf("Tick at 500: %d from %q", dmg, src)
+	}
+	// A late update catches up on missed ticks, but none past expiry
+	if dmg, _ := s.Update(5000); dmg != 6 {
+		t.Errorf("Catch-up damage = %d, want 6", dmg)
+	}
+	if s.Has(EffectBurn, 5000) || len(s.List()) != 0 {
+		t.Error("Burn should have expired")
+	}
+}
+
+func TestStatusEffects_Immunity(t *testing.T) {
+	var s StatusEffects
+	s.Apply(EffectStun, 1000, 0, "", 0)
+	s.Update(1000)
+	if s.Disabled(1000) {
+		t.Fatal("Stun should have ended")
+	}
+	if s.Apply(EffectStun, 1000, 0, "", 2000) {
+		t.Error("Stunned again inside the immunity window")
+	}
+	if !s.Apply(EffectFreeze, 1000, 0, "", 2000) {
+		t.Error("Immunity is per effect")
+	}
+	if !s.Apply(EffectStun, 1000, 0, "", 1000+effectDefs[EffectStun].Immunity) {
+		t.Error("Immunity should run out")
+	}
+}
+
+func TestStatusEffects_ShieldAndBuffs(t *testing.T) {
+	var s StatusEffects
+	s.Apply(EffectShield, 10000, 30, "", 0)
+	if got := s.AbsorbShield(20, 0); got != 0 {
+		t.Errorf("Shield let %d through", got)
+	}
+	if got := s.AbsorbShield(20, 0); got != 10 || s.Has(EffectShield, 0) {
+		t.Errorf("Broken shield let %d through, want 10", got)
+	}
+
+	s.Apply(EffectDamageBuff, 1000, 0.25, "", 0)
+	s.Apply(EffectSlow, 1000, 0.4, "", 0)
+	if s.DamageFactor(0) != 1.25 || s.SpeedFactor(0) != 0.4 {
+		t.Errorf("Factors %v/%v", s.DamageFactor(0), s.SpeedFactor(0))
+	}
+	if s.DamageFactor(1000) != 1 || s.SpeedFactor(1000) != 1 {
+		t.Error("Expired buffs still apply")
+	}
+}
+
+func TestStatusEffects_JSON(t *testing.T) {
+	p := &Player{ID: "p"}
+	p.Effects.Apply(EffectSlow, 1000, 0.5, "Ice Admiral", 0)
+	data, _ := json.Marshal(p)
+	var state struct {
+		Effects []StatusEffect `json:"effects"`
+	}
+	json.Unmarshal(data, &state)
+	if len(state.Effects) != 1 || state.Effects[0].Kind != EffectSlow || state.Effects[0].ExpiresAt != 1000 {
+		t.Errorf("effects in state = %s", data)
+	}
+
+	var loaded Player
+	if err := json.Unmarshal(data, &loaded); err != nil || !loaded.Effects.Has(EffectSlow, 500) {
+		t.Errorf("Effects not restored: %v", err)
+	}
+}
+
+func TestStatusEffects_Players(t *testing.T) {
+	hub := newHub()
+	p := &Player{ID: "p", RoomID: defaultRoomID, Level: 1, Health: 10, MaxHealth: 100, X: 100}
+	hub.players[p.ID] = p
+
+	// Disabled players can't move or attack
+	p.Effects.Apply(EffectFreeze, 60000, 0, "Ice Admiral", nowMs())
+	hub.handleInput(nil, p, InputMessage{Type: "move", X: 110})
+	if p.X != 100 {
+		t.Error("Frozen player moved")
+	}
+
+	// Damage over time can kill, credited to whoever applied it
+	p.Effects.Apply(EffectPoison, 10000, 20, "Venom Boss", 0)
+	hub.updatePlayerEffectsUnsafe(500)
+	if !p.Dead || len(p.Effects.List()) != 0 {
+		t.Errorf("Poison should kill and death clears effects: dead=%v effects=%v", p.Dead, p.Effects.List())
+	}
+}
+
+func TestStatusEffects_MobAbilityApplies(t *testing.T) {
+	hub := newHub()
+	p := &Player{ID: "p", Health: 100, MaxHealth: 100}
+	ability := &MobAbility{Name: "GlacierField", Damage: 10, Effect: &EffectSpec{Kind: EffectFreeze, Duration: 2000}}
+	hub.dealDamageUnsafe(&DamageEvent{Mob: &Mob{Type: "Ice Admiral"}, Target: p, Ability: ability, Base: 10, Tags: []string{TagAbility}, Now: 0})
+	if fx := p.Effects.Get(EffectFreeze, 0); fx == nil || fx.Source != "Ice Admiral" {
+		t.Errorf("Freeze = %+v", fx)
+	}
+}
+
+func TestStatusEffects_StunnedMobStaysPut(t *testing.T) {
+	hub := newHub()
+	mm := NewMobManager(hub, defaultRoomID)
+	mm.SpawnMob("mob1", "Gorilla", 100, 100)
+	mob := mm.Mobs["mob1"]
+	hub.players["p"] = &Player{ID: "p", RoomID: defaultRoomID, Health: 100, MaxHealth: 100, X: 105, Z: 100}
+
+	mob.Effects.Apply(EffectStun, 60000, 0, "p", nowMs())
+	mm.Update(0.05)
+	if mob.X != 100 || mob.State != StateStunned || mob.TargetID != "" {
+		t.Errorf("Stunned mob acted: %+v", mob)
+	}
+}
+
+func nowMs() int64 { return time.Now().UnixMilli() }
+
+func TestStatusEffects_PlayerDoTUsesPipeline(t *testing.T) {
+	hub := newHub()
+	p := &Player{ID: "p", RoomID: defaultRoomID, Level: 1, Health: 100, MaxHealth: 100}
+	hub.players[p.ID] = p
+
+	p.Effects.Apply(EffectShield, 10000, 30, "p", 0)
+	p.Effects.Apply(EffectBurn, 10000, 20, "Magma Boss", 0)
+	hub.updatePlayerEffectsUnsafe(500)
+	if p.Health != 100 {
+		t.Errorf("Health = %d, the shield should absorb the burn tick", p.Health)
+	}
+	if fx := p.Effects.Get(EffectShield, 500); fx == nil || fx.Magnitude != 10 {
+		t.Errorf("Shield = %+v, want 10 left", fx)
+	}
+}
+
+func TestStatusEffects_SlowCapsMoves(t *testing.T) {
+	hub, clock := setupClockTest()
+	p := &Player{ID: "p", RoomID: defaultRoomID, Level: 1, Health: 100, MaxHealth: 100, X: 100}
+	hub.players[p.ID] = p
+
+	hub.handleInput(nil, p, InputMessage{Type: "move", X: 101})
+	p.Effects.Apply(EffectSlow, 60000, 0.5, "Ice Admiral", hub.now())
+	clock.Advance(100 * time.Millisecond)
+	hub.handleInput(nil, p, InputMessage{Type: "move", X: 111})
+	if want := 101 + playerRunSpeed*0.5*0.1; math.Abs(p.X-want) > 1e-9 {
+		t.Errorf("X = %v, want the slowed stride to %v", p.X, want)
+	}
+	clock.Advance(100 * time.Millisecond)
+	hub.handleInput(nil, p, InputMessage{Type: "move", X: p.X + 0.5})
+	if math.Abs(p.X-102.3) > 1e-9 {
+		t.Errorf("X = %v, a short step should go through", p.X)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	tradeRequestTimeout = 30000 // ms a trade request stays open
+	tradeMaxItems       = 10    // Distinct items per offer
+)
+
+// TradeOffer is what one side puts up in a trade.
+type TradeOffer struct {
+	Items map[string]int `json:"items"`
+	Money int            `json:"money"`
+}
+
+// validate checks the offer against what the player currently owns.
+func (o *TradeOffer) validate(p *Player) error {
+	if o.Money < 0 || len(o.Items) > tradeMaxItems {
+		return errors.New("invalid offer")
+	}
+	if o.Money > p.Money {
+		return errors.New("not enough money")
+	}
+	for item, qty := range o.Items {
+		if qty <= 0 {
+			return fmt.Errorf("invalid quantity for %s", item)
+		}
+		if item == "melee" {
+			return errors.New("melee can't be traded")
+		}
+		if item == p.Weapon {
+			return fmt.Errorf("equip another weapon before trading %s", item)
+		}
+		if p.Inventory.Count(item) < qty {
+			return fmt.Errorf("you don't have %d %s", qty, item)
+		}
+	}
+	return nil
+}
+
+// TradeSession is a trade between two players. Offers are held on the session
+// until both sides have locked them in and confirmed; changing an offer
+// unlocks both sides.
+type TradeSession struct {
+	ID        string                 `json:"id"`
+	Initiator string                 `json:"initiator"`
+	Partner   string                 `json:"partner"`
+	Accepted  bool                   `json:"accepted"`
+	Offers    map[string]*TradeOffer `json:"offers"`
+	Locked    map[string]bool        `json:"locked"`
+	Confirmed map[string]bool        `json:"confirmed"`
+	CreatedAt int64                  `json:"-"`
+}
+
+func (s *TradeSession) other(playerID string) string {
+	if playerID == s.Initiator {
+		return s.Partner
+	}
+	return s.Initiator
+}
+
+func (s *TradeSession) expired(now int64) bool {
+	return !s.Accepted && now-s.CreatedAt > tradeRequestTimeout
+}
+
+// TradeRecord is the audit entry written for every completed trade.
+type TradeRecord struct {
+	ID        int64       `json:"id"`
+	PlayerA   string      `json:"playerA"`
+	PlayerB   string      `json:"playerB"`
+	OfferA    *TradeOffer `json:"offerA"`
+	OfferB    *TradeOffer `json:"offerB"`
+	CreatedAt int64       `json:"createdAt"`
+}
+
+// sendToPlayerUnsafe writes a message to a player's connection if online.
+// Caller MUST hold h.mutex.
+func (h *Hub) sendToPlayerUnsafe(playerID string, msg []byte) {
+	if conn, ok := h.clientsUnsafe(playerID); ok {
+		conn.WriteMessage(websocket.TextMessage, msg)
+	}
+}
+
+func tradeErrorMsg(reason string) []byte {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":   "trade_error",
+		"reason": reason,
+	})
+	return msg
+}
+
+// broadcastTradeUnsafe sends the session state to both sides.
+// Caller MUST hold h.mutex.
+func (h *Hub) broadcastTradeUnsafe(s *TradeSession) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":  "trade_update",
+		"trade": s,
+	})
+	h.sendToPlayerUnsafe(s.Initiator, msg)
+	h.sendToPlayerUnsafe(s.Partner, msg)
+}
+
+// tradeOfUnsafe returns the player's current trade, dropping requests
+// that were never accepted.
+// Caller MUST hold h.mutex.
+func (h *Hub) tradeOfUnsafe(playerID string, now int64) *TradeSession {
+	s, ok := h.trades[playerID]
+	if !ok {
+		return nil
+	}
+	if s.expired(now) {
+		delete(h.trades, s.Initiator)
+		delete(h.trades, s.Partner)
+		return nil
+	}
+	return s
+}
+
+// cancelTradeUnsafe ends the player's trade, if any, and tells both sides.
+// Caller MUST hold h.mutex.
+func (h *Hub) cancelTradeUnsafe(playerID, reason string) {
+	s, ok := h.trades[playerID]
+	if !ok {
+		return
+	}
+	delete(h.trades, s.Initiator)
+	delete(h.trades, s.Partner)
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":   "trade_cancelled",
+		"id":     s.ID,
+		"reason": reason,
+	})
+	h.sendToPlayerUnsafe(s.Initiator, msg)
+	h.sendToPlayerUnsafe(s.Partner, msg)
+}
+
+// handleTradeInput runs the trade protocol: trade_request (Item = partner),
+// trade_accept, trade_offer (Items, Money), trade_lock, trade_confirm and
+// trade_cancel.
+// Caller MUST hold h.mutex.
+func (h *Hub) handleTradeInput(c *websocket.Conn, player *Player, input InputMessage) {
+	now := h.now()
+	session := h.tradeOfUnsafe(player.ID, now)
+
+	switch input.Type {
+	case "trade_request":
+		partner, ok := h.players[input.Item]
+		if !ok || partner.ID == player.ID || partner.RoomID != player.RoomID {
+			c.WriteMessage(websocket.TextMessage, tradeErrorMsg("player not available"))
+			return
+		}
+		if session != nil || h.tradeOfUnsafe(partner.ID, now) != nil {
+			c.WriteMessage(websocket.TextMessage, tradeErrorMsg("already trading"))
+			return
+		}
+		s := &TradeSession{
+			ID:        fmt.Sprintf("trade_%s_%d", player.ID, now),
+			Initiator: player.ID,
+			Partner:   partner.ID,
+			Offers: map[string]*TradeOffer{
+				player.ID:  {Items: map[string]int{}},
+				partner.ID: {Items: map[string]int{}},
+			},
+			Locked:    map[string]bool{},
+			Confirmed: map[string]bool{},
+			CreatedAt: now,
+		}
+		h.trades[player.ID] = s
+		h.trades[partner.ID] = s
+
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type": "trade_request",
+			"id":   s.ID,
+			"from": player.ID,
+		})
+		h.sendToPlayerUnsafe(partner.ID, msg)
+
+	case "trade_accept":
+		if session == nil || session.Accepted || session.Partner != player.ID {
+			return
+		}
+		session.Accepted = true
+		h.broadcastTradeUnsafe(session)
+
+	case "trade_offer":
+		if session == nil || !session.Accepted {
+			return
+		}
+		offer := &TradeOffer{Items: input.Items, Money: input.Money}
+		if offer.Items == nil {
+			offer.Items = map[string]int{}
+		}
+		if err := offer.validate(player); err != nil {
+			c.WriteMessage(websocket.TextMessage, tradeErrorMsg(err.Error()))
+			return
+		}
+		session.Offers[player.ID] = offer
+		// Any change reopens the negotiation for both sides
+		session.Locked = map[string]bool{}
+		session.Confirmed = map[string]bool{}
+		h.broadcastTradeUnsafe(session)
+
+	case "trade_lock":
+		if session == nil || !session.Accepted {
+			return
+		}
+		if err := session.Offers[player.ID].validate(player); err != nil {
+			c.WriteMessage(websocket.TextMessage, tradeErrorMsg(err.Error()))
+			return
+		}
+		session.Locked[player.ID] = true
+		h.broadcastTradeUnsafe(session)
+
+	case "trade_confirm":
+		if session == nil || !session.Locked[session.Initiator] || !session.Locked[session.Partner] {
+			return
+		}
+		session.Confirmed[player.ID] = true
+		if !session.Confirmed[session.other(player.ID)] {
+			h.broadcastTradeUnsafe(session)
+			return
+		}
+
+		rec, err := h.executeTradeUnsafe(session, now)
+		if err != nil {
+			h.cancelTradeUnsafe(player.ID, err.Error())
+			return
+		}
+		delete(h.trades, session.Initiator)
+		delete(h.trades, session.Partner)
+
+		for _, id := range []string{session.Initiator, session.Partner} {
+			p := h.players[id]
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type":      "trade_complete",
+				"id":        session.ID,
+				"auditId":   rec.ID,
+				"money":     p.Money,
+				"inventory": p.Inventory,
+			})
+			h.sendToPlayerUnsafe(id, msg)
+			pc, _ := h.clientsUnsafe(id)
+			checkCollectQuest(p, now, pc)
+		}
+
+	case "trade_cancel":
+		if session != nil {
+			h.cancelTradeUnsafe(player.ID, "cancelled by "+player.ID)
+		}
+	}
+}
+
+// executeTradeUnsafe swaps both offers. The swap is applied to copies of
+// both players first and only becomes live once it has been saved together
+// with its audit record, so a failed trade leaves nothing half-moved.
+// Caller MUST hold h.mutex.
+func (h *Hub) executeTradeUnsafe(s *TradeSession, now int64) (*TradeRecord, error) {
+	a, okA := h.players[s.Initiator]
+	b, okB := h.players[s.Partner]
+	if !okA || !okB {
+		return nil, errors.New("trade partner left")
+	}
+	offerA, offerB := s.Offers[a.ID], s.Offers[b.ID]
+	if err := offerA.validate(a); err != nil {
+		return nil, fmt.Errorf("%s: %v", a.ID, err)
+	}
+	if err := offerB.validate(b); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.ID, err)
+	}
+
+	invA, invB := a.Inventory.Clone(), b.Inventory.Clone()
+
+	// Move both offers into escrow before delivering, so items leaving an
+	// inventory free up room for items arriving
+	escrowA := &Inventory{Capacity: tradeMaxItems}
+	escrowB := &Inventory{Capacity: tradeMaxItems}
+	for item, qty := range offerA.Items {
+		if err := invA.TransferTo(escrowA, item, qty); err != nil {
+			return nil, fmt.Errorf("%s: %v", a.ID, err)
+		}
+	}
+	for item, qty := range offerB.Items {
+		if err := invB.TransferTo(escrowB, item, qty); err != nil {
+			return nil, fmt.Errorf("%s: %v", b.ID, err)
+		}
+	}
+	for item, qty := range offerA.Items {
+		if err := escrowA.TransferTo(invB, item, qty); err != nil {
+			return nil, fmt.Errorf("%s can't receive %s: %v", b.ID, item, err)
+		}
+	}
+	for item, qty := range offerB.Items {
+		if err := escrowB.TransferTo(invA, item, qty); err != nil {
+			return nil, fmt.Errorf("%s can't receive %s: %v", a.ID, item, err)
+		}
+	}
+
+	nextA, nextB := *a, *b
+	nextA.Inventory, nextB.Inventory = invA, invB
+	nextA.Money += offerB.Money - offerA.Money
+	nextB.Money += offerA.Money - offerB.Money
+
+	rec := &TradeRecord{
+		PlayerA:   a.ID,
+		PlayerB:   b.ID,
+		OfferA:    offerA,
+		OfferB:    offerB,
+		CreatedAt: now,
+	}
+	if err := SaveTrade(&nextA, &nextB, rec); err != nil {
+		log.Printf("Trade %s failed to save: %v", s.ID, err)
+		return nil, errors.New("trade could not be saved")
+	}
+
+	a.Inventory, a.Money = nextA.Inventory, nextA.Money
+	b.Inventory, b.Money = nextB.Inventory, nextB.Money
+	return rec, nil
+}
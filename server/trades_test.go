@@ -0,0 +1,153 @@
+package main
+
+import (
+	"testing"
+)
+
+func setupTradeTest(t *testing.T) (*Hub, *Player, *Player) {
+	t.Helper()
+	useTestDB(t)
+
+	hub := newHub()
+	a := &Player{ID: "alice", RoomID: defaultRoomID, Money: 1000, Inventory: NewInventory("melee", "katana")}
+	b := &Player{ID: "bob", RoomID: defaultRoomID, Money: 500, Inventory: NewInventory("melee")}
+	b.Inventory.AddQuantity("Iron Scrap", 20)
+	for _, p := range []*Player{a, b} {
+		if err := RegisterUser(p.ID, "password"); err != nil {
+			t.Fatalf("RegisterUser: %v", err)
+		}
+		hub.players[p.ID] = p
+	}
+	return hub, a, b
+}
+
+func openTrade(hub *Hub, a, b *Player) *TradeSession {
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_request", Item: b.ID})
+	hub.handleTradeInput(nil, b, InputMessage{Type: "trade_accept"})
+	return hub.trades[a.ID]
+}
+
+func TestTrade_Swap(t *testing.T) {
+	hub, a, b := setupTradeTest(t)
+
+	s := openTrade(hub, a, b)
+	if s == nil || !s.Accepted {
+		t.Fatal("Expected an accepted trade session")
+	}
+
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_offer", Items: map[string]int{"katana": 1}, Money: 200})
+	hub.handleTradeInput(nil, b, InputMessage{Type: "trade_offer", Items: map[string]int{"Iron Scrap": 15}})
+	for _, p := range []*Player{a, b} {
+		hub.handleTradeInput(nil, p, InputMessage{Type: "trade_lock"})
+	}
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_confirm"})
+	if a.Inventory.Has("Iron Scrap") {
+		t.Fatal("Trade executed before both sides confirmed")
+	}
+	hub.handleTradeInput(nil, b, InputMessage{Type: "trade_confirm"})
+
+	if a.Inventory.Has("katana") || !b.Inventory.Has("katana") {
+		t.Error("Katana was not moved")
+	}
+	if a.Inventory.Count("Iron Scrap") != 15 || b.Inventory.Count("Iron Scrap") != 5 {
+		t.Errorf("Iron Scrap not moved: a=%d b=%d", a.Inventory.Count("Iron Scrap"), b.Inventory.Count("Iron Scrap"))
+	}
+	if a.Money != 800 || b.Money != 700 {
+		t.Errorf("Money not moved: a=%d b=%d", a.Money, b.Money)
+	}
+	if len(hub.trades) != 0 {
+		t.Error("Session should be closed")
+	}
+
+	// Both players are persisted
+	saved, err := LoadUser(b.ID)
+	if err != nil {
+		t.Fatalf("LoadUser: %v", err)
+	}
+	if !saved.Inventory.Has("katana") || saved.Money != 700 {
+		t.Errorf("Trade not persisted: money=%d", saved.Money)
+	}
+
+	// And audited
+	history, err := LoadTradeHistory(a.ID, 10)
+	if err != nil {
+		t.Fatalf("LoadTradeHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].OfferA.Money != 200 || history[0].OfferB.Items["Iron Scrap"] != 15 {
+		t.Errorf("Unexpected audit trail: %+v", history)
+	}
+}
+
+func TestTrade_OfferChangeUnlocks(t *testing.T) {
+	hub, a, b := setupTradeTest(t)
+	s := openTrade(hub, a, b)
+
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_lock"})
+	hub.handleTradeInput(nil, b, InputMessage{Type: "trade_lock"})
+	hub.handleTradeInput(nil, b, InputMessage{Type: "trade_offer", Money: 100})
+
+	if s.Locked[a.ID] || s.Locked[b.ID] {
+		t.Error("Changing an offer should unlock both sides")
+	}
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_confirm"})
+	if s.Confirmed[a.ID] {
+		t.Error("Confirm should require both sides locked")
+	}
+}
+
+func TestTrade_RevalidatedOnConfirm(t *testing.T) {
+	hub, a, b := setupTradeTest(t)
+	openTrade(hub, a, b)
+
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_offer", Items: map[string]int{"katana": 1}})
+	for _, p := range []*Player{a, b} {
+		hub.handleTradeInput(nil, p, InputMessage{Type: "trade_lock"})
+	}
+	// The katana leaves the inventory after locking
+	a.Inventory.Consume("katana")
+
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_confirm"})
+	hub.handleTradeInput(nil, b, InputMessage{Type: "trade_confirm"})
+
+	if b.Inventory.Has("katana") {
+		t.Error("Item that is no longer owned was traded")
+	}
+	if len(hub.trades) != 0 {
+		t.Error("Failed trade should be cancelled")
+	}
+	history, _ := LoadTradeHistory(a.ID, 10)
+	if len(history) != 0 {
+		t.Errorf("Failed trade was audited: %+v", history)
+	}
+}
+
+func TestTrade_ReceiverFull(t *testing.T) {
+	hub, a, b := setupTradeTest(t)
+	b.Inventory.Capacity = 2 // melee + Iron Scrap
+	openTrade(hub, a, b)
+
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_offer", Items: map[string]int{"katana": 1}})
+	for _, p := range []*Player{a, b} {
+		hub.handleTradeInput(nil, p, InputMessage{Type: "trade_lock"})
+	}
+	hub.handleTradeInput(nil, a, InputMessage{Type: "trade_confirm"})
+	hub.handleTradeInput(nil, b, InputMessage{Type: "trade_confirm"})
+
+	if !a.Inventory.Has("katana") || b.Inventory.Has("katana") {
+		t.Error("Trade into a full inventory should leave both sides untouched")
+	}
+}
+
+func TestTradeOffer_RejectsEquippedAndMelee(t *testing.T) {
+	p := &Player{ID: "alice", Weapon: "katana", Inventory: NewInventory("melee", "katana", "cutlass")}
+	for _, item := range []string{"melee", "katana"} {
+		offer := &TradeOffer{Items: map[string]int{item: 1}}
+		if err := offer.validate(p); err == nil {
+			t.Errorf("Offering %s should be rejected", item)
+		}
+	}
+	offer := &TradeOffer{Items: map[string]int{"cutlass": 1}}
+	if err := offer.validate(p); err != nil {
+		t.Errorf("Unequipped weapon rejected: %v", err)
+	}
+}